@@ -27,5 +27,12 @@ func mustConfig(homedir string) *config.Configfile {
 		}
 		log.Info("Continuing onwards and upwards!")
 	}
+	if cfg.Migrated() {
+		if err := cfg.Save(""); err != nil {
+			log.Warn("Could not migrate %s to schema version %d: %s", rpath, config.SchemaVersion, err)
+		} else {
+			log.Info("Migrated %s to schema version %d", rpath, config.SchemaVersion)
+		}
+	}
 	return cfg
 }
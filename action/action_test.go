@@ -24,3 +24,7 @@ func (r TestRunner) Delete(name, ktype, ns string) ([]byte, error) {
 func (r TestRunner) Get(stdin []byte, ns string) ([]byte, error) {
 	return r.out, r.err
 }
+
+func (r TestRunner) List(kinds []string, ns string) ([]byte, error) {
+	return r.out, r.err
+}
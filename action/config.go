@@ -0,0 +1,98 @@
+package action
+
+import (
+	"fmt"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/helm/helm-classic/config"
+	"github.com/helm/helm-classic/log"
+	helm "github.com/helm/helm-classic/util"
+)
+
+// ConfigView prints the effective configuration, with any project file merged
+// in, and the file that each value came from.
+func ConfigView(home string) {
+	cfg := mustConfig(home)
+	if p := cfg.Project(); p != "" {
+		log.Info("Using project configuration %s", p)
+	}
+
+	w := tabwriter.NewWriter(log.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, s := range cfg.Settings() {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, s.Value, s.Source)
+	}
+	w.Flush()
+}
+
+// ConfigGet prints the effective value of a configuration key.
+func ConfigGet(home, key string) {
+	v, err := mustConfig(home).Get(key)
+	if err != nil {
+		log.Die("%s", err)
+	}
+	log.Msg("%s", v)
+}
+
+// ConfigSet sets a key in $HELMC_HOME/config.yaml.
+//
+// The configuration is validated first, and is left unchanged if it is not
+// valid.
+func ConfigSet(home, key, value string) {
+	editConfig(home, func(cfg *config.Configfile) error {
+		return cfg.Set(key, value)
+	})
+}
+
+// ConfigUnset clears a key in $HELMC_HOME/config.yaml. See ConfigSet.
+func ConfigUnset(home, key string) {
+	editConfig(home, func(cfg *config.Configfile) error {
+		return cfg.Unset(key)
+	})
+}
+
+// editConfig changes the configuration in $HELMC_HOME, without any project
+// file, and saves it if it is still valid.
+func editConfig(home string, edit func(*config.Configfile) error) {
+	rpath := filepath.Join(home, helm.Configfile)
+	cfg, err := config.LoadFile(rpath)
+	if err != nil {
+		log.Die("Could not load %s: %s", rpath, err)
+	}
+	if err := edit(cfg); err != nil {
+		log.Die("%s", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Die("Not saving %s: %s", rpath, err)
+	}
+	if err := cfg.Save(""); err != nil {
+		log.Die("Could not save %s: %s", rpath, err)
+	}
+	log.Info("Saved %s", rpath)
+}
+
+// ConfigValidate checks the configuration, with any project file merged in,
+// and reports every problem found.
+func ConfigValidate(home string) {
+	rpath := filepath.Join(home, helm.Configfile)
+	cfg, err := config.Load(rpath)
+	if err != nil {
+		log.Die("Could not load %s: %s", rpath, err)
+	}
+	if cfg.Migrated() {
+		log.Info("%s uses an older schema. It will be migrated to version %d the next time it is used.", rpath, config.SchemaVersion)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		if verr, ok := err.(*config.ValidationError); ok {
+			for _, p := range verr.Problems {
+				log.Err("%s", p)
+			}
+		} else {
+			log.Err("%s", err)
+		}
+		log.Die("The configuration is not valid.")
+	}
+	log.Info("The configuration is valid.")
+}
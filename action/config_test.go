@@ -0,0 +1,68 @@
+package action
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/helm/helm-classic/test"
+	"github.com/helm/helm-classic/util"
+)
+
+func TestConfigView(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	test.FakeUpdate(tmpHome)
+
+	actual := test.CaptureOutput(func() {
+		ConfigView(tmpHome)
+	})
+	test.ExpectContains(t, actual, "KEY")
+	test.ExpectContains(t, actual, "repos.tables.charts.repo")
+	test.ExpectContains(t, actual, util.Configfile)
+}
+
+func TestConfigSet(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	test.FakeUpdate(tmpHome)
+
+	actual := test.CaptureOutput(func() {
+		ConfigSet(tmpHome, "namespace", "dev")
+		ConfigGet(tmpHome, "namespace")
+		ConfigValidate(tmpHome)
+	})
+	test.ExpectContains(t, actual, "Saved ")
+	test.ExpectContains(t, actual, "dev")
+	test.ExpectContains(t, actual, "The configuration is valid.")
+}
+
+func TestConfigUnknownKey(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	test.FakeUpdate(tmpHome)
+	rpath := filepath.Join(tmpHome, util.Configfile)
+	f, err := os.OpenFile(rpath, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("namspace: dev\n")
+	f.Close()
+
+	actual := test.CaptureOutput(func() {
+		ConfigSet(tmpHome, "namespace", "dev")
+	})
+	test.ExpectContains(t, actual, "unknown key namspace")
+
+	actual = test.CaptureOutput(func() {
+		ConfigUnset(tmpHome, "namspace")
+		ConfigValidate(tmpHome)
+	})
+	test.ExpectContains(t, actual, "The configuration is valid.")
+	data, err := ioutil.ReadFile(rpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "namspace") {
+		t.Errorf("Expected namspace to be removed, got:\n%s", data)
+	}
+}
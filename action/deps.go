@@ -0,0 +1,290 @@
+package action
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/dependency"
+	"github.com/helm/helm-classic/kubectl"
+	"github.com/helm/helm-classic/log"
+	"github.com/helm/helm-classic/record"
+	helm "github.com/helm/helm-classic/util"
+)
+
+// depSources returns the repository tables that dependencies are fetched
+// from. The default table is searched first.
+func depSources(home string) []*dependency.Source {
+	r := mustConfig(home).Repos
+	sources := []*dependency.Source{}
+	for _, t := range r.Tables {
+		s := &dependency.Source{Name: t.Name, Repo: t.Repo, Dir: helm.CacheDirectory(home, t.Name)}
+		if t.Name == r.Default {
+			sources = append([]*dependency.Source{s}, sources...)
+		} else {
+			sources = append(sources, s)
+		}
+	}
+	return sources
+}
+
+// fetchDeps fetches the dependencies of a chart, and their dependencies in
+// turn, into the workspace.
+//
+// If the chart has a Chart.lock, the pinned charts are fetched. Otherwise the
+// dependencies are resolved against the repository tables.
+//
+// It returns every dependency in the order they must be installed, including
+// those that were already in the workspace.
+func fetchDeps(cf *chart.Chartfile, home, chartName string, verify bool) []*dependency.Node {
+	lockfile := helm.WorkspaceChartDirectory(home, chartName, dependency.LockFile)
+	if _, err := os.Stat(lockfile); err == nil {
+		return fetchLocked(cf, home, chartName, lockfile, verify)
+	}
+
+	nodes, err := dependency.Walk(cf, helm.WorkspaceChartDirectory(home), depSources(home))
+	if err != nil {
+		log.Die("Could not resolve the dependencies of %s: %s", cf.Name, err)
+	}
+	for _, n := range nodes {
+		if n.Source == nil {
+			log.Debug("Dependency %s %s is already in the workspace", n.Name, n.Chartfile.Version)
+			continue
+		}
+		log.Info("Fetching dependency %s %s from %s", n.Name, n.Chartfile.Version, n.Source.Name)
+		fetch(n.Name, n.Name, home, n.Source.Name, verify)
+	}
+	return nodes
+}
+
+// fetchLocked fetches the charts pinned by a chart's lock file.
+//
+// Each chart is taken from the commit of its table that the lock records,
+// even if the table has moved on since.
+func fetchLocked(cf *chart.Chartfile, home, chartName, lockfile string, verify bool) []*dependency.Node {
+	lock, err := dependency.LoadLock(lockfile)
+	if err != nil {
+		log.Die("Could not read %s: %s", lockfile, err)
+	}
+	if err := lock.Check(cf); err != nil {
+		log.Die("%s is out of date: %s. Run `helmc deps update %s`.", lockfile, err, chartName)
+	}
+
+	r := mustConfig(home).Repos
+	nodes := []*dependency.Node{}
+	for _, p := range lock.Dependencies {
+		n := &dependency.Node{Name: p.Name}
+		dest := helm.WorkspaceChartDirectory(home, p.Name)
+		if wcf, err := chart.LoadChartfile(filepath.Join(dest, Chartfile)); err == nil {
+			v := wcf.Version
+			if wcf.From != nil {
+				v = wcf.From.Version
+			}
+			if v != p.Version {
+				log.Die("%s pins %s %s, but your workspace has %s at version %s. Rename or remove it.", dependency.LockFile, p.Name, p.Version, p.Name, v)
+			}
+			log.Debug("Dependency %s %s is already in the workspace", p.Name, p.Version)
+			n.Chartfile = wcf
+			nodes = append(nodes, n)
+			continue
+		}
+
+		if !r.Exists(p.Table) {
+			log.Die("%s pins %s to table %q, which is not configured. Add it with `helmc repo add %s %s`.", dependency.LockFile, p.Name, p.Table, p.Table, p.Repo)
+		}
+		log.Info("Fetching dependency %s %s from %s at %s", p.Name, p.Version, p.Table, p.Commit)
+		if head, err := r.Commit(p.Table); err == nil && head == p.Commit {
+			fetch(p.Name, p.Name, home, p.Table, verify)
+		} else if err := r.ExportChart(p.Table, p.Commit, p.Name, dest); err != nil {
+			log.Die("Could not fetch %s from %s: %s. Run `helmc update` if the commit is newer than your copy of the table.", p.Name, p.Table, err)
+		} else if verify || tableVerifies(home, p.Table) {
+			if _, err := checkSignature(dest, home); err != nil {
+				os.RemoveAll(dest)
+				log.Die("Refusing chart %s: %s.", p.Name, err)
+			}
+		}
+
+		wcf, err := chart.LoadChartfile(filepath.Join(dest, Chartfile))
+		if err != nil {
+			log.Die("Fetched %s is not a valid chart: %s", p.Name, err)
+		}
+		if wcf.From == nil {
+			wcf.From = &chart.Dependency{Name: wcf.Name, Version: wcf.Version, Repo: p.Repo, Commit: p.Commit}
+			wcf.Name = p.Name
+			if err := wcf.Save(filepath.Join(dest, Chartfile)); err != nil {
+				log.Die("Failed to update Chart.yaml: %s", err)
+			}
+		}
+		if wcf.From.Version != p.Version {
+			log.Die("%s pins %s %s, but %s at %s has version %s.", dependency.LockFile, p.Name, p.Version, p.Table, p.Commit, wcf.From.Version)
+		}
+		n.Chartfile = wcf
+		n.Source = &dependency.Source{Name: p.Table, Repo: p.Repo, Dir: helm.CacheDirectory(home, p.Table)}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// warnLock warns about dependencies in the workspace that differ from the
+// versions pinned by a chart's lock file.
+func warnLock(home, chartName string) {
+	lock, err := dependency.LoadLock(helm.WorkspaceChartDirectory(home, chartName, dependency.LockFile))
+	if err != nil {
+		return
+	}
+	if stale := lock.Stale(helm.WorkspaceChartDirectory(home)); len(stale) > 0 {
+		log.Warn("Dependencies differ from %s:", dependency.LockFile)
+		for _, s := range stale {
+			log.Msg("\t%s", s)
+		}
+	}
+}
+
+// UpdateDeps resolves the dependencies of a chart in the workspace against the
+// repository tables, and pins the result in the chart's Chart.lock.
+//
+// Charts in the workspace are ignored, so the lock reflects what the tables
+// provide. Each chart is pinned to the commit that its table is at.
+func UpdateDeps(chartName, home string) {
+	cd := helm.WorkspaceChartDirectory(home, chartName)
+	cf, err := chart.LoadChartfile(filepath.Join(cd, Chartfile))
+	if err != nil {
+		log.Die("Could not load %s: %s", chartName, err)
+	}
+
+	nodes, err := dependency.Walk(cf, "", depSources(home))
+	if err != nil {
+		log.Die("Could not resolve the dependencies of %s: %s", chartName, err)
+	}
+
+	r := mustConfig(home).Repos
+	commits := map[string]string{}
+	for _, n := range nodes {
+		if _, ok := commits[n.Source.Name]; ok {
+			continue
+		}
+		c, err := r.Commit(n.Source.Name)
+		if err != nil {
+			log.Die("Could not find the commit of table %s: %s", n.Source.Name, err)
+		}
+		commits[n.Source.Name] = c
+	}
+
+	lock := dependency.NewLock(nodes, commits)
+	lockfile := filepath.Join(cd, dependency.LockFile)
+	if err := lock.Save(lockfile); err != nil {
+		log.Die("Could not write %s: %s", lockfile, err)
+	}
+	for _, p := range lock.Dependencies {
+		log.Msg("\t%s %s (%s@%s)", p.Name, p.Version, p.Table, shortSHA(p.Commit))
+	}
+	log.Info("Wrote %s", lockfile)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// installDeps fetches and installs the dependencies of a chart, dependencies
+// first.
+//
+// A dependency is skipped if it is already deployed in the destination
+// namespace. Dependencies are installed with the same options as the chart.
+func installDeps(cf *chart.Chartfile, home, chartName string, opts *InstallOptions, client kubectl.Runner) {
+	nodes := fetchDeps(cf, home, chartName, opts.Verify)
+	if len(nodes) == 0 {
+		return
+	}
+
+	depOpts := *opts
+	depOpts.WithDeps = false
+	for _, n := range nodes {
+		rel, err := record.Load(helm.ReleaseDirectory(home), n.Name)
+		if err == nil && rel.Status == record.StatusDeployed && rel.Namespace == opts.releaseNamespace() {
+			log.Info("Dependency %s is already installed", n.Name)
+			continue
+		}
+		log.Info("Installing dependency %s %s", n.Name, n.Chartfile.Version)
+		Install(n.Name, home, &depOpts, client)
+	}
+}
+
+// DepsTree prints the dependency tree of a chart in the workspace.
+//
+// Each dependency is shown with the version that satisfies it, and whether
+// that chart is in the workspace, can be fetched from a repository table, or
+// is missing. If asJSON is true, the tree is printed as JSON.
+func DepsTree(chartName, home string, asJSON bool) {
+	cf, err := chart.LoadChartfile(helm.WorkspaceChartDirectory(home, chartName, Chartfile))
+	if err != nil {
+		log.Die("Could not load %s: %s", chartName, err)
+	}
+	tree, err := dependency.BuildTree(cf, helm.WorkspaceChartDirectory(home), depSources(home))
+	if err != nil {
+		log.Die("Could not resolve the dependencies of %s: %s", chartName, err)
+	}
+
+	if asJSON {
+		printJSON(struct {
+			Name         string             `json:"name"`
+			Version      string             `json:"version"`
+			Dependencies []*dependency.Tree `json:"dependencies"`
+		}{cf.Name, cf.Version, tree})
+		return
+	}
+	log.Msg("%s %s", cf.Name, cf.Version)
+	printTree(tree, "  ")
+}
+
+func printTree(tree []*dependency.Tree, indent string) {
+	for _, t := range tree {
+		req := t.Name + " " + t.Constraint
+		if t.Repo != "" {
+			req += " from " + t.Repo
+		}
+		switch t.Status {
+		case dependency.StatusSatisfied:
+			log.Msg("%s%s => %s (in workspace as %s)", indent, req, t.Version, t.Workspace)
+		case dependency.StatusAvailable:
+			log.Msg("%s%s => %s (not fetched, available from %s)", indent, req, t.Version, t.Source)
+		case dependency.StatusCycle:
+			log.Msg("%s%s => %s (cycle)", indent, req, t.Version)
+		default:
+			log.Msg("%s%s => MISSING", indent, req)
+		}
+		printTree(t.Dependencies, indent+"  ")
+	}
+}
+
+// DepsReverse prints the charts in the repository tables that depend on the
+// named chart. If asJSON is true, the list is printed as JSON.
+func DepsReverse(chartName, home string, asJSON bool) {
+	deps, err := dependency.Dependents(chartName, depSources(home))
+	if err != nil {
+		log.Die("Could not search the repository tables: %s", err)
+	}
+
+	if asJSON {
+		printJSON(deps)
+		return
+	}
+	if len(deps) == 0 {
+		log.Info("No charts depend on %s.", chartName)
+		return
+	}
+	for _, d := range deps {
+		log.Msg("%s/%s %s requires %s %s", d.Source, d.Name, d.Version, chartName, d.Constraint)
+	}
+}
+
+func printJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Die("Could not encode JSON: %s", err)
+	}
+	log.Msg("%s", data)
+}
@@ -0,0 +1,188 @@
+package action
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/dependency"
+	"github.com/helm/helm-classic/test"
+	"github.com/helm/helm-classic/util"
+)
+
+// writeDepChart adds a chart with a single Service to the default table.
+func writeDepChart(t *testing.T, home, name, version, deps string) {
+	dir := util.CacheDirectory(home, "charts", name)
+	if err := os.MkdirAll(filepath.Join(dir, "manifests"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cf := "name: " + name + "\nversion: " + version + "\n" + deps
+	if err := ioutil.WriteFile(filepath.Join(dir, Chartfile), []byte(cf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	svc := "apiVersion: v1\nkind: Service\nmetadata:\n  name: " + name + "\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "manifests", "svc.yaml"), []byte(svc), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInstallWithDeps(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	defer os.RemoveAll(tmpHome)
+	test.FakeUpdate(tmpHome)
+
+	pp := os.Getenv("PATH")
+	defer os.Setenv("PATH", pp)
+	os.Setenv("PATH", filepath.Join(test.HelmRoot, "testdata")+":"+pp)
+
+	writeDepChart(t, tmpHome, "app", "1.0.0", "dependencies:\n  - name: web\n    version: ~2.0\n  - name: db\n    version: \">=3.0.0\"\n")
+	writeDepChart(t, tmpHome, "web", "2.0.1", "dependencies:\n  - name: db\n    version: \"<4.0.0\"\n")
+	writeDepChart(t, tmpHome, "db", "3.1.0", "")
+
+	actual := test.CaptureOutput(func() {
+		Fetch("app", "", tmpHome, &FetchOptions{WithDeps: true})
+	})
+	test.ExpectContains(t, actual, "Fetching dependency db 3.1.0 from charts")
+	test.ExpectContains(t, actual, "Fetching dependency web 2.0.1 from charts")
+	if strings.Contains(actual, "Unsatisfied dependencies") {
+		t.Errorf("Expected every dependency to be fetched, got %s", actual)
+	}
+	for _, name := range []string{"app", "web", "db"} {
+		if !chartFetched(name, tmpHome) {
+			t.Errorf("Expected %s in the workspace", name)
+		}
+	}
+
+	actual = test.CaptureOutput(func() {
+		Install("app", tmpHome, &InstallOptions{WithDeps: true}, TestRunner{})
+	})
+	order := []string{"Installing dependency db 3.1.0", "Installing dependency web 2.0.1"}
+	last := -1
+	for _, o := range order {
+		i := strings.Index(actual, o)
+		if i <= last {
+			t.Errorf("Expected %q in order in %s", order, actual)
+			break
+		}
+		last = i
+	}
+
+	// Installed dependencies are skipped.
+	actual = test.CaptureOutput(func() {
+		Install("app", tmpHome, &InstallOptions{WithDeps: true}, TestRunner{})
+	})
+	test.ExpectContains(t, actual, "Dependency web is already installed")
+
+	// A cycle stops the install.
+	writeDepChart(t, tmpHome, "db", "3.1.0", "dependencies:\n  - name: web\n    version: \"*\"\n")
+	os.RemoveAll(util.WorkspaceChartDirectory(tmpHome, "db"))
+	actual = test.CaptureOutput(func() {
+		Install("app", tmpHome, &InstallOptions{WithDeps: true}, TestRunner{})
+	})
+	test.ExpectContains(t, actual, "Could not resolve the dependencies of app: dependency cycle: web -> db -> web")
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	args = append([]string{"-C", dir, "-c", "user.name=helmc", "-c", "user.email=helmc@example.com"}, args...)
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %s %s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestUpdateDeps(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	defer os.RemoveAll(tmpHome)
+	test.FakeUpdate(tmpHome)
+
+	writeDepChart(t, tmpHome, "app", "1.0.0", "dependencies:\n  - name: web\n    version: ~2.0\n")
+	writeDepChart(t, tmpHome, "web", "2.0.1", "dependencies:\n  - name: db\n    version: \"<4.0.0\"\n")
+	writeDepChart(t, tmpHome, "db", "3.1.0", "")
+	table := util.CacheDirectory(tmpHome, "charts")
+	git(t, table, "init", "-q")
+	git(t, table, "add", ".")
+	git(t, table, "commit", "-q", "-m", "Add charts")
+	pinned := git(t, table, "rev-parse", "HEAD")
+
+	test.CaptureOutput(func() {
+		Fetch("app", "", tmpHome, nil)
+		UpdateDeps("app", tmpHome)
+	})
+	lock, err := dependency.LoadLock(util.WorkspaceChartDirectory(tmpHome, "app", dependency.LockFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, p := range lock.Dependencies {
+		got = append(got, p.Name+"@"+p.Version+"<"+p.Table+"@"+p.Commit)
+	}
+	expect := []string{"db@3.1.0<charts@" + pinned, "web@2.0.1<charts@" + pinned}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("Expected %v, got %v", expect, got)
+	}
+
+	// The table moves on, but the lock still pins the old charts.
+	writeDepChart(t, tmpHome, "db", "3.2.0", "")
+	git(t, table, "commit", "-q", "-a", "-m", "Bump db")
+
+	actual := test.CaptureOutput(func() {
+		Fetch("app", "", tmpHome, &FetchOptions{WithDeps: true})
+	})
+	test.ExpectContains(t, actual, "Fetching dependency db 3.1.0 from charts at "+pinned)
+	cf, err := chart.LoadChartfile(util.WorkspaceChartDirectory(tmpHome, "db", Chartfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.From == nil || cf.From.Version != "3.1.0" {
+		t.Errorf("Expected db 3.1.0 in the workspace, got %+v", cf.From)
+	}
+
+	// Changed dependencies need a new lock.
+	app := &chart.Chartfile{Name: "app", Version: "1.0.0", Dependencies: []*chart.Dependency{{Name: "db", Version: ">=3.2.0"}}}
+	if err := app.Save(util.WorkspaceChartDirectory(tmpHome, "app", Chartfile)); err != nil {
+		t.Fatal(err)
+	}
+	actual = test.CaptureOutput(func() {
+		Install("app", tmpHome, &InstallOptions{WithDeps: true}, TestRunner{})
+	})
+	test.ExpectContains(t, actual, "is out of date: db >=3.2.0 is not pinned. Run `helmc deps update app`.")
+}
+
+func TestDepsTree(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	defer os.RemoveAll(tmpHome)
+	test.FakeUpdate(tmpHome)
+
+	writeDepChart(t, tmpHome, "app", "1.0.0", "dependencies:\n  - name: web\n    version: ~2.0\n  - name: cache\n    version: 1.0.0\n")
+	writeDepChart(t, tmpHome, "web", "2.0.1", "dependencies:\n  - name: db\n    version: \"<4.0.0\"\n")
+	writeDepChart(t, tmpHome, "db", "3.1.0", "")
+
+	actual := test.CaptureOutput(func() {
+		Fetch("app", "", tmpHome, nil)
+		Fetch("web", "", tmpHome, nil)
+		DepsTree("app", tmpHome, false)
+	})
+	test.ExpectContains(t, actual, "app 1.0.0\n  web ~2.0 => 2.0.1 (in workspace as web)\n    db <4.0.0 => 3.1.0 (not fetched, available from charts)\n  cache 1.0.0 => MISSING\n")
+
+	actual = test.CaptureOutput(func() {
+		DepsTree("app", tmpHome, true)
+	})
+	test.ExpectContains(t, actual, `"status": "satisfied"`)
+	test.ExpectContains(t, actual, `"source": "charts"`)
+
+	actual = test.CaptureOutput(func() {
+		DepsReverse("db", tmpHome, false)
+	})
+	test.ExpectContains(t, actual, "charts/web 2.0.1 requires db <4.0.0")
+
+	actual = test.CaptureOutput(func() {
+		DepsReverse("dep1", tmpHome, true)
+	})
+	test.ExpectContains(t, actual, `"name": "deptest"`)
+}
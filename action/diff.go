@@ -0,0 +1,258 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/diff"
+	"github.com/helm/helm-classic/kubectl"
+	"github.com/helm/helm-classic/log"
+	"github.com/helm/helm-classic/record"
+	helm "github.com/helm/helm-classic/util"
+)
+
+// serverFields are the metadata fields that Kubernetes populates on every object.
+var serverFields = []string{"uid", "resourceVersion", "selfLink", "creationTimestamp", "generation", "deletionTimestamp", "deletionGracePeriodSeconds"}
+
+// Diff compares a workspace chart to the objects that are running in Kubernetes.
+//
+// Each manifest is compared to the live object of the same kind and name. Objects
+// that are in the chart but not in the cluster are reported as added. Objects
+// that were recorded in the chart's release but are no longer in the chart are
+// reported as removed.
+//
+// Before comparing, fields that are populated by the server, including status,
+// are stripped from the live object. Fields that the chart does not set are
+// ignored, since Kubernetes fills in defaults for them. The values of Secrets
+// are never printed; the diff only shows which of their keys changed.
+//
+// If any differences are found, an error is logged so that helmc exits with a
+// non-zero status.
+//
+// If namespace is empty, the namespace of the chart's release is used.
+func Diff(chartName, home, namespace string, client kubectl.Runner) {
+	if !chartFetched(chartName, home) {
+		log.Die("No chart named %q in your workspace.", chartName)
+	}
+
+	c, err := chart.Load(helm.WorkspaceChartDirectory(home, chartName))
+	if err != nil {
+		log.Die("Failed to load chart: %s", err)
+	}
+
+	rel, err := record.Live(helm.ReleaseDirectory(home), chartName)
+	if err != nil {
+		log.Debug("No release for %s: %s", chartName, err)
+		rel = nil
+	} else if namespace == "" {
+		namespace = rel.Namespace
+	}
+
+	CheckKubePrereqs()
+
+	drift := 0
+	seen := map[string]bool{}
+	plan, err := installPlan(c)
+	if err != nil {
+		log.Die("Cannot diff %s: %s", chartName, err)
+	}
+	for _, m := range plan {
+		key := m.String()
+		seen[key] = true
+
+		data, err := render(c, m.Manifest, chartName, namespace)
+		if err != nil {
+			log.Die("Failed to render %s: %s", key, err)
+		}
+		if diffObject(key, data, namespace, false, client) {
+			drift++
+		}
+	}
+
+	if rel != nil && rel.Status != record.StatusDeleted && rel.Namespace == namespace {
+		for _, o := range rel.Manifests {
+			key := o.Kind + "/" + o.Name
+			if seen[key] {
+				continue
+			}
+			if diffObject(key, []byte(o.Data), namespace, true, client) {
+				drift++
+			}
+		}
+	}
+
+	if drift > 0 {
+		log.Err("%d objects differ from chart %s", drift, chartName)
+		return
+	}
+	log.Info("No differences found.")
+}
+
+// diffObject prints the differences between an object and its live state.
+//
+// If removed is true, the object is no longer part of the chart, and is only
+// reported if it still exists. It returns true if a difference was printed.
+func diffObject(key string, data []byte, namespace string, removed bool, client kubectl.Runner) bool {
+	live, found, err := liveObject(data, namespace, client)
+	if err != nil {
+		log.Err("Could not get %s: %s", key, err)
+		return false
+	}
+
+	if removed && !found {
+		return false
+	}
+	var desired map[string]interface{}
+	if !removed {
+		if desired, err = decodeObject(data); err != nil {
+			log.Err("Could not decode %s: %s", key, err)
+			return false
+		}
+		if found {
+			live, _ = prune(live, desired).(map[string]interface{})
+		}
+	}
+	maskSecret(live, desired)
+
+	var from, to string
+	if found {
+		from = toYAML(live)
+	}
+	if !removed {
+		to = toYAML(desired)
+	}
+
+	d := diff.Unified(from, to, "live/"+key, "chart/"+key, 3)
+	if d == "" {
+		log.Debug("%s is unchanged", key)
+		return false
+	}
+
+	switch {
+	case removed:
+		log.Msg("removed: %s", key)
+	case !found:
+		log.Msg("added: %s", key)
+	default:
+		log.Msg("changed: %s", key)
+	}
+	log.Msg(d)
+	return true
+}
+
+// liveObject gets the live state of an object from Kubernetes.
+//
+// Server-populated fields are stripped from the result. If the object does not
+// exist, found is false.
+func liveObject(data []byte, namespace string, client kubectl.Runner) (obj map[string]interface{}, found bool, err error) {
+	out, err := client.Get(data, namespace)
+	if err != nil {
+		if isNotFound(out, err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("%s %s", err, out)
+	}
+
+	obj, err = decodeObject(out)
+	if err != nil {
+		return nil, false, err
+	}
+	stripServerFields(obj)
+	return obj, true, nil
+}
+
+// maskSecret replaces the values in the data and stringData of a Secret with
+// placeholders, so that a diff only shows which keys changed.
+//
+// Either object may be nil.
+func maskSecret(live, desired map[string]interface{}) {
+	if live["kind"] != "Secret" && desired["kind"] != "Secret" {
+		return
+	}
+	for _, field := range []string{"data", "stringData"} {
+		l, _ := live[field].(map[string]interface{})
+		d, _ := desired[field].(map[string]interface{})
+		for k, dv := range d {
+			lv, ok := l[k]
+			if ok && fmt.Sprint(lv) != fmt.Sprint(dv) {
+				l[k], d[k] = maskedValue, maskedValue+" (changed)"
+				continue
+			}
+			if ok {
+				l[k] = maskedValue
+			}
+			d[k] = maskedValue
+		}
+		for k := range l {
+			if _, ok := d[k]; !ok {
+				l[k] = maskedValue
+			}
+		}
+	}
+}
+
+// maskedValue is shown in place of the values of Secrets.
+const maskedValue = "<redacted>"
+
+func decodeObject(data []byte) (map[string]interface{}, error) {
+	var obj map[string]interface{}
+	return obj, json.Unmarshal(data, &obj)
+}
+
+// stripServerFields removes the fields that Kubernetes populates on an object.
+func stripServerFields(obj map[string]interface{}) {
+	delete(obj, "status")
+
+	md, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, f := range serverFields {
+		delete(md, f)
+	}
+	if ann, ok := md["annotations"].(map[string]interface{}); ok {
+		delete(ann, "kubectl.kubernetes.io/last-applied-configuration")
+	}
+}
+
+// prune removes everything from live that is not present in desired.
+//
+// Lists are pruned element by element when they have the same length.
+func prune(live, desired interface{}) interface{} {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return live
+		}
+		res := make(map[string]interface{}, len(d))
+		for k, dv := range d {
+			if lv, ok := l[k]; ok {
+				res[k] = prune(lv, dv)
+			}
+		}
+		return res
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			return live
+		}
+		res := make([]interface{}, len(l))
+		for i := range l {
+			res[i] = prune(l[i], d[i])
+		}
+		return res
+	}
+	return live
+}
+
+func toYAML(v interface{}) string {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
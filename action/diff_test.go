@@ -0,0 +1,121 @@
+package action
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/helm/helm-classic/test"
+)
+
+// getRunner is a TestRunner that returns the given output for every Get.
+type getRunner struct {
+	TestRunner
+	get func(stdin []byte) ([]byte, error)
+}
+
+func (r getRunner) Get(stdin []byte, ns string) ([]byte, error) {
+	return r.get(stdin)
+}
+
+// liveRunner returns a getRunner that reports the object it was sent, after
+// passing it through fn.
+func liveRunner(fn func(map[string]interface{})) getRunner {
+	return getRunner{get: func(stdin []byte) ([]byte, error) {
+		var obj map[string]interface{}
+		if err := json.Unmarshal(stdin, &obj); err != nil {
+			return nil, err
+		}
+		fn(obj)
+		return json.Marshal(obj)
+	}}
+}
+
+func TestDiff(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	defer os.RemoveAll(tmpHome)
+	test.FakeUpdate(tmpHome)
+
+	pp := os.Getenv("PATH")
+	defer os.Setenv("PATH", pp)
+	os.Setenv("PATH", filepath.Join(test.HelmRoot, "testdata")+":"+pp)
+
+	Fetch("redis", "", tmpHome, nil)
+
+	tests := []struct {
+		name     string
+		client   getRunner
+		expected []string
+	}{
+		{
+			name: "with no live object",
+			client: getRunner{get: func([]byte) ([]byte, error) {
+				return []byte(`Error from server: pods "redis" not found`), errors.New("exit status 1")
+			}},
+			expected: []string{"added: Pod/redis", "+kind: Pod", "1 objects differ from chart redis"},
+		},
+		{
+			name: "with server fields",
+			client: liveRunner(func(obj map[string]interface{}) {
+				obj["status"] = map[string]interface{}{"phase": "Running"}
+				md := obj["metadata"].(map[string]interface{})
+				md["uid"] = "1234"
+				md["namespace"] = "default"
+				obj["spec"].(map[string]interface{})["dnsPolicy"] = "ClusterFirst"
+			}),
+			expected: []string{"No differences found."},
+		},
+		{
+			name: "with a changed object",
+			client: liveRunner(func(obj map[string]interface{}) {
+				obj["spec"].(map[string]interface{})["image"] = "redis:2"
+			}),
+			expected: []string{"changed: Pod/redis", "--- live/Pod/redis", "-  image: redis:2", "+  image: redis", "1 objects differ"},
+		},
+		{
+			name: "with a kubectl error",
+			client: getRunner{get: func([]byte) ([]byte, error) {
+				return []byte("unable to connect"), errors.New("exit status 1")
+			}},
+			expected: []string{"Could not get Pod/redis: exit status 1 unable to connect"},
+		},
+	}
+
+	for _, tt := range tests {
+		actual := test.CaptureOutput(func() {
+			Diff("redis", tmpHome, "default", tt.client)
+		})
+
+		for _, exp := range tt.expected {
+			test.ExpectContains(t, actual, exp)
+		}
+	}
+}
+
+func TestMaskSecret(t *testing.T) {
+	live := map[string]interface{}{
+		"kind": "Secret",
+		"data": map[string]interface{}{"same": "c2FtZQ==", "old": "b2xk", "gone": "Z29uZQ=="},
+	}
+	desired := map[string]interface{}{
+		"kind": "Secret",
+		"data": map[string]interface{}{"same": "c2FtZQ==", "old": "bmV3", "new": "bmV3"},
+	}
+	maskSecret(live, desired)
+
+	from, to := toYAML(live), toYAML(desired)
+	for _, secret := range []string{"c2FtZQ==", "b2xk", "Z29uZQ==", "bmV3"} {
+		if strings.Contains(from+to, secret) {
+			t.Errorf("Expected %q to be masked in:\n%s%s", secret, from, to)
+		}
+	}
+	test.ExpectContains(t, to, "old: <redacted> (changed)")
+	test.ExpectContains(t, to, "same: <redacted>\n")
+	test.ExpectContains(t, from, "gone: <redacted>")
+
+	maskSecret(nil, desired)
+	maskSecret(map[string]interface{}{"kind": "Secret"}, nil)
+}
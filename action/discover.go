@@ -0,0 +1,189 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/kubectl"
+	"github.com/helm/helm-classic/record"
+)
+
+// clusterObject is an object that was found in Kubernetes.
+type clusterObject struct {
+	record.Object
+	// Namespace is the namespace of the object, or "" if it is cluster-scoped.
+	Namespace string
+	// Weight is the value of the object's chart.AnnWeight annotation.
+	Weight int
+}
+
+// findObjects lists the objects in Kubernetes that were installed by a release,
+// in the order they are uninstalled.
+//
+// Objects belong to the release if their chart.AnnRelease annotation matches
+// its name. If no object carries that annotation, as is the case for releases
+// installed by older versions of Helm Classic, objects without it belong to the
+// release if their chart.AnnChartName annotation matches the release name, or
+// the name declared by the chart in the workspace or by the release record.
+// Kubernetes cannot select objects by annotation, so every object of the kinds
+// that the chart may contain is listed, and the objects are filtered here.
+//
+// If namespace is empty, every namespace is searched. The chart and the release
+// may be nil.
+func findObjects(release, namespace string, c *chart.Chart, rel *record.Release, client kubectl.Runner) ([]*clusterObject, error) {
+	names := map[string]bool{release: true}
+	var first []string
+	if c != nil {
+		names[c.Chartfile.Name] = true
+		first = c.Chartfile.UninstallOrder
+	}
+	if rel != nil && rel.Chart != "" {
+		names[rel.Chart] = true
+	}
+
+	out, err := client.List(discoveryKinds(c, rel), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s", err, out)
+	}
+	list := struct {
+		Items []map[string]interface{} `json:"items"`
+	}{}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("could not read object list: %s", err)
+	}
+
+	byRelease, byName := []*clusterObject{}, []*clusterObject{}
+	seen := map[string]bool{}
+	for _, item := range list.Items {
+		ann, _ := nested(item, "metadata", "annotations")
+		annotations, _ := ann.(map[string]interface{})
+		r, hasRelease := annotations[chart.AnnRelease].(string)
+		name, _ := annotations[chart.AnnChartName].(string)
+		if r != release && (hasRelease || !names[name]) {
+			continue
+		}
+
+		o, err := newClusterObject(item, annotations)
+		if err != nil {
+			return nil, err
+		}
+		// An object can be listed once for each API group that serves its kind.
+		key := o.Namespace + "/" + o.Kind + "/" + o.Name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if hasRelease {
+			byRelease = append(byRelease, o)
+		} else {
+			byName = append(byName, o)
+		}
+	}
+
+	objs := byRelease
+	if len(objs) == 0 {
+		objs = byName
+	}
+	kinds := []string{}
+	for _, o := range objs {
+		kinds = append(kinds, o.Kind)
+	}
+	rank := map[string]int{}
+	for i, k := range uninstallKinds(kinds, first) {
+		rank[k] = i
+	}
+	sort.Sort(byUninstall{objs, rank})
+	return objs, nil
+}
+
+// newClusterObject reads an object from a list returned by Kubernetes.
+func newClusterObject(item, annotations map[string]interface{}) (*clusterObject, error) {
+	kind, _ := item["kind"].(string)
+	name, _ := nested(item, "metadata", "name")
+	ns, _ := nested(item, "metadata", "namespace")
+	data, err := json.MarshalIndent(item, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	o := &clusterObject{Object: record.Object{Kind: kind, Data: string(data)}}
+	o.Name, _ = name.(string)
+	o.Namespace, _ = ns.(string)
+	if w, ok := annotations[chart.AnnWeight].(string); ok {
+		o.Weight, _ = strconv.Atoi(w)
+	}
+	return o, nil
+}
+
+// discoveryKinds returns the kinds that are searched for the objects of a chart.
+//
+// These are the kinds in InstallOrder and UninstallOrder, plus the kinds in the
+// chart and in its release record, qualified by their apiVersion. The chart and
+// the release may be nil.
+func discoveryKinds(c *chart.Chart, rel *record.Release) []string {
+	kinds := mergeOrder(InstallOrder, UninstallOrder)
+	seen := map[string]bool{}
+	extra := []string{}
+	add := func(k string) {
+		if !seen[k] {
+			seen[k] = true
+			extra = append(extra, k)
+		}
+	}
+	if c != nil {
+		for k, ms := range c.Kind {
+			for _, m := range ms {
+				add(kubectl.QualifiedKind(m.Version, k))
+			}
+		}
+	}
+	if rel != nil {
+		for _, o := range rel.Manifests {
+			add(objectKind(o))
+		}
+	}
+	sort.Strings(extra)
+	return mergeOrder(kinds, extra)
+}
+
+// objectNamespaces returns the namespaces that contain the given objects,
+// sorted by name. If they are all cluster-scoped, def is returned.
+func objectNamespaces(objs []*clusterObject, def string) []string {
+	seen := map[string]bool{}
+	res := []string{}
+	for _, o := range objs {
+		if o.Namespace != "" && !seen[o.Namespace] {
+			seen[o.Namespace] = true
+			res = append(res, o.Namespace)
+		}
+	}
+	if len(res) == 0 {
+		return []string{def}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// byUninstall sorts objects by weight, highest first, then by the rank of
+// their kind, namespace, and name.
+type byUninstall struct {
+	objs []*clusterObject
+	rank map[string]int
+}
+
+func (b byUninstall) Len() int      { return len(b.objs) }
+func (b byUninstall) Swap(i, j int) { b.objs[i], b.objs[j] = b.objs[j], b.objs[i] }
+func (b byUninstall) Less(i, j int) bool {
+	x, y := b.objs[i], b.objs[j]
+	switch {
+	case x.Weight != y.Weight:
+		return x.Weight > y.Weight
+	case b.rank[x.Kind] != b.rank[y.Kind]:
+		return b.rank[x.Kind] < b.rank[y.Kind]
+	case x.Namespace != y.Namespace:
+		return x.Namespace < y.Namespace
+	}
+	return x.Name < y.Name
+}
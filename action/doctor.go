@@ -3,6 +3,7 @@ package action
 import (
 	"os/exec"
 
+	"github.com/helm/helm-classic/kubectl"
 	"github.com/helm/helm-classic/log"
 	helm "github.com/helm/helm-classic/util"
 )
@@ -26,7 +27,18 @@ func CheckAllPrereqs(home string) {
 // CheckKubePrereqs makes sure we have the tools necessary to interact
 // with a kubernetes cluster
 func CheckKubePrereqs() {
-	ensureCommand("kubectl")
+	client := kubectl.Client
+	if r, ok := client.(*kubectl.Recorder); ok {
+		client = r.Runner
+	}
+	switch c := client.(type) {
+	case *kubectl.APIRunner:
+		// The API client talks to the API server directly, without kubectl.
+	case kubectl.RealRunner:
+		ensureCommand(c.Binary())
+	default:
+		ensureCommand(kubectl.Path)
+	}
 }
 
 // CheckLocalPrereqs makes sure we have all the tools we need to work with
@@ -1,30 +1,60 @@
 package action
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/config"
 	"github.com/helm/helm-classic/dependency"
 	"github.com/helm/helm-classic/log"
 	helm "github.com/helm/helm-classic/util"
 )
 
+// FetchOptions controls how a chart is fetched.
+type FetchOptions struct {
+	// WithDeps also fetches the chart's dependencies, and theirs in turn.
+	WithDeps bool
+	// Verify refuses charts that are not signed by a key in the keyring.
+	Verify bool
+}
+
 // Fetch gets a chart from the source repo and copies to the workdir.
 //
-// - chartName is the source
+// - chartName is the source, or the path or URL of a chart archive
 // - lname is the local name for that chart (chart-name); if blank, it is set to the chart.
 // - homedir is the home directory for the user
-func Fetch(chartName, lname, homedir string) {
+// - opts may be nil, which fetches the chart alone without verifying it
+//
+// A source may be followed by @ and a version or semver constraint, as in
+// redis@~0.3, to fetch that version from the history of its table.
+//
+// Charts from a table that is configured to verify signatures are always
+// verified.
+func Fetch(chartName, lname, homedir string, opts *FetchOptions) {
+	if opts == nil {
+		opts = &FetchOptions{}
+	}
 
-	r := mustConfig(homedir).Repos
-	repository, chartName := r.RepoChart(chartName)
+	if isArchive(chartName) {
+		lname = fetchArchive(chartName, lname, homedir, opts.Verify)
+	} else {
+		r := mustConfig(homedir).Repos
+		name, version := splitVersion(chartName)
+		repository, chartName := r.RepoChart(name)
 
-	if lname == "" {
-		lname = chartName
-	}
+		if lname == "" {
+			lname = chartName
+		}
 
-	fetch(chartName, lname, homedir, repository)
+		if version != "" {
+			fetchVersion(chartName, lname, homedir, repository, version, opts.Verify)
+		} else {
+			fetch(chartName, lname, homedir, repository, opts.Verify)
+		}
+	}
 
 	chartFilePath := helm.WorkspaceChartDirectory(homedir, lname, Chartfile)
 	cfile, err := chart.LoadChartfile(chartFilePath)
@@ -32,6 +62,10 @@ func Fetch(chartName, lname, homedir string) {
 		log.Die("Source is not a valid chart. Missing Chart.yaml: %s", err)
 	}
 
+	if opts.WithDeps {
+		fetchDeps(cfile, homedir, lname, opts.Verify)
+	}
+
 	deps, err := dependency.Resolve(cfile, helm.WorkspaceChartDirectory(homedir))
 	if err != nil {
 		log.Warn("Could not check dependencies: %s", err)
@@ -44,19 +78,76 @@ func Fetch(chartName, lname, homedir string) {
 			log.Msg("\t%s %s", d.Name, d.Version)
 		}
 	}
+	warnLock(homedir, lname)
 
 	log.Info("Fetched chart into workspace %s", helm.WorkspaceChartDirectory(homedir, lname))
 	log.Info("Done")
 }
 
-func fetch(chartName, lname, homedir, chartpath string) {
+// splitVersion splits a chart name of the form "name@version".
+func splitVersion(name string) (string, string) {
+	if i := strings.LastIndex(name, "@"); i > 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+// fetchVersion copies a chart from the history of a table into the
+// workspace, at the highest version that matches constraint.
+//
+// The commit that the chart was taken from is recorded in its From.
+func fetchVersion(chartName, lname, homedir, table, constraint string, verify bool) {
+	r := mustConfig(homedir).Repos
+	commit, version, err := r.FindVersion(table, chartName, constraint)
+	if err != nil {
+		log.Die("Could not find %s@%s: %s", chartName, constraint, err)
+	}
+	log.Info("Fetching %s %s from %s at %s", chartName, version, table, shortSHA(commit))
+
+	tmp, err := ioutil.TempDir("", "helmc-fetch")
+	if err != nil {
+		log.Die("Could not create a temporary directory: %s", err)
+	}
+	defer os.RemoveAll(tmp)
+	if err := r.ExportChart(table, commit, chartName, tmp); err != nil {
+		log.Die("Could not fetch %s from %s: %s", chartName, table, err)
+	}
+	if verify || tableVerifies(homedir, table) {
+		verifyChart(tmp, chartName, homedir)
+	}
+	cf, err := chart.LoadChartfile(filepath.Join(tmp, Chartfile))
+	if err != nil {
+		log.Die("Source is not a valid chart. Missing Chart.yaml: %s", err)
+	}
+
+	dest := helm.WorkspaceChartDirectory(homedir, lname)
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		log.Die("Could not create %q: %s", dest, err)
+	}
+	log.Debug("Fetching %s at %s to %s", chartName, commit, dest)
+	if err := helm.CopyDir(tmp, dest); err != nil {
+		log.Die("Failed copying %s to %s", chartName, dest)
+	}
+
+	cf.From = &chart.Dependency{Name: cf.Name, Version: cf.Version, Repo: r.Table(table).Repo, Commit: commit}
+	cf.Name = lname
+	if err := cf.Save(filepath.Join(dest, Chartfile)); err != nil {
+		log.Die("Failed to update Chart.yaml: %s", err)
+	}
+}
+
+// fetch copies a chart from a table into the workspace.
+//
+// If verify is true, or the table is configured to verify signatures, the
+// chart is verified before it is copied.
+func fetch(chartName, lname, homedir, chartpath string, verify bool) {
 	src := helm.CacheDirectory(homedir, chartpath, chartName)
 	dest := helm.WorkspaceChartDirectory(homedir, lname)
 
 	fi, err := os.Stat(src)
 	if err != nil {
 		log.Warn("Oops. Looks like there was an issue finding the chart, %s, in %s. Running `helmc update` to ensure you have the latest version of all Charts from Github...", lname, src)
-		Update(homedir)
+		Update(homedir, config.DefaultUpdateWorkers)
 		fi, err = os.Stat(src)
 		if err != nil {
 			log.Die("Chart %s not found in %s", lname, src)
@@ -68,6 +159,10 @@ func fetch(chartName, lname, homedir, chartpath string) {
 		log.Die("Malformed chart %s: Chart must be in a directory.", chartName)
 	}
 
+	if verify || tableVerifies(homedir, chartpath) {
+		verifyChart(src, chartName, homedir)
+	}
+
 	if err := os.MkdirAll(dest, 0755); err != nil {
 		log.Die("Could not create %q: %s", dest, err)
 	}
@@ -77,12 +172,18 @@ func fetch(chartName, lname, homedir, chartpath string) {
 		log.Die("Failed copying %s to %s", src, dest)
 	}
 
-	if err := updateChartfile(src, dest, lname); err != nil {
+	var repo string
+	if t := mustConfig(homedir).Repos.Table(chartpath); t != nil && t.IsHTTP() {
+		repo = t.Repo
+	} else {
+		repo = chart.RepoName(src)
+	}
+	if err := updateChartfile(src, dest, lname, repo); err != nil {
 		log.Die("Failed to update Chart.yaml: %s", err)
 	}
 }
 
-func updateChartfile(src, dest, lname string) error {
+func updateChartfile(src, dest, lname, repo string) error {
 	sc, err := chart.LoadChartfile(filepath.Join(src, Chartfile))
 	if err != nil {
 		return err
@@ -97,7 +198,7 @@ func updateChartfile(src, dest, lname string) error {
 	dc.From = &chart.Dependency{
 		Name:    sc.Name,
 		Version: sc.Version,
-		Repo:    chart.RepoName(src),
+		Repo:    repo,
 	}
 
 	return dc.Save(filepath.Join(dest, Chartfile))
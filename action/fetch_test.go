@@ -1,8 +1,10 @@
 package action
 
 import (
+	"os"
 	"testing"
 
+	"github.com/helm/helm-classic/chart"
 	"github.com/helm/helm-classic/test"
 	"github.com/helm/helm-classic/util"
 )
@@ -13,9 +15,38 @@ func TestFetch(t *testing.T) {
 	chartName := "kitchensink"
 
 	actual := test.CaptureOutput(func() {
-		Fetch(chartName, "", tmpHome)
+		Fetch(chartName, "", tmpHome, nil)
 	})
 
 	workspacePath := util.WorkspaceChartDirectory(tmpHome, chartName)
 	test.ExpectContains(t, actual, "Fetched chart into workspace "+workspacePath)
 }
+
+func TestFetchVersion(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	defer os.RemoveAll(tmpHome)
+	test.FakeUpdate(tmpHome)
+
+	table := util.CacheDirectory(tmpHome, "charts")
+	git(t, table, "init", "-q")
+	shas := map[string]string{}
+	for _, v := range []string{"0.3.0", "0.3.1", "0.4.0"} {
+		writeDepChart(t, tmpHome, "db", v, "")
+		git(t, table, "add", ".")
+		git(t, table, "commit", "-q", "-m", "db "+v)
+		shas[v] = git(t, table, "rev-parse", "HEAD")
+	}
+
+	actual := test.CaptureOutput(func() {
+		Fetch("db@~0.3", "olddb", tmpHome, nil)
+	})
+	test.ExpectContains(t, actual, "Fetching db 0.3.1 from charts at "+shas["0.3.1"][:7])
+
+	cf, err := chart.LoadChartfile(util.WorkspaceChartDirectory(tmpHome, "olddb", Chartfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.Name != "olddb" || cf.From.Name != "db" || cf.From.Version != "0.3.1" || cf.From.Commit != shas["0.3.1"] {
+		t.Errorf("Expected olddb to be db 0.3.1 at %s, got %+v from %+v", shas["0.3.1"], cf, cf.From)
+	}
+}
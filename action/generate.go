@@ -13,19 +13,16 @@ import (
 // Generate runs generators on the entire chart.
 //
 // By design, this only operates on workspaces, as it should never be run
-// on the cache.
+// on the cache. The paths in the configuration's exclude list are skipped in
+// addition to exclude.
 func Generate(chart, homedir string, exclude []string, force bool) {
 	if abs, err := filepath.Abs(homedir); err == nil {
 		homedir = abs
 	}
 	chartPath := util.WorkspaceChartDirectory(homedir, chart)
 
-	// Although helmc itself may use the new HELMC_HOME environment variable to optionally define its
-	// home directory, to maintain compatibility with charts created for the ORIGINAL helm, we
-	// continue to support expansion of these "legacy" environment variables, including HELM_HOME.
-	os.Setenv("HELM_HOME", homedir)
-	os.Setenv("HELM_DEFAULT_REPO", mustConfig(homedir).Repos.Default)
-	os.Setenv("HELM_FORCE_FLAG", strconv.FormatBool(force))
+	setGeneratorEnv(homedir, force)
+	exclude = append(mustConfig(homedir).Exclude, exclude...)
 
 	count, err := generator.Walk(chartPath, exclude, force)
 	if err != nil {
@@ -33,3 +30,13 @@ func Generate(chart, homedir string, exclude []string, force bool) {
 	}
 	log.Info("Ran %d generators.", count)
 }
+
+// setGeneratorEnv sets the environment variables that generators and hooks can use.
+func setGeneratorEnv(homedir string, force bool) {
+	// Although helmc itself may use the new HELMC_HOME environment variable to optionally define its
+	// home directory, to maintain compatibility with charts created for the ORIGINAL helm, we
+	// continue to support expansion of these "legacy" environment variables, including HELM_HOME.
+	os.Setenv("HELM_HOME", homedir)
+	os.Setenv("HELM_DEFAULT_REPO", mustConfig(homedir).Repos.Default)
+	os.Setenv("HELM_FORCE_FLAG", strconv.FormatBool(force))
+}
@@ -12,7 +12,7 @@ func TestGenerate(t *testing.T) {
 	ch := "generate"
 	homedir := test.CreateTmpHome()
 	test.FakeUpdate(homedir)
-	Fetch(ch, ch, homedir)
+	Fetch(ch, ch, homedir, nil)
 
 	Generate(ch, homedir, []string{"ignore"}, true)
 
@@ -0,0 +1,27 @@
+package action
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/helm/helm-classic/log"
+	"github.com/helm/helm-classic/record"
+	helm "github.com/helm/helm-classic/util"
+)
+
+// History prints every recorded revision of a release.
+func History(name, homedir string) {
+	h, err := record.History(helm.ReleaseDirectory(homedir), name)
+	if err == record.ErrNoRelease {
+		log.Die("No release named %q.", name)
+	} else if err != nil {
+		log.Die("Could not load history of release %s: %s", name, err)
+	}
+
+	w := tabwriter.NewWriter(log.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "REVISION\tUPDATED\tSTATUS\tVERSION\tNAMESPACE\tDESCRIPTION")
+	for _, r := range h {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", r.Revision, r.Timestamp, r.Status, r.Version, r.Namespace, r.Description)
+	}
+	w.Flush()
+}
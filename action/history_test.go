@@ -0,0 +1,34 @@
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/helm/helm-classic/test"
+)
+
+func TestHistory(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	defer os.RemoveAll(tmpHome)
+	test.FakeUpdate(tmpHome)
+
+	pp := os.Getenv("PATH")
+	defer os.Setenv("PATH", pp)
+	os.Setenv("PATH", filepath.Join(test.HelmRoot, "testdata")+":"+pp)
+
+	actual := test.CaptureOutput(func() {
+		History("redis", tmpHome)
+	})
+	test.ExpectContains(t, actual, "No release named \"redis\".")
+
+	test.CaptureOutput(func() {
+		Install("redis", tmpHome, &InstallOptions{Namespace: "default"}, TestRunner{})
+	})
+
+	actual = test.CaptureOutput(func() {
+		History("redis", tmpHome)
+	})
+	test.ExpectMatches(t, actual, `REVISION\s+UPDATED\s+STATUS\s+VERSION\s+NAMESPACE\s+DESCRIPTION`)
+	test.ExpectMatches(t, actual, `1\s+\S+\s+DEPLOYED\s+0.0.1\s+default\s+Install complete`)
+}
@@ -0,0 +1,179 @@
+package action
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/generator"
+	"github.com/helm/helm-classic/kubectl"
+	"github.com/helm/helm-classic/log"
+	"github.com/helm/helm-classic/record"
+)
+
+// hookTimeout is how long a hook Job may run before the hook fails.
+var hookTimeout = 5 * time.Minute
+
+// runHooks runs the commands and Jobs that a chart declares for a hook event.
+//
+// Commands are declared in Chart.yaml, and run first, sorted by name. They run
+// in the chart directory, with the same environment variables as generators,
+// plus HELM_HOOK and HELM_NAMESPACE. Since they run on this machine, they only
+// run if allow is true, and are listed before any of them runs. See
+// checkHookCommands.
+//
+// Hook Jobs are then created in the namespace one at a time, and each must
+// complete before the next one starts. A Job that completes is deleted again,
+// so that the hook can run the next time. A Job that fails is left in place,
+// so that it can be inspected, and is returned in a *hookJobError. A Job of the
+// same name that an earlier run left behind is deleted before the Job is
+// created.
+//
+// If timeout is zero, hookTimeout is used. On a dry run, commands are printed
+// instead of run, and Jobs are not waited for.
+//
+// The first failure stops the hook, and is returned.
+func runHooks(event string, c *chart.Chart, dir, home, namespace string, force, allow bool, timeout time.Duration, client kubectl.Runner) error {
+	cmds := c.Chartfile.HookCommands(event)
+	jobs := c.Hooks[event]
+	if len(cmds) == 0 && len(jobs) == 0 {
+		return nil
+	}
+	if timeout == 0 {
+		timeout = hookTimeout
+	}
+	_, dry := client.(kubectl.PrintRunner)
+
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) > 0 && !dry {
+		if !allow {
+			return fmt.Errorf("the hook runs commands on this machine. Pass --run-hooks to allow it")
+		}
+		log.Info("The %s hook of %s runs these commands on this machine:", event, c.Chartfile.Name)
+		for _, name := range names {
+			log.Msg("\t%s: %s", name, cmds[name])
+		}
+	}
+	if len(names) > 0 {
+		setGeneratorEnv(home, force)
+		os.Setenv("HELM_HOOK", event)
+		os.Setenv("HELM_NAMESPACE", namespace)
+	}
+	for _, name := range names {
+		line := cmds[name]
+		os.Setenv("HELM_GENERATE_COMMAND", line)
+		os.Setenv("HELM_GENERATE_FILE", filepath.Join(dir, Chartfile))
+		os.Setenv("HELM_GENERATE_DIR", dir)
+		line = os.ExpandEnv(line)
+		os.Setenv("HELM_GENERATE_COMMAND_EXPANDED", line)
+
+		if dry {
+			log.Msg("[HOOK] %s %s: %s", event, name, line)
+			continue
+		}
+		log.Info("Running %s hook %s: %s", event, name, line)
+		cmd, err := generator.Command(line, force)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		cmd.Dir = dir
+		cmd.Stdout = log.Stdout
+		cmd.Stderr = log.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+	}
+
+	for _, m := range jobs {
+		data, err := render(c, m, filepath.Base(dir), namespace)
+		if err != nil {
+			return fmt.Errorf("Job/%s: %s", m.Name, err)
+		}
+		kind := kubectl.QualifiedKind(m.Version, "Job")
+		if !dry {
+			out, err := client.Delete(m.Name, kind, namespace)
+			if err == nil {
+				log.Info("Deleted Job/%s, which an earlier run of the hook left behind", m.Name)
+			} else if !isNotFound(out, err) {
+				log.Debug("Could not delete an earlier hook Job/%s: %s %s", m.Name, err, out)
+			}
+		}
+		log.Info("Running %s hook Job/%s", event, m.Name)
+		out, err := client.Create(data, namespace)
+		log.Msg(string(out))
+		if err != nil {
+			return fmt.Errorf("Job/%s: %s", m.Name, err)
+		}
+		if dry {
+			continue
+		}
+
+		job := &record.Object{Kind: "Job", Name: m.Name, Data: string(data)}
+		if err := waitForReady([]*record.Object{job}, namespace, timeout, client); err != nil {
+			return &hookJobError{job: job, err: err}
+		}
+		if out, err := client.Delete(m.Name, kind, namespace); err != nil {
+			log.Warn("Could not delete hook Job/%s: %s %s", m.Name, err, out)
+		}
+	}
+	return nil
+}
+
+// hookJobError is returned by runHooks when a hook Job fails. The Job is left in
+// Kubernetes.
+type hookJobError struct {
+	job *record.Object
+	err error
+}
+
+func (e *hookJobError) Error() string {
+	return fmt.Sprintf("Job/%s: %s", e.job.Name, e.err)
+}
+
+// leftJob returns the hook Job that a failed hook left in Kubernetes, if any.
+func leftJob(err error) []*record.Object {
+	if e, ok := err.(*hookJobError); ok {
+		return []*record.Object{e.job}
+	}
+	return nil
+}
+
+// checkHookCommands exits if a chart's hooks would run commands on this
+// machine for the given events and allow is false. The commands are listed
+// first, so that they can be reviewed.
+//
+// It is called before anything is changed, so that a hook that is not allowed
+// does not leave a chart half installed or uninstalled.
+func checkHookCommands(c *chart.Chart, allow bool, events ...string) {
+	if allow {
+		return
+	}
+	lines := []string{}
+	for _, event := range events {
+		cmds := c.Chartfile.HookCommands(event)
+		names := make([]string, 0, len(cmds))
+		for name := range cmds {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			lines = append(lines, fmt.Sprintf("%s %s: %s", event, name, cmds[name]))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	log.Warn("Chart %s runs these commands on this machine in its hooks:", c.Chartfile.Name)
+	for _, l := range lines {
+		log.Msg("\t%s", l)
+	}
+	log.Die("Not running hook commands without --run-hooks. Review them, then pass --run-hooks to allow them.")
+}
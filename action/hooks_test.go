@@ -0,0 +1,130 @@
+package action
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/kubectl"
+	"github.com/helm/helm-classic/record"
+	"github.com/helm/helm-classic/test"
+	"github.com/helm/helm-classic/util"
+)
+
+const hookJob = `apiVersion: extensions/v1beta1
+kind: Job
+metadata:
+  name: hooked-migrate
+  annotations:
+    chart.helm.sh/hook: postinstall
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+      - name: migrate
+        image: alpine:3.2
+`
+
+func TestHooks(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	defer os.RemoveAll(tmpHome)
+	test.FakeUpdate(tmpHome)
+
+	pp := os.Getenv("PATH")
+	defer os.Setenv("PATH", pp)
+	os.Setenv("PATH", filepath.Join(test.HelmRoot, "testdata")+":"+pp)
+
+	Create("hooked", tmpHome)
+	dir := util.WorkspaceChartDirectory(tmpHome, "hooked")
+	cf, err := chart.LoadChartfile(filepath.Join(dir, Chartfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf.PreInstall = map[string]string{"keys": "touch $HELM_GENERATE_DIR/pre-$HELM_NAMESPACE"}
+	cf.PostInstall = map[string]string{"done": "touch $HELM_HOOK"}
+	cf.PreDelete = map[string]string{"refuse": "false"}
+	if err := cf.Save(filepath.Join(dir, Chartfile)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "manifests", "hook-job.yaml"), []byte(hookJob), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var transcript bytes.Buffer
+	client := kubectl.NewRecorder(liveRunner(func(obj map[string]interface{}) {
+		obj["status"] = map[string]interface{}{"succeeded": 1}
+	}), &transcript)
+
+	actual := test.CaptureOutput(func() {
+		Install("hooked", tmpHome, &InstallOptions{Namespace: "default"}, client)
+	})
+	test.ExpectContains(t, actual, "Not running hook commands without --run-hooks.")
+	if transcript.Len() != 0 {
+		t.Errorf("Expected nothing to be sent to Kubernetes, got %s", transcript.String())
+	}
+
+	actual = test.CaptureOutput(func() {
+		Install("hooked", tmpHome, &InstallOptions{Namespace: "default", RunHooks: true}, client)
+	})
+	test.ExpectContains(t, actual, "The preinstall hook of hooked runs these commands on this machine:")
+	for _, f := range []string{"pre-default", "postinstall"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err != nil {
+			t.Errorf("Expected hook to create %s: %s", f, err)
+		}
+	}
+
+	// The hook Job is created, waited for, and deleted, but is not part of the release.
+	calls := transcript.String()
+	for _, c := range []string{`"method":"Get","namespace":"default"`, `"method":"Delete","namespace":"default","kind":"Job","name":"hooked-migrate"`} {
+		test.ExpectContains(t, calls, c)
+	}
+	rel, err := record.Load(util.ReleaseDirectory(tmpHome), "hooked")
+	if err != nil {
+		t.Fatalf("Failed to load release: %s", err)
+	}
+	test.ExpectEquals(t, rel.Status, record.StatusDeployed)
+	if len(rel.Manifests) != 1 || rel.Manifests[0].Kind != "Pod" {
+		t.Errorf("Expected only the Pod to be released, got %v", rel.Manifests)
+	}
+
+	// A hook Job that fails is left in place, but is deleted before the hook
+	// runs again, and by an atomic install.
+	defer func(d time.Duration) { waitInterval = d }(waitInterval)
+	waitInterval = time.Millisecond
+	transcript.Reset()
+	failing := kubectl.NewRecorder(liveRunner(func(obj map[string]interface{}) {
+		if obj["kind"] == "Pod" {
+			obj["status"] = map[string]interface{}{
+				"phase":      "Running",
+				"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+			}
+		}
+	}), &transcript)
+	test.CaptureOutput(func() {
+		Install("hooked", tmpHome, &InstallOptions{Namespace: "default", RunHooks: true, Atomic: true, Wait: 5 * time.Millisecond}, failing)
+	})
+	deleteJob := `"method":"Delete","namespace":"default","kind":"Job","name":"hooked-migrate"`
+	if n := strings.Count(transcript.String(), deleteJob); n != 2 {
+		t.Errorf("Expected the hook Job to be deleted before it runs and after it fails, got %d deletes:\n%s", n, transcript.String())
+	}
+	rel, err = record.Load(util.ReleaseDirectory(tmpHome), "hooked")
+	if err != nil {
+		t.Fatalf("Failed to load release: %s", err)
+	}
+	test.ExpectContains(t, rel.Description, "rolled back 2 objects")
+
+	transcript.Reset()
+	actual = test.CaptureOutput(func() {
+		Uninstall("hooked", tmpHome, "default", true, true, client)
+	})
+	test.ExpectContains(t, actual, "Aborting uninstall. The predelete hook failed: refuse: exit status 1")
+	if strings.Contains(transcript.String(), `"method":"Delete"`) {
+		t.Errorf("Expected nothing to be deleted, got %s", transcript.String())
+	}
+}
@@ -1,20 +1,25 @@
 package action
 
 import (
+	"fmt"
 	"os"
+	"sort"
+	"time"
 
 	"github.com/helm/helm-classic/chart"
 	"github.com/helm/helm-classic/dependency"
 	"github.com/helm/helm-classic/kubectl"
 	"github.com/helm/helm-classic/log"
 	"github.com/helm/helm-classic/manifest"
+	"github.com/helm/helm-classic/record"
 	helm "github.com/helm/helm-classic/util"
 )
 
-// InstallOrder defines the order in which manifests should be installed, by Kind.
+// InstallOrder defines the order in which manifests should be installed, by Kind,
+// when they do not reference each other.
 //
-// Anything not on the list will be installed after the last listed item, in
-// an indeterminate order.
+// Anything not on the list will be installed after the last listed item,
+// sorted by Kind.
 var InstallOrder = []string{"Namespace", "Secret", "ConfigMap", "PersistentVolume", "ServiceAccount", "Service", "Pod", "ReplicationController", "Deployment", "DaemonSet", "Ingress", "Job"}
 
 // UninstallOrder defines the order in which manifests are uninstalled.
@@ -24,111 +29,417 @@ var InstallOrder = []string{"Namespace", "Secret", "ConfigMap", "PersistentVolum
 // types depend on non-core types.
 var UninstallOrder = []string{"Service", "Pod", "ReplicationController", "Deployment", "DaemonSet", "ConfigMap", "Secret", "PersistentVolume", "ServiceAccount", "Ingress", "Job", "Namespace"}
 
+// InstallOptions controls how a chart is installed.
+type InstallOptions struct {
+	// Namespace is the Kubernetes destination namespace.
+	Namespace string
+	// DefaultNamespace is the namespace that Kubernetes uses when Namespace is
+	// empty, as set by the kubeconfig context. It is recorded in the release
+	// in place of an empty Namespace, so that uninstall knows where to look.
+	DefaultNamespace string
+	// Force installs the chart even if its dependencies are not satisfied.
+	Force bool
+	// Generate runs the generator before installing.
+	Generate bool
+	// Exclude lists files and directories that the generator skips.
+	Exclude []string
+	// ConfigMap also stores the release record as a ConfigMap in the
+	// destination namespace.
+	ConfigMap bool
+	// Atomic deletes every object that was created if the install fails.
+	Atomic bool
+	// Wait is how long to wait for the chart's workloads to become ready.
+	// If it is zero, Install does not wait.
+	Wait time.Duration
+	// CreateNamespace creates the destination namespace if it does not exist.
+	CreateNamespace bool
+	// WithDeps fetches and installs the chart's dependencies, and theirs in
+	// turn, before the chart.
+	WithDeps bool
+	// Verify refuses charts that are not signed by a key in the keyring.
+	Verify bool
+	// RunHooks allows the chart's hooks to run commands on this machine.
+	RunHooks bool
+}
+
+// releaseNamespace returns the namespace that a release is recorded in.
+func (o *InstallOptions) releaseNamespace() string {
+	if o.Namespace == "" {
+		return o.DefaultNamespace
+	}
+	return o.Namespace
+}
+
 // Install loads a chart into Kubernetes.
 //
 // If the chart is not found in the workspace, it is fetched and then installed.
+// If chartName is the path or URL of a chart archive, the archive is unpacked
+// into the workspace first, replacing any chart of the same name.
+//
+// During install, manifests are sent to Kubernetes in the order computed by
+// installPlan: objects come after the objects they reference, and otherwise in
+// the order specified by InstallOrder. On a dry run, the order is printed.
+//
+// Every install is recorded as a release in $HELMC_HOME/releases. If
+// opts.ConfigMap is true, the release record is also stored as a ConfigMap in
+// the target namespace.
+//
+// The chart's preinstall hooks run before anything is sent to Kubernetes, and
+// the install is aborted if they fail. Its postinstall hooks run once the
+// manifests have been uploaded.
 //
-// During install, manifests are sent to Kubernetes in the ordered specified by InstallOrder.
-func Install(chartName, home, namespace string, force bool, generate bool, exclude []string, client kubectl.Runner) {
-	ochart := chartName
-	r := mustConfig(home).Repos
-	table, chartName := r.RepoChart(chartName)
+// Every namespaced manifest is installed into opts.Namespace. Install stops
+// before anything is sent to Kubernetes if a manifest declares a different
+// namespace. If opts.CreateNamespace is true, the namespace is created first
+// when it does not exist.
+//
+// If opts.Wait is set, Install waits for the chart's workloads to become ready,
+// and fails if they are not ready in time.
+//
+// If opts.Atomic is true and the install fails, every object that was created
+// during the install is deleted again, including a hook Job that failed and a
+// namespace created by opts.CreateNamespace.
+//
+// If opts.WithDeps is true, the chart's dependencies are fetched and installed
+// first. See installDeps.
+//
+// If opts.Verify is true, or the chart is named by a table that is configured
+// to verify signatures, the chart in the workspace must be signed by a key in
+// the keyring, and must not have changed since it was signed.
+//
+// If the chart's hooks run commands on this machine, opts.RunHooks must be
+// true, or nothing is installed. See checkHookCommands.
+func Install(chartName, home string, opts *InstallOptions, client kubectl.Runner) {
+	verify := opts.Verify
+	if isArchive(chartName) {
+		log.Info("Fetching chart archive %s", chartName)
+		chartName = fetchArchive(chartName, "", home, verify)
+	} else {
+		ochart := chartName
+		r := mustConfig(home).Repos
+		table, name := r.RepoChart(chartName)
+		chartName = name
+		verify = verify || tableVerifies(home, table)
 
-	if !chartFetched(chartName, home) {
-		log.Info("No chart named %q in your workspace. Fetching now.", ochart)
-		fetch(chartName, chartName, home, table)
+		if !chartFetched(chartName, home) {
+			log.Info("No chart named %q in your workspace. Fetching now.", ochart)
+			fetch(chartName, chartName, home, table, verify)
+		}
 	}
 
 	cd := helm.WorkspaceChartDirectory(home, chartName)
+	if verify {
+		verifyChart(cd, chartName, home)
+	}
 	c, err := chart.Load(cd)
 	if err != nil {
 		log.Die("Failed to load chart: %s", err)
 	}
 
+	if opts.WithDeps {
+		installDeps(c.Chartfile, home, chartName, opts, client)
+	}
+
 	// Give user the option to bale if dependencies are not satisfied.
-	nope, err := dependency.Resolve(c.Chartfile, helm.WorkspaceChartDirectory(home))
+	checkDependencies(c.Chartfile, home, "install", opts.Force)
+	warnLock(home, chartName)
 
+	// Run the generator if -g is set.
+	if opts.Generate {
+		Generate(chartName, home, opts.Exclude, opts.Force)
+	}
+
+	plan, err := installPlan(c)
 	if err != nil {
-		log.Warn("Failed to check dependencies: %s", err)
-		if !force {
-			log.Die("Re-run with --force to install anyway.")
-		}
-	} else if len(nope) > 0 {
-		log.Warn("Unsatisfied dependencies:")
-		for _, d := range nope {
-			log.Msg("\t%s %s", d.Name, d.Version)
+		log.Die("Cannot install %s: %s", chartName, err)
+	}
+	if err := checkNamespaces(c, opts.Namespace); err != nil {
+		log.Die("Cannot install %s into namespace %q: %s. Remove the namespace from these manifests, or install into the namespace they declare.", chartName, opts.Namespace, err)
+	}
+	_, dry := client.(kubectl.PrintRunner)
+	if dry {
+		printPlan(plan)
+	} else {
+		checkHookCommands(c, opts.RunHooks, chart.HookPreInstall, chart.HookPostInstall)
+	}
+
+	CheckKubePrereqs()
+
+	// extra holds the objects that the install creates outside of the release,
+	// so that a failed install can clean them up too.
+	var extra []*record.Object
+
+	if opts.CreateNamespace {
+		ns, err := ensureNamespace(c, opts.Namespace, client)
+		if err != nil {
+			log.Die("Failed to create namespace %q: %s", opts.Namespace, err)
 		}
-		if !force {
-			log.Die("Stopping install. Re-run with --force to install anyway.")
+		if ns != nil {
+			extra = append(extra, ns)
 		}
 	}
 
-	// Run the generator if -g is set.
-	if generate {
-		Generate(chartName, home, exclude, force)
+	if err := runHooks(chart.HookPreInstall, c, cd, home, opts.Namespace, opts.Force, opts.RunHooks, opts.Wait, client); err != nil {
+		extra = append(extra, leftJob(err)...)
+		if opts.Atomic {
+			log.Err("Aborting install. The preinstall hook failed: %s", err)
+			deleteCreated(extra, opts.Namespace, client)
+		} else {
+			listLeft(extra)
+		}
+		log.Die("Aborting install. The preinstall hook failed: %s", err)
 	}
 
-	CheckKubePrereqs()
+	rel := record.New(chartName, opts.releaseNamespace(), c.Chartfile)
 
 	log.Info("Running `kubectl create -f` ...")
-	if err := uploadManifests(c, namespace, client); err != nil {
-		log.Die("Failed to upload manifests: %s", err)
+	if err := uploadManifests(c, plan, opts.Namespace, client, rel); err != nil {
+		failInstall(home, rel, extra, opts, client, "Failed to upload manifests", err)
+	}
+
+	if opts.Wait > 0 {
+		if dry {
+			log.Info("Dry run. Not waiting for objects to become ready.")
+		} else if err := waitForReady(rel.Manifests, opts.Namespace, opts.Wait, client); err != nil {
+			failInstall(home, rel, extra, opts, client, "Chart did not become ready", err)
+		}
+	}
+
+	if err := runHooks(chart.HookPostInstall, c, cd, home, opts.Namespace, opts.Force, opts.RunHooks, opts.Wait, client); err != nil {
+		failInstall(home, rel, append(extra, leftJob(err)...), opts, client, "The postinstall hook failed", err)
 	}
+
+	rel.Status = record.StatusDeployed
+	rel.Description = "Install complete"
+	saveRelease(home, rel, opts.ConfigMap, client)
 	log.Info("Done")
 
 	PrintREADME(chartName, home)
 }
 
-// uploadManifests sends manifests to Kubectl in a particular order.
-func uploadManifests(c *chart.Chart, namespace string, client kubectl.Runner) error {
-
-	// Install known kinds in a predictable order.
-	for _, k := range InstallOrder {
-		for _, m := range c.Kind[k] {
-			o := m.VersionedObject
-			o.AddAnnotations(map[string]string{
-				chart.AnnFile:         m.Source,
-				chart.AnnChartVersion: c.Chartfile.Version,
-				chart.AnnChartDesc:    c.Chartfile.Description,
-				chart.AnnChartName:    c.Chartfile.Name,
-			})
-			var data []byte
-			var err error
-			if data, err = o.JSON(); err != nil {
-				return err
+// failInstall records a failed install and dies.
+//
+// If opts.Atomic is set, the objects that were created are deleted first. These
+// are the objects of the release, and extra objects that the install created
+// outside of it: a failed hook Job, and the namespace if it was created.
+// Otherwise the extra objects are listed, since the release record does not
+// name them.
+func failInstall(home string, rel *record.Release, extra []*record.Object, opts *InstallOptions, client kubectl.Runner, msg string, err error) {
+	rel.Status = record.StatusFailed
+	rel.Description = "Install failed: " + err.Error()
+	if opts.Atomic {
+		log.Err("%s: %s", msg, err)
+		objs := append(append([]*record.Object{}, rel.Manifests...), extra...)
+		deleted := deleteCreated(objs, opts.Namespace, client)
+		gone := map[*record.Object]bool{}
+		for _, o := range deleted {
+			gone[o] = true
+		}
+		remaining := []*record.Object{}
+		for _, o := range rel.Manifests {
+			if !gone[o] {
+				remaining = append(remaining, o)
 			}
+		}
+		rel.Manifests = remaining
+		rel.Description += fmt.Sprintf(" (rolled back %d objects)", len(deleted))
+	} else {
+		listLeft(extra)
+	}
+	saveRelease(home, rel, opts.ConfigMap, client)
+	log.Die("%s: %s", msg, err)
+}
 
-			var action = client.Create
-			// If it's a keeper manifest, do "kubectl apply" instead of "create."
-			if manifest.IsKeeper(data) {
-				action = client.Apply
-			}
-			log.Debug("File: %s", string(data))
-			out, err := action(data, namespace)
-			log.Msg(string(out))
-			if err != nil {
-				return err
-			}
+// listLeft lists the objects that a failed install created outside of its
+// release, and leaves in place.
+func listLeft(objs []*record.Object) {
+	if len(objs) == 0 {
+		return
+	}
+	log.Warn("The install created these objects outside of the release, and left them in place:")
+	for _, o := range objs {
+		log.Msg("\t%s/%s", o.Kind, o.Name)
+	}
+}
+
+// uploadManifests sends manifests to Kubectl in the order of an install plan.
+//
+// Each manifest is added to the release once it has been sent successfully.
+func uploadManifests(c *chart.Chart, plan []*step, namespace string, client kubectl.Runner, rel *record.Release) error {
+	for _, m := range plan {
+		data, err := render(c, m.Manifest, rel.Name, namespace)
+		if err != nil {
+			return err
 		}
+
+		var action = client.Create
+		// If it's a keeper manifest, do "kubectl apply" instead of "create."
+		if manifest.IsKeeper(data) {
+			action = client.Apply
+		}
+		log.Debug("File: %s", string(data))
+		out, err := action(data, namespace)
+		log.Msg(string(out))
+		if err != nil {
+			return err
+		}
+		rel.Add(m.Kind, m.Name, data)
 	}
+	return nil
+}
 
-	// Install unknown kinds afterward. Order here is not predictable.
-	for _, k := range c.UnknownKinds(InstallOrder) {
-		for _, o := range c.Kind[k] {
-			o.VersionedObject.AddAnnotations(map[string]string{chart.AnnFile: o.Source})
-			data, err := o.VersionedObject.JSON()
-			if err != nil {
-				return err
-			}
-			out, err := client.Create(data, namespace)
-			log.Msg(string(out))
-			if err != nil {
-				return err
+// deleteCreated deletes objects that an install created, in the order
+// specified by UninstallOrder, and returns those that were deleted.
+//
+// This is used to roll back a failed install. Keeper manifests are not deleted.
+func deleteCreated(objs []*record.Object, namespace string, client kubectl.Runner) []*record.Object {
+	byKind := map[string][]*record.Object{}
+	kinds := []string{}
+	for _, o := range objs {
+		byKind[o.Kind] = append(byKind[o.Kind], o)
+		kinds = append(kinds, o.Kind)
+	}
+
+	log.Info("Rolling back %d objects created by this install ...", len(objs))
+	deleted := []*record.Object{}
+	gone := map[*record.Object]bool{}
+	for _, k := range uninstallKinds(kinds, nil) {
+		for _, o := range byKind[k] {
+			if deleteObject(o, namespace, client) {
+				deleted = append(deleted, o)
+				gone[o] = true
 			}
 		}
 	}
 
-	return nil
+	remaining := []*record.Object{}
+	for _, o := range objs {
+		if !gone[o] {
+			remaining = append(remaining, o)
+		}
+	}
+
+	log.Info("Rolled back %d objects:", len(deleted))
+	for _, o := range deleted {
+		log.Msg("\t%s/%s", o.Kind, o.Name)
+	}
+	if len(remaining) > 0 {
+		log.Warn("%d objects were left in place:", len(remaining))
+		for _, o := range remaining {
+			log.Msg("\t%s/%s", o.Kind, o.Name)
+		}
+	}
+	return deleted
+}
+
+// installKinds returns the kinds in a chart in the order they are installed,
+// when their objects do not depend on each other.
+//
+// Kinds listed in the chart's installOrder come first. Known kinds are
+// installed next, in the order specified by InstallOrder. Unknown kinds are
+// installed afterward, sorted by name.
+func installKinds(c *chart.Chart) []string {
+	order := InstallOrder
+	if c.Chartfile != nil {
+		order = mergeOrder(c.Chartfile.InstallOrder, InstallOrder)
+	}
+	unknown := c.UnknownKinds(order)
+	sort.Strings(unknown)
+	return append(append([]string{}, order...), unknown...)
+}
+
+// mergeOrder returns the kinds in first, followed by the kinds in order that
+// are not in first.
+func mergeOrder(first, order []string) []string {
+	listed := make(map[string]bool, len(first))
+	res := make([]string, 0, len(first)+len(order))
+	for _, k := range first {
+		if !listed[k] {
+			listed[k] = true
+			res = append(res, k)
+		}
+	}
+	for _, k := range order {
+		if !listed[k] {
+			res = append(res, k)
+		}
+	}
+	return res
+}
+
+// render annotates a manifest with information about its chart and the name of
+// its release, puts it into the destination namespace, and returns it encoded
+// as JSON.
+func render(c *chart.Chart, m *manifest.Manifest, release, namespace string) ([]byte, error) {
+	if err := setNamespace(m, namespace); err != nil {
+		return nil, err
+	}
+	o := m.VersionedObject
+	o.AddAnnotations(map[string]string{
+		chart.AnnFile:         m.Source,
+		chart.AnnChartVersion: c.Chartfile.Version,
+		chart.AnnChartDesc:    c.Chartfile.Description,
+		chart.AnnChartName:    c.Chartfile.Name,
+		chart.AnnRelease:      release,
+	})
+	return o.JSON()
+}
+
+// saveRelease records a release as a new revision in $HELMC_HOME, and
+// optionally in a ConfigMap.
+//
+// When a revision is deployed, all earlier deployed revisions are marked as
+// superseded. Dry runs are never recorded. Failure to record a release is reported, but
+// is not fatal, since the manifests have already been sent to Kubernetes. A
+// failure to store the ConfigMap makes the command exit with an error.
+func saveRelease(home string, rel *record.Release, configMap bool, client kubectl.Runner) {
+	if _, ok := client.(kubectl.PrintRunner); ok {
+		log.Debug("Dry run. Not recording release %s.", rel.Name)
+		return
+	}
+
+	dir := helm.ReleaseDirectory(home)
+	if err := record.Save(dir, rel); err != nil {
+		log.Warn("Could not record release %s: %s", rel.Name, err)
+	} else if rel.Status == record.StatusDeployed {
+		if err := record.Supersede(dir, rel.Name, rel.Revision); err != nil {
+			log.Warn("Could not update history of release %s: %s", rel.Name, err)
+		}
+	}
+
+	if !configMap {
+		return
+	}
+	data, err := rel.ConfigMap()
+	if err != nil {
+		log.Err("Could not store release %s in ConfigMap %s: %s", rel.Name, record.ConfigMapName(rel.Name), err)
+		return
+	}
+	if out, err := client.Apply(data, rel.Namespace); err != nil {
+		log.Err("Could not store release %s in ConfigMap %s: %s %s", rel.Name, record.ConfigMapName(rel.Name), err, out)
+	}
+}
+
+// checkDependencies dies if a chart's dependencies are not satisfied, unless force is set.
+//
+// The op is the name of the operation that is stopped, e.g. "install".
+func checkDependencies(cf *chart.Chartfile, home, op string, force bool) {
+	nope, err := dependency.Resolve(cf, helm.WorkspaceChartDirectory(home))
+
+	if err != nil {
+		log.Warn("Failed to check dependencies: %s", err)
+		if !force {
+			log.Die("Re-run with --force to %s anyway.", op)
+		}
+	} else if len(nope) > 0 {
+		log.Warn("Unsatisfied dependencies:")
+		for _, d := range nope {
+			log.Msg("\t%s %s", d.Name, d.Version)
+		}
+		if !force {
+			log.Die("Stopping %s. Re-run with --force to %s anyway.", op, op)
+		}
+	}
 }
 
 // Check by chart directory name whether a chart is fetched into the workspace.
@@ -4,10 +4,14 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/helm/helm-classic/kubectl"
+	"github.com/helm/helm-classic/record"
 	"github.com/helm/helm-classic/test"
+	"github.com/helm/helm-classic/util"
 )
 
 func TestInstall(t *testing.T) {
@@ -71,7 +75,7 @@ func TestInstall(t *testing.T) {
 
 	for _, tt := range tests {
 		actual := test.CaptureOutput(func() {
-			Install(tt.chart, tmpHome, "", tt.force, false, []string{}, tt.client)
+			Install(tt.chart, tmpHome, &InstallOptions{Force: tt.force}, tt.client)
 		})
 
 		for _, exp := range tt.expected {
@@ -79,3 +83,181 @@ func TestInstall(t *testing.T) {
 		}
 	}
 }
+
+// failingRunner is a TestRunner whose Create fails on the failAt'th call.
+//
+// It records every object that it is asked to delete, and finds none.
+type failingRunner struct {
+	TestRunner
+	creates *int
+	failAt  int
+	deleted *[]string
+}
+
+func (r failingRunner) Create(stdin []byte, ns string) ([]byte, error) {
+	*r.creates++
+	if *r.creates == r.failAt {
+		return []byte("create failed"), errors.New("oh snap")
+	}
+	return r.out, r.err
+}
+
+func (r failingRunner) Delete(name, ktype, ns string) ([]byte, error) {
+	*r.deleted = append(*r.deleted, ktype+"/"+name)
+	return r.out, r.err
+}
+
+func (r failingRunner) Get(stdin []byte, ns string) ([]byte, error) {
+	return nil, &kubectl.APIError{Reason: kubectl.ReasonNotFound, Message: "not found"}
+}
+
+func TestInstallAtomic(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	defer os.RemoveAll(tmpHome)
+	test.FakeUpdate(tmpHome)
+
+	pp := os.Getenv("PATH")
+	defer os.Setenv("PATH", pp)
+	os.Setenv("PATH", filepath.Join(test.HelmRoot, "testdata")+":"+pp)
+
+	// The third object in install order is the ConfigMap.
+	client := failingRunner{creates: new(int), failAt: 3, deleted: &[]string{}}
+	actual := test.CaptureOutput(func() {
+		Install("kitchensink", tmpHome, &InstallOptions{Force: true, Atomic: true}, client)
+	})
+	test.ExpectContains(t, actual, "Failed to upload manifests: oh snap")
+
+	expected := []string{"Secret/deis-etcd-discovery-token", "Namespace/kitchensink"}
+	if !reflect.DeepEqual(*client.deleted, expected) {
+		t.Errorf("Expected %v to be deleted, got %v", expected, *client.deleted)
+	}
+
+	rel, err := record.Load(util.ReleaseDirectory(tmpHome), "kitchensink")
+	if err != nil {
+		t.Fatalf("Failed to load release: %s", err)
+	}
+	test.ExpectEquals(t, rel.Status, record.StatusFailed)
+	test.ExpectContains(t, rel.Description, "rolled back 2 objects")
+	test.ExpectEquals(t, len(rel.Manifests), 0)
+
+	*client.creates = 0
+	*client.deleted = []string{}
+	test.CaptureOutput(func() {
+		Install("kitchensink", tmpHome, &InstallOptions{Force: true}, client)
+	})
+	if len(*client.deleted) > 0 {
+		t.Errorf("Expected nothing to be deleted without atomic, got %v", *client.deleted)
+	}
+
+	// A namespace created for the install is deleted with it.
+	client = failingRunner{creates: new(int), failAt: 2, deleted: &[]string{}}
+	test.CaptureOutput(func() {
+		Install("redis", tmpHome, &InstallOptions{Namespace: "staging", CreateNamespace: true, Atomic: true}, client)
+	})
+	expected = []string{"Namespace/staging"}
+	if !reflect.DeepEqual(*client.deleted, expected) {
+		t.Errorf("Expected %v to be deleted, got %v", expected, *client.deleted)
+	}
+}
+
+func TestInstallWait(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	defer os.RemoveAll(tmpHome)
+	test.FakeUpdate(tmpHome)
+
+	pp := os.Getenv("PATH")
+	defer os.Setenv("PATH", pp)
+	os.Setenv("PATH", filepath.Join(test.HelmRoot, "testdata")+":"+pp)
+
+	defer func(d time.Duration) { waitInterval = d }(waitInterval)
+	waitInterval = time.Millisecond
+
+	polls := 0
+	client := liveRunner(func(obj map[string]interface{}) {
+		polls++
+		obj["status"] = map[string]interface{}{"phase": "Pending"}
+		if polls >= 3 {
+			obj["status"] = map[string]interface{}{
+				"phase":      "Running",
+				"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+			}
+		}
+	})
+	actual := test.CaptureOutput(func() {
+		Install("redis", tmpHome, &InstallOptions{Wait: time.Minute}, client)
+	})
+	test.ExpectContains(t, actual, "Pod/redis is ready: running")
+	test.ExpectEquals(t, polls, 3)
+
+	pending := liveRunner(func(obj map[string]interface{}) {
+		obj["status"] = map[string]interface{}{"phase": "Pending"}
+	})
+	actual = test.CaptureOutput(func() {
+		Install("redis", tmpHome, &InstallOptions{Wait: 5 * time.Millisecond, DefaultNamespace: "dev"}, pending)
+	})
+	test.ExpectContains(t, actual, "Chart did not become ready: timed out waiting for 1 objects to become ready")
+
+	rel, err := record.Load(util.ReleaseDirectory(tmpHome), "redis")
+	if err != nil {
+		t.Fatalf("Failed to load release: %s", err)
+	}
+	test.ExpectEquals(t, rel.Status, record.StatusFailed)
+	test.ExpectEquals(t, rel.Namespace, "dev")
+}
+
+// createRunner is a getRunner that records what it is asked to create.
+type createRunner struct {
+	getRunner
+	created *[]string
+}
+
+func (r createRunner) Create(stdin []byte, ns string) ([]byte, error) {
+	*r.created = append(*r.created, string(stdin))
+	return nil, nil
+}
+
+func TestInstallNamespace(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	defer os.RemoveAll(tmpHome)
+	test.FakeUpdate(tmpHome)
+
+	pp := os.Getenv("PATH")
+	defer os.Setenv("PATH", pp)
+	os.Setenv("PATH", filepath.Join(test.HelmRoot, "testdata")+":"+pp)
+
+	missing := getRunner{get: func([]byte) ([]byte, error) {
+		return nil, &kubectl.APIError{Reason: kubectl.ReasonNotFound, Message: "not found"}
+	}}
+	client := createRunner{getRunner: missing, created: &[]string{}}
+	test.CaptureOutput(func() {
+		Install("redis", tmpHome, &InstallOptions{Namespace: "staging", CreateNamespace: true}, client)
+	})
+	if len(*client.created) != 2 {
+		t.Fatalf("Expected the namespace and the pod to be created, got %v", *client.created)
+	}
+	test.ExpectContains(t, (*client.created)[0], `"kind": "Namespace"`)
+	test.ExpectContains(t, (*client.created)[0], `"name": "staging"`)
+	test.ExpectContains(t, (*client.created)[1], `"namespace": "staging"`)
+
+	// The namespace is not created again if it exists.
+	exists := liveRunner(func(map[string]interface{}) {})
+	client = createRunner{getRunner: exists, created: &[]string{}}
+	test.CaptureOutput(func() {
+		Install("redis", tmpHome, &InstallOptions{Namespace: "staging", CreateNamespace: true}, client)
+	})
+	if len(*client.created) != 1 {
+		t.Errorf("Expected only the pod to be created, got %v", *client.created)
+	}
+
+	// Manifests that declare another namespace stop the install.
+	client = createRunner{getRunner: exists, created: &[]string{}}
+	actual := test.CaptureOutput(func() {
+		Install("kitchensink", tmpHome, &InstallOptions{Namespace: "staging", Force: true}, client)
+	})
+	test.ExpectContains(t, actual, `ConfigMap/drone declares namespace "default"`)
+	test.ExpectContains(t, actual, `Cannot install kitchensink into namespace "staging"`)
+	test.ExpectContains(t, actual, `LimitRange/kitchensink-limits declares namespace "kitchensink"`)
+	if len(*client.created) != 0 {
+		t.Errorf("Expected nothing to be created, got %v", *client.created)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/google/go-github/github"
+	"github.com/helm/helm-classic/chart"
 	"github.com/helm/helm-classic/log"
 	"github.com/helm/helm-classic/manifest"
 	"github.com/helm/helm-classic/util"
@@ -54,7 +56,10 @@ func LintAll(homedir string) {
 // Lint validates that a chart is well-formed
 //
 // - chartPath path to chart directory
-func Lint(chartPath string) {
+//
+// It reports whether the chart passed all necessary checks. Failed warnings
+// do not count.
+func Lint(chartPath string) bool {
 	cv := new(validation.ChartValidation)
 
 	chartPresenceValidation := cv.AddError("Chart found at "+chartPath, func(path string, v *validation.Validation) bool {
@@ -158,6 +163,61 @@ func Lint(chartPath string) {
 		return success
 	})
 
+	manifestsParsingValidation.AddError("Hooks are Jobs for known hook events", func(path string, v *validation.Validation) bool {
+		known := map[string]bool{}
+		for _, e := range chart.HookEvents {
+			known[e] = true
+		}
+
+		for _, m := range cv.Manifests {
+			meta, _ := m.VersionedObject.Meta()
+			hook, ok := meta.Annotations[chart.AnnHook]
+			if !ok {
+				continue
+			}
+			if meta.Kind != "Job" {
+				return false
+			}
+			for _, e := range strings.Split(hook, ",") {
+				if !known[strings.TrimSpace(e)] {
+					return false
+				}
+			}
+		}
+		return true
+	})
+
+	manifestsParsingValidation.AddError("Install and uninstall orders list known kinds", func(path string, v *validation.Validation) bool {
+		if cv.Chartfile == nil {
+			return true
+		}
+
+		known := map[string]bool{}
+		for _, k := range append(append([]string{}, InstallOrder...), UninstallOrder...) {
+			known[k] = true
+		}
+		for _, m := range cv.Manifests {
+			known[m.Kind] = true
+		}
+
+		for _, k := range append(append([]string{}, cv.Chartfile.InstallOrder...), cv.Chartfile.UninstallOrder...) {
+			if !known[k] {
+				log.Warn("Unknown kind %q in Chart.yaml", k)
+				return false
+			}
+		}
+		return true
+	})
+
+	manifestsParsingValidation.AddError("Manifest weights are integers", func(path string, v *validation.Validation) bool {
+		for _, m := range cv.Manifests {
+			if _, err := chart.Weight(m); err != nil {
+				return false
+			}
+		}
+		return true
+	})
+
 	if cv.Valid() {
 		log.Info("Chart [%s] has passed all necessary checks", cv.ChartName())
 	} else {
@@ -167,4 +227,5 @@ func Lint(chartPath string) {
 			log.Warn("Chart [%s] has passed all necessary checks but failed some checks as well. Proceed with caution. Check out the warnings listed.", cv.ChartName())
 		}
 	}
+	return cv.ErrorCount == 0
 }
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/helm/helm-classic/chart"
 	"github.com/helm/helm-classic/test"
 	"github.com/helm/helm-classic/util"
 
@@ -134,3 +135,38 @@ func TestLintBadPath(t *testing.T) {
 	msg := "Chart found at " + tmpHome + "/workspace/charts/" + chartName + " : false"
 	test.ExpectContains(t, output, msg)
 }
+
+func TestLintOrder(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	test.FakeUpdate(tmpHome)
+
+	chartName := "orderChart"
+
+	Create(chartName, tmpHome)
+
+	chartYaml := util.WorkspaceChartDirectory(tmpHome, chartName, Chartfile)
+	cf, err := chart.LoadChartfile(chartYaml)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cf.InstallOrder = []string{"Pod", "Namespace"}
+	cf.UninstallOrder = []string{"Pod"}
+	if err := cf.Save(chartYaml); err != nil {
+		t.Fatal(err)
+	}
+	output := test.CaptureOutput(func() {
+		Lint(util.WorkspaceChartDirectory(tmpHome, chartName))
+	})
+	test.ExpectContains(t, output, "Install and uninstall orders list known kinds : true")
+	test.ExpectContains(t, output, "Manifest weights are integers : true")
+
+	cf.UninstallOrder = []string{"Widget"}
+	if err := cf.Save(chartYaml); err != nil {
+		t.Fatal(err)
+	}
+	output = test.CaptureOutput(func() {
+		Lint(util.WorkspaceChartDirectory(tmpHome, chartName))
+	})
+	test.ExpectContains(t, output, "Install and uninstall orders list known kinds : false")
+}
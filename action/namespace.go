@@ -0,0 +1,97 @@
+package action
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/kubectl"
+	"github.com/helm/helm-classic/log"
+	"github.com/helm/helm-classic/manifest"
+	"github.com/helm/helm-classic/record"
+)
+
+// setNamespace puts a manifest into the destination namespace.
+//
+// Cluster-scoped kinds, such as Namespaces and PersistentVolumes, are left
+// alone. An error is returned if the manifest declares a different namespace.
+// If namespace is empty, the manifest is not changed.
+func setNamespace(m *manifest.Manifest, namespace string) error {
+	if namespace == "" || kubectl.IsClusterScoped(m.Kind) {
+		return nil
+	}
+	md, err := m.VersionedObject.Meta()
+	if err != nil {
+		return err
+	}
+	if md.Namespace != "" && md.Namespace != namespace {
+		return fmt.Errorf("%s/%s declares namespace %q, not %q", m.Kind, m.Name, md.Namespace, namespace)
+	}
+	return m.VersionedObject.SetNamespace(namespace)
+}
+
+// checkNamespaces verifies that no manifest in a chart, including its hooks,
+// declares a namespace other than the destination namespace.
+//
+// The error lists every conflict. If namespace is empty, manifests that declare
+// a namespace are installed there, and a warning is logged for each of them.
+func checkNamespaces(c *chart.Chart, namespace string) error {
+	conflicts := []string{}
+	for _, m := range c.Manifests {
+		if kubectl.IsClusterScoped(m.Kind) {
+			continue
+		}
+		md, err := m.VersionedObject.Meta()
+		if err != nil || md.Namespace == "" || md.Namespace == namespace {
+			continue
+		}
+		if namespace == "" {
+			log.Warn("%s/%s will be installed into namespace %q, which is declared in its manifest.", m.Kind, m.Name, md.Namespace)
+			continue
+		}
+		conflicts = append(conflicts, fmt.Sprintf("%s/%s declares namespace %q", m.Kind, m.Name, md.Namespace))
+	}
+	if len(conflicts) > 0 {
+		return errors.New(strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
+// ensureNamespace creates the destination namespace if it does not exist, and
+// returns it if it was created.
+//
+// Nothing is done if the chart creates the namespace itself. The namespace is
+// not part of the release, so it is not deleted when the chart is uninstalled,
+// but a failed atomic install deletes it again.
+func ensureNamespace(c *chart.Chart, namespace string, client kubectl.Runner) (*record.Object, error) {
+	if namespace == "" {
+		return nil, nil
+	}
+	for _, m := range c.Kind["Namespace"] {
+		if m.Name == namespace {
+			log.Debug("Namespace %q is created by the chart", namespace)
+			return nil, nil
+		}
+	}
+
+	data := []byte(fmt.Sprintf(`{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": %q}}`, namespace))
+	if _, dry := client.(kubectl.PrintRunner); !dry {
+		_, found, err := liveObject(data, "", client)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			log.Debug("Namespace %q already exists", namespace)
+			return nil, nil
+		}
+	}
+
+	log.Info("Creating namespace %q", namespace)
+	out, err := client.Create(data, "")
+	log.Msg(string(out))
+	if err != nil {
+		return nil, err
+	}
+	return &record.Object{Kind: "Namespace", Name: namespace, Data: string(data)}, nil
+}
@@ -0,0 +1,321 @@
+package action
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/log"
+	"github.com/helm/helm-classic/manifest"
+)
+
+// step is one manifest in an install plan.
+type step struct {
+	*manifest.Manifest
+	// after lists the manifests in the chart that this one references, and
+	// which are therefore installed before it.
+	after []*step
+}
+
+func (s *step) String() string {
+	return s.Kind + "/" + s.Name
+}
+
+// installPlan returns the manifests of a chart in the order they are installed.
+//
+// Objects are installed after the objects in the chart that they reference.
+// Pods, and the pod templates of controllers such as Deployments and Jobs,
+// reference the Secrets and ConfigMaps they mount or read environment
+// variables from, the PersistentVolumeClaims they mount, their ServiceAccount,
+// and their image pull Secrets. PersistentVolumeClaims reference the
+// PersistentVolume they are bound to.
+//
+// Objects that do not depend on each other are installed by weight, lowest
+// first, and then in the order of installKinds. The weight of a manifest is set
+// with the chart.AnnWeight annotation, and the chart can override the order of
+// kinds in its Chart.yaml.
+//
+// An error is returned if the references form a cycle.
+func installPlan(c *chart.Chart) ([]*step, error) {
+	steps := []*step{}
+	byName := map[string]*step{}
+	for _, k := range installKinds(c) {
+		for _, m := range c.Kind[k] {
+			s := &step{Manifest: m}
+			steps = append(steps, s)
+			byName[s.String()] = s
+		}
+	}
+
+	weights, err := stepWeights(steps)
+	if err != nil {
+		return nil, err
+	}
+	sort.Stable(byWeight{steps, weights})
+
+	for _, s := range steps {
+		refs, err := references(s.Manifest)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %s", s, err)
+		}
+		for _, r := range refs {
+			if dep, ok := byName[r]; ok && dep != s && !contains(s.after, dep) {
+				s.after = append(s.after, dep)
+			}
+		}
+	}
+
+	// Repeatedly take the first step whose dependencies are done.
+	plan := make([]*step, 0, len(steps))
+	done := map[*step]bool{}
+	for len(plan) < len(steps) {
+		var next *step
+		for _, s := range steps {
+			if !done[s] && s.ready(done) {
+				next = s
+				break
+			}
+		}
+		if next == nil {
+			return nil, fmt.Errorf("dependency cycle: %s", findCycle(steps, done))
+		}
+		done[next] = true
+		plan = append(plan, next)
+	}
+	return plan, nil
+}
+
+// uninstallPlan returns the manifests of a chart in the order they are
+// uninstalled.
+//
+// Manifests with the highest weight are uninstalled first. Manifests with the
+// same weight are uninstalled in the order of uninstallKinds, which puts the
+// kinds listed in the chart's uninstallOrder first.
+func uninstallPlan(c *chart.Chart) ([]*manifest.Manifest, error) {
+	kinds := make([]string, 0, len(c.Kind))
+	for k := range c.Kind {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	var first []string
+	if c.Chartfile != nil {
+		first = c.Chartfile.UninstallOrder
+	}
+
+	steps := []*step{}
+	for _, k := range uninstallKinds(kinds, first) {
+		for _, m := range c.Kind[k] {
+			steps = append(steps, &step{Manifest: m})
+		}
+	}
+
+	weights, err := stepWeights(steps)
+	if err != nil {
+		return nil, err
+	}
+	for m, w := range weights {
+		weights[m] = -w
+	}
+	sort.Stable(byWeight{steps, weights})
+
+	plan := make([]*manifest.Manifest, len(steps))
+	for i, s := range steps {
+		plan[i] = s.Manifest
+	}
+	return plan, nil
+}
+
+// stepWeights returns the weights of the manifests in the given steps.
+func stepWeights(steps []*step) (map[*manifest.Manifest]int, error) {
+	weights := make(map[*manifest.Manifest]int, len(steps))
+	for _, s := range steps {
+		w, err := chart.Weight(s.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		weights[s.Manifest] = w
+	}
+	return weights, nil
+}
+
+// byWeight sorts steps by the weights of their manifests, lowest first.
+type byWeight struct {
+	steps   []*step
+	weights map[*manifest.Manifest]int
+}
+
+func (b byWeight) Len() int      { return len(b.steps) }
+func (b byWeight) Swap(i, j int) { b.steps[i], b.steps[j] = b.steps[j], b.steps[i] }
+func (b byWeight) Less(i, j int) bool {
+	return b.weights[b.steps[i].Manifest] < b.weights[b.steps[j].Manifest]
+}
+
+// ready returns true if every step that s depends on is done.
+func (s *step) ready(done map[*step]bool) bool {
+	for _, dep := range s.after {
+		if !done[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// printPlan shows the order in which a chart will be installed.
+func printPlan(plan []*step) {
+	log.Info("Install order:")
+	for i, s := range plan {
+		if len(s.after) == 0 {
+			log.Msg("%3d. %s", i+1, s)
+			continue
+		}
+		deps := make([]string, len(s.after))
+		for j, d := range s.after {
+			deps[j] = d.String()
+		}
+		log.Msg("%3d. %s (after %s)", i+1, s, strings.Join(deps, ", "))
+	}
+}
+
+// findCycle describes a dependency cycle among the steps that are not done.
+func findCycle(steps []*step, done map[*step]bool) string {
+	var start *step
+	for _, s := range steps {
+		if !done[s] {
+			start = s
+			break
+		}
+	}
+
+	// Every remaining step waits for another remaining step, so following
+	// unfinished dependencies must eventually revisit a step.
+	seen := map[*step]int{}
+	path := []*step{}
+	for s := start; ; {
+		if i, ok := seen[s]; ok {
+			names := []string{}
+			for _, p := range path[i:] {
+				names = append(names, p.String())
+			}
+			return strings.Join(append(names, s.String()), " -> ")
+		}
+		seen[s] = len(path)
+		path = append(path, s)
+		next := s
+		for _, dep := range s.after {
+			if !done[dep] {
+				next = dep
+				break
+			}
+		}
+		if next == s {
+			return s.String()
+		}
+		s = next
+	}
+}
+
+func contains(steps []*step, s *step) bool {
+	for _, x := range steps {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// references returns the Kind/Name of every object that a manifest refers to.
+func references(m *manifest.Manifest) ([]string, error) {
+	data, err := m.VersionedObject.JSON()
+	if err != nil {
+		return nil, err
+	}
+	obj, err := decodeObject(data)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := []string{}
+	add := func(kind string, name interface{}) {
+		if n, ok := name.(string); ok && n != "" {
+			refs = append(refs, kind+"/"+n)
+		}
+	}
+
+	if m.Kind == "PersistentVolumeClaim" {
+		v, _ := nested(obj, "spec", "volumeName")
+		add("PersistentVolume", v)
+	}
+
+	spec := podSpec(m.Kind, obj)
+	if spec == nil {
+		return refs, nil
+	}
+
+	if sa, ok := spec["serviceAccountName"]; ok {
+		add("ServiceAccount", sa)
+	} else {
+		add("ServiceAccount", spec["serviceAccount"])
+	}
+	for _, s := range list(spec["imagePullSecrets"]) {
+		add("Secret", s["name"])
+	}
+	for _, v := range list(spec["volumes"]) {
+		sv, _ := nested(v, "secret", "secretName")
+		add("Secret", sv)
+		cm, _ := nested(v, "configMap", "name")
+		add("ConfigMap", cm)
+		pvc, _ := nested(v, "persistentVolumeClaim", "claimName")
+		add("PersistentVolumeClaim", pvc)
+	}
+	containers := append(list(spec["containers"]), list(spec["initContainers"])...)
+	for _, ct := range containers {
+		for _, e := range list(ct["env"]) {
+			s, _ := nested(e, "valueFrom", "secretKeyRef", "name")
+			add("Secret", s)
+			cm, _ := nested(e, "valueFrom", "configMapKeyRef", "name")
+			add("ConfigMap", cm)
+		}
+		for _, e := range list(ct["envFrom"]) {
+			s, _ := nested(e, "secretRef", "name")
+			add("Secret", s)
+			cm, _ := nested(e, "configMapRef", "name")
+			add("ConfigMap", cm)
+		}
+	}
+
+	sort.Strings(refs)
+	return refs, nil
+}
+
+// podSpec returns the pod spec of a Pod, or the pod template spec of a
+// controller. It returns nil if the object has no pod spec.
+func podSpec(kind string, obj map[string]interface{}) map[string]interface{} {
+	paths := [][]string{
+		{"spec", "template", "spec"},
+		{"spec", "jobTemplate", "spec", "template", "spec"},
+	}
+	if kind == "Pod" {
+		paths = [][]string{{"spec"}}
+	}
+	for _, p := range paths {
+		if v, ok := nested(obj, p...); ok {
+			if spec, ok := v.(map[string]interface{}); ok {
+				return spec
+			}
+		}
+	}
+	return nil
+}
+
+// list returns the objects in a decoded JSON list.
+func list(v interface{}) []map[string]interface{} {
+	items, _ := v.([]interface{})
+	res := make([]map[string]interface{}, 0, len(items))
+	for _, i := range items {
+		if m, ok := i.(map[string]interface{}); ok {
+			res = append(res, m)
+		}
+	}
+	return res
+}
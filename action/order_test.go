@@ -0,0 +1,189 @@
+package action
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/codec"
+	"github.com/helm/helm-classic/manifest"
+)
+
+const orderManifests = `apiVersion: v1
+kind: ReplicationController
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      serviceAccountName: web
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: data
+      - name: tls
+        secret:
+          secretName: tls
+      containers:
+      - name: web
+        image: nginx
+        envFrom:
+        - configMapRef:
+            name: settings
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+spec:
+  volumeName: disk
+---
+apiVersion: v1
+kind: PersistentVolume
+metadata:
+  name: disk
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: tls
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`
+
+func orderChart(t *testing.T, data string) *chart.Chart {
+	docs, err := codec.YAML.Decode([]byte(data)).All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &chart.Chart{Kind: map[string][]*manifest.Manifest{}}
+	for _, d := range docs {
+		ref, err := d.Meta()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &manifest.Manifest{Version: ref.APIVersion, Kind: ref.Kind, Name: ref.Name, VersionedObject: d}
+		c.Kind[m.Kind] = append(c.Kind[m.Kind], m)
+	}
+	return c
+}
+
+func TestInstallPlan(t *testing.T) {
+	plan, err := installPlan(orderChart(t, orderManifests))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos := map[string]int{}
+	for i, s := range plan {
+		pos[s.String()] = i
+	}
+	if len(pos) != 7 {
+		t.Fatalf("Expected 7 steps, got %v", plan)
+	}
+
+	before := [][2]string{
+		{"ServiceAccount/web", "ReplicationController/web"},
+		{"PersistentVolumeClaim/data", "ReplicationController/web"},
+		{"Secret/tls", "ReplicationController/web"},
+		{"ConfigMap/settings", "ReplicationController/web"},
+		{"PersistentVolume/disk", "PersistentVolumeClaim/data"},
+	}
+	for _, b := range before {
+		if pos[b[0]] > pos[b[1]] {
+			t.Errorf("Expected %s before %s, got %v", b[0], b[1], plan)
+		}
+	}
+
+	// Without references, InstallOrder decides.
+	if pos["Service/web"] > pos["ReplicationController/web"] {
+		t.Errorf("Expected the Service before the ReplicationController, got %v", plan)
+	}
+}
+
+func TestFindCycle(t *testing.T) {
+	a := &step{Manifest: &manifest.Manifest{Kind: "Pod", Name: "a"}}
+	b := &step{Manifest: &manifest.Manifest{Kind: "Secret", Name: "b"}}
+	c := &step{Manifest: &manifest.Manifest{Kind: "ConfigMap", Name: "c"}}
+	a.after = []*step{c, b}
+	b.after = []*step{a}
+
+	if cycle := findCycle([]*step{c, a, b}, map[*step]bool{c: true}); cycle != "Pod/a -> Secret/b -> Pod/a" {
+		t.Errorf("Unexpected cycle %q", cycle)
+	}
+}
+
+func TestPlanOverrides(t *testing.T) {
+	data := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+apiVersion: extensions/v1beta1
+kind: Job
+metadata:
+  name: setup
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: late
+  annotations:
+    chart.helm.sh/weight: "10"
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`
+	c := orderChart(t, data)
+	c.Chartfile = &chart.Chartfile{
+		InstallOrder:   []string{"Job", "ConfigMap"},
+		UninstallOrder: []string{"Job"},
+	}
+
+	plan, err := installPlan(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, s := range plan {
+		got = append(got, s.String())
+	}
+	expect := "Job/setup ConfigMap/settings Service/web Secret/late"
+	if strings.Join(got, " ") != expect {
+		t.Errorf("Expected install order %q, got %q", expect, got)
+	}
+
+	ms, err := uninstallPlan(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = []string{}
+	for _, m := range ms {
+		got = append(got, m.Kind+"/"+m.Name)
+	}
+	expect = "Secret/late Job/setup Service/web ConfigMap/settings"
+	if strings.Join(got, " ") != expect {
+		t.Errorf("Expected uninstall order %q, got %q", expect, got)
+	}
+
+	m := c.Kind["Secret"][0]
+	m.VersionedObject.AddAnnotations(map[string]string{chart.AnnWeight: "heavy"})
+	if _, err := installPlan(c); err == nil {
+		t.Error("Expected an error for a weight that is not an integer")
+	}
+}
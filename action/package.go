@@ -0,0 +1,168 @@
+package action
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/log"
+	helm "github.com/helm/helm-classic/util"
+)
+
+// DigestSuffix is appended to the name of a chart archive to get the name of
+// the file that holds its SHA-256 digest.
+const DigestSuffix = ".sha256"
+
+// Package builds a versioned archive of a chart in the workspace.
+//
+// The chart is linted first, and is not packaged if it fails. The archive is
+// written to outdir as NAME-VERSION.tgz, leaving out the files listed in the
+// chart's .helmcignore. Its SHA-256 digest is written next to it, in the
+// format of sha256sum.
+//
+// It returns the path to the archive.
+func Package(chartName, home, outdir string) string {
+	cd := helm.WorkspaceChartDirectory(home, chartName)
+	if !Lint(cd) {
+		log.Die("Not packaging %s, because it failed some necessary checks.", chartName)
+	}
+
+	cf, err := chart.LoadChartfile(filepath.Join(cd, Chartfile))
+	if err != nil {
+		log.Die("Could not load %s: %s", chartName, err)
+	}
+	patterns, err := chart.LoadIgnore(cd)
+	if err != nil {
+		log.Die("Could not read the ignore file of %s: %s", chartName, err)
+	}
+
+	var buf bytes.Buffer
+	if err := chart.Pack(cd, cf.Name, patterns, &buf); err != nil {
+		log.Die("Could not package %s: %s", chartName, err)
+	}
+
+	if err := os.MkdirAll(outdir, 0755); err != nil {
+		log.Die("Could not create %s: %s", outdir, err)
+	}
+	base := fmt.Sprintf("%s-%s.tgz", cf.Name, cf.Version)
+	archive := filepath.Join(outdir, base)
+	if err := ioutil.WriteFile(archive, buf.Bytes(), 0644); err != nil {
+		log.Die("Could not write %s: %s", archive, err)
+	}
+	sum := digest(buf.Bytes())
+	if err := ioutil.WriteFile(archive+DigestSuffix, []byte(fmt.Sprintf("%s  %s\n", sum, base)), 0644); err != nil {
+		log.Die("Could not write %s: %s", archive+DigestSuffix, err)
+	}
+
+	log.Info("Packaged %s %s into %s", cf.Name, cf.Version, archive)
+	log.Msg("Digest: sha256:%s", sum)
+	return archive
+}
+
+// digest returns the hex-encoded SHA-256 digest of data.
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// isArchive reports whether a chart argument is a chart archive, given by a
+// local path or an HTTP URL, rather than the name of a chart in a table.
+func isArchive(name string) bool {
+	return isURL(name) || strings.HasSuffix(name, ".tgz")
+}
+
+func isURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+// readArchive reads a file from a local path or an HTTP URL.
+//
+// If the file is not found, the returned error satisfies os.IsNotExist.
+func readArchive(src string) ([]byte, error) {
+	if !isURL(src) {
+		return ioutil.ReadFile(src)
+	}
+	res, err := http.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, &os.PathError{Op: "GET", Path: src, Err: os.ErrNotExist}
+	} else if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", src, res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// fetchArchive unpacks a chart archive into the workspace.
+//
+// If the archive has a digest file next to it, the archive is verified
+// against it. If verify is true, the chart's signature is verified too.
+// lname is the local name of the chart; if it is blank, the name declared in
+// the chart's Chart.yaml is used. The local name is returned.
+func fetchArchive(src, lname, home string, verify bool) string {
+	data, err := readArchive(src)
+	if err != nil {
+		log.Die("Could not read chart archive %s: %s", src, err)
+	}
+
+	sum, err := readArchive(src + DigestSuffix)
+	switch {
+	case os.IsNotExist(err):
+		log.Warn("No digest found for %s. Skipping verification.", src)
+	case err != nil:
+		log.Die("Could not read the digest of %s: %s", src, err)
+	default:
+		fields := strings.Fields(string(sum))
+		if len(fields) == 0 || !strings.EqualFold(fields[0], digest(data)) {
+			log.Die("Chart archive %s does not match its digest.", src)
+		}
+		log.Debug("Verified digest of %s", src)
+	}
+
+	tmp, err := ioutil.TempDir("", "helmc-archive")
+	if err != nil {
+		log.Die("Could not create a temporary directory: %s", err)
+	}
+	defer os.RemoveAll(tmp)
+	if err := chart.Unpack(bytes.NewReader(data), tmp); err != nil {
+		log.Die("Could not unpack %s: %s", src, err)
+	}
+	cf, err := chart.LoadChartfile(filepath.Join(tmp, Chartfile))
+	if err != nil {
+		log.Die("Archive %s is not a valid chart. Missing Chart.yaml: %s", src, err)
+	}
+	if verify {
+		verifyChart(tmp, src, home)
+	}
+	if lname == "" {
+		lname = cf.Name
+	}
+
+	dest := helm.WorkspaceChartDirectory(home, lname)
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		log.Die("Could not create %q: %s", dest, err)
+	}
+	log.Debug("Fetching %s to %s", src, dest)
+	if err := helm.CopyDir(tmp, dest); err != nil {
+		log.Die("Failed copying %s to %s", src, dest)
+	}
+
+	if abs, err := filepath.Abs(src); err == nil && !isURL(src) {
+		src = abs
+	}
+	cf.From = &chart.Dependency{Name: cf.Name, Version: cf.Version, Repo: src}
+	cf.Name = lname
+	if err := cf.Save(filepath.Join(dest, Chartfile)); err != nil {
+		log.Die("Failed to update Chart.yaml: %s", err)
+	}
+	return lname
+}
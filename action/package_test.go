@@ -0,0 +1,80 @@
+package action
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/test"
+	"github.com/helm/helm-classic/util"
+)
+
+func TestPackage(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	defer os.RemoveAll(tmpHome)
+	test.FakeUpdate(tmpHome)
+
+	pp := os.Getenv("PATH")
+	defer os.Setenv("PATH", pp)
+	os.Setenv("PATH", filepath.Join(test.HelmRoot, "testdata")+":"+pp)
+
+	out := filepath.Join(tmpHome, "dist")
+	var archive string
+	test.CaptureOutput(func() {
+		Fetch("redis", "", tmpHome, nil)
+		archive = Package("redis", tmpHome, out)
+	})
+	if archive != filepath.Join(out, "redis-0.0.1.tgz") {
+		t.Errorf("Unexpected archive %s", archive)
+	}
+	data, err := ioutil.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := ioutil.ReadFile(archive + DigestSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(sum) != digest(data)+"  redis-0.0.1.tgz\n" {
+		t.Errorf("Unexpected digest file %q", sum)
+	}
+
+	// Fetch from a local archive.
+	actual := test.CaptureOutput(func() {
+		Fetch(archive, "cache", tmpHome, nil)
+	})
+	test.ExpectContains(t, actual, "Fetched chart into workspace "+util.WorkspaceChartDirectory(tmpHome, "cache"))
+	cf, err := chart.LoadChartfile(util.WorkspaceChartDirectory(tmpHome, "cache", Chartfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.Name != "cache" || cf.From == nil || cf.From.Repo != archive || cf.From.Version != "0.0.1" {
+		t.Errorf("Unexpected Chart.yaml %+v from %+v", cf, cf.From)
+	}
+
+	// Install from a URL.
+	ts := httptest.NewServer(http.FileServer(http.Dir(out)))
+	defer ts.Close()
+	actual = test.CaptureOutput(func() {
+		Install(ts.URL+"/redis-0.0.1.tgz", tmpHome, &InstallOptions{}, TestRunner{out: []byte("hello from redis")})
+	})
+	test.ExpectContains(t, actual, "hello from redis")
+
+	// Archives must match their digest.
+	ioutil.WriteFile(archive+DigestSuffix, []byte("0000  redis-0.0.1.tgz\n"), 0644)
+	actual = test.CaptureOutput(func() {
+		Install(ts.URL+"/redis-0.0.1.tgz", tmpHome, &InstallOptions{}, TestRunner{})
+	})
+	test.ExpectContains(t, actual, "does not match its digest")
+
+	// Charts that fail lint are not packaged.
+	os.RemoveAll(util.WorkspaceChartDirectory(tmpHome, "cache", "manifests"))
+	actual = test.CaptureOutput(func() {
+		Package("cache", tmpHome, out)
+	})
+	test.ExpectContains(t, actual, "Not packaging cache")
+}
@@ -3,23 +3,29 @@ package action
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
 
+	"github.com/helm/helm-classic/kubectl"
 	"github.com/helm/helm-classic/log"
 	"github.com/helm/helm-classic/manifest"
 	helm "github.com/helm/helm-classic/util"
 )
 
-// kubeGetter wraps the kubectl command, override in tests
-type kubeGetter func(string) string
+// kubeGetter gets an object from Kubernetes, override in tests
+type kubeGetter func([]byte) ([]byte, error)
 
-var kubeGet kubeGetter = func(m string) string {
-	log.Debug("Getting manifests from %s", m)
+var kubeGet kubeGetter = func(data []byte) ([]byte, error) {
+	return kubectl.Client.Get(data, "")
+}
 
-	a := []string{"get", "-f", m}
-	out, _ := exec.Command("kubectl", a...).CombinedOutput()
-	return string(out)
+// isNotFound reports whether a failed Get failed because the object does not
+// exist. The API client returns a structured error, and kubectl says so in its
+// output.
+func isNotFound(out []byte, err error) bool {
+	if err == nil {
+		return false
+	}
+	return kubectl.IsNotFound(err) || strings.Contains(string(out), "not found") || strings.Contains(string(out), "NotFound")
 }
 
 // Remove removes a chart from the workdir.
@@ -35,19 +41,10 @@ func Remove(chart, homedir string, force bool) {
 	}
 
 	if !force {
-		var connectionFailure bool
-
 		// check if any chart manifests are installed
 		installed, err := checkManifests(chartPath)
 		if err != nil {
-			if strings.Contains(err.Error(), "unable to connect") {
-				connectionFailure = true
-			} else {
-				log.Die(err.Error())
-			}
-		}
-
-		if connectionFailure {
+			log.Warn("%s", err)
 			log.Err("Could not determine if %s is installed.  To remove the chart --force flag must be set.", chart)
 			return
 		} else if len(installed) > 0 {
@@ -64,27 +61,35 @@ func Remove(chart, homedir string, force bool) {
 	log.Info("All clear! You have successfully removed %s from your workspace.", chart)
 }
 
-// checkManifests gets any installed manifests within a chart
+// checkManifests gets any installed objects within a chart
+//
+// Each document of a manifest file is looked up on its own. An error is
+// returned if an object cannot be looked up for any reason other than that it
+// does not exist.
 func checkManifests(chartPath string) ([]string, error) {
-	var foundManifests []string
+	var found []string
 
-	manifests, err := manifest.Files(chartPath)
+	manifests, err := manifest.ParseDir(chartPath)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, m := range manifests {
-		out := kubeGet(m)
-
-		if strings.Contains(out, "unable to connect") {
-			return nil, fmt.Errorf(out)
+		data, err := m.VersionedObject.JSON()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", m.Source, err)
 		}
-		if !strings.Contains(out, "not found") {
-			foundManifests = append(foundManifests, m)
+		log.Debug("Getting %s/%s from %s", m.Kind, m.Name, m.Source)
+		out, err := kubeGet(data)
+		if isNotFound(out, err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("could not get %s/%s: %s %s", m.Kind, m.Name, err, out)
 		}
+		found = append(found, m.Kind+"/"+m.Name)
 	}
 
-	log.Debug("Found %d installed manifests", len(foundManifests))
+	log.Debug("Found %d installed manifests", len(found))
 
-	return foundManifests, nil
+	return found, nil
 }
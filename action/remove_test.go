@@ -1,17 +1,27 @@
 package action
 
 import (
+	"errors"
 	"os"
 	"testing"
 
+	"github.com/helm/helm-classic/kubectl"
 	"github.com/helm/helm-classic/test"
 )
 
 var (
-	// mock responses from kubectl
-	mockFoundGetter    = func(string) string { return "installed" }
-	mockNotFoundGetter = func(string) string { return "not found" }
-	mockFailConnection = func(string) string { return "unable to connect to a server" }
+	// mock responses from kubectl and the API client
+	mockFoundGetter    = func([]byte) ([]byte, error) { return []byte("{}"), nil }
+	mockNotFoundGetter = func([]byte) ([]byte, error) {
+		return []byte(`Error from server (NotFound): pods "x" not found`), errors.New("exit status 1")
+	}
+	mockAPINotFound    = func([]byte) ([]byte, error) { return nil, &kubectl.APIError{Code: 404, Reason: kubectl.ReasonNotFound} }
+	mockFailConnection = func([]byte) ([]byte, error) {
+		return []byte("Unable to connect to the server"), errors.New("exit status 1")
+	}
+	mockAPIFailConnection = func([]byte) ([]byte, error) {
+		return nil, errors.New("dial tcp 127.0.0.1:6443: connection refused")
+	}
 )
 
 func TestTRemove(t *testing.T) {
@@ -27,7 +37,7 @@ func TestTRemove(t *testing.T) {
 		{"kitchensink", mockNotFoundGetter, false, "All clear! You have successfully removed kitchensink from your workspace."},
 
 		// when manifests are installed
-		{"kitchensink", mockFoundGetter, false, "Found 12 installed manifests for kitchensink.  To remove a chart that has been installed the --force flag must be set."},
+		{"kitchensink", mockFoundGetter, false, "Found 13 installed manifests for kitchensink.  To remove a chart that has been installed the --force flag must be set."},
 
 		// when manifests are installed and force is set
 		{"kitchensink", mockNotFoundGetter, true, "All clear! You have successfully removed kitchensink from your workspace."},
@@ -35,6 +45,10 @@ func TestTRemove(t *testing.T) {
 		// when kubectl cannot connect
 		{"kitchensink", mockFailConnection, false, "Could not determine if kitchensink is installed.  To remove the chart --force flag must be set."},
 
+		// when the API client reports missing objects, or cannot connect
+		{"kitchensink", mockAPINotFound, false, "All clear! You have successfully removed kitchensink from your workspace."},
+		{"kitchensink", mockAPIFailConnection, false, "Could not determine if kitchensink is installed."},
+
 		// when kubectl cannot connect and force is set
 		{"kitchensink", mockFailConnection, true, "All clear! You have successfully removed kitchensink from your workspace."},
 	}
@@ -43,7 +57,7 @@ func TestTRemove(t *testing.T) {
 		tmpHome := test.CreateTmpHome()
 		test.FakeUpdate(tmpHome)
 
-		Fetch("kitchensink", "", tmpHome)
+		Fetch("kitchensink", "", tmpHome, nil)
 
 		// set the mock getter
 		kubeGet = tt.getter
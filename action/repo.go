@@ -1,6 +1,7 @@
 package action
 
 import (
+	"github.com/helm/helm-classic/config"
 	"github.com/helm/helm-classic/log"
 )
 
@@ -13,15 +14,26 @@ func ListRepos(homedir string) {
 		if t.Name == rf.Default {
 			n += "*"
 		}
+		if t.IsHTTP() {
+			log.Msg("\t%s\t%s\t(http)", n, t.Repo)
+			continue
+		}
+		if t.Ref != "" {
+			log.Msg("\t%s\t%s\t%s", n, t.Repo, t.Ref)
+			continue
+		}
 		log.Msg("\t%s\t%s", n, t.Repo)
 	}
 }
 
 // AddRepo adds a repo to the list of repositories.
-func AddRepo(homedir, name, repository string) {
+//
+// The table may be a Git repository, optionally pinned to a ref, or an HTTP
+// table. See config.Table.
+func AddRepo(homedir string, table *config.Table) {
 	cfg := mustConfig(homedir)
 
-	if err := cfg.Repos.Add(name, repository); err != nil {
+	if err := cfg.Repos.Add(table); err != nil {
 		log.Die(err.Error())
 	}
 	if err := cfg.Save(""); err != nil {
@@ -1,10 +1,16 @@
 package action
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/config"
 	"github.com/helm/helm-classic/log"
 	"github.com/helm/helm-classic/test"
+	"github.com/helm/helm-classic/util"
 )
 
 func TestListRepos(t *testing.T) {
@@ -19,3 +25,46 @@ func TestListRepos(t *testing.T) {
 
 	test.ExpectContains(t, actual, "charts*\thttps://github.com/helm/charts")
 }
+
+func TestHTTPRepo(t *testing.T) {
+	homedir := test.CreateTmpHome()
+	defer os.RemoveAll(homedir)
+	test.FakeUpdate(homedir)
+
+	data, digest := test.ChartArchive("memcached", map[string]string{
+		"Chart.yaml":         "name: memcached\nversion: 1.4.0\ndescription: A distributed memory cache\n",
+		"manifests/pod.yaml": "apiVersion: v1\nkind: Pod\nmetadata:\n  name: memcached\n",
+	})
+	index := "charts:\n- name: memcached\n  version: 1.4.0\n  digest: " + digest + "\n  url: memcached-1.4.0.tgz\n"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.yaml":
+			w.Write([]byte(index))
+		case "/memcached-1.4.0.tgz":
+			w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	actual := test.CaptureOutput(func() {
+		AddRepo(homedir, &config.Table{Name: "static", Repo: ts.URL + "/index.yaml", Type: config.TableHTTP})
+		ListRepos(homedir)
+		Search("memory", homedir, false)
+		Info("static/memcached", homedir, "")
+		Fetch("static/memcached", "", homedir, nil)
+	})
+	test.ExpectContains(t, actual, "static\t"+ts.URL+"/index.yaml\t(http)")
+	test.ExpectContains(t, actual, "static/memcached - A distributed memory cache")
+	test.ExpectContains(t, actual, "Version: 1.4.0")
+	test.ExpectContains(t, actual, "Fetched chart into workspace")
+
+	cf, err := chart.LoadChartfile(util.WorkspaceChartDirectory(homedir, "memcached", Chartfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.From == nil || cf.From.Repo != ts.URL+"/index.yaml" || cf.From.Version != "1.4.0" {
+		t.Errorf("Expected the chart to come from the HTTP table, got %+v", cf.From)
+	}
+}
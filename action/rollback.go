@@ -0,0 +1,67 @@
+package action
+
+import (
+	"fmt"
+
+	"github.com/helm/helm-classic/kubectl"
+	"github.com/helm/helm-classic/log"
+	"github.com/helm/helm-classic/record"
+	helm "github.com/helm/helm-classic/util"
+)
+
+// Rollback restores a release to the manifests of a previous revision.
+//
+// The manifests recorded for the given revision are re-applied to Kubernetes.
+// Objects that did not exist in that revision are deleted. The current state
+// of the release is taken from its history. See record.Live. The rollback is
+// recorded as a new revision of the release.
+//
+// Only revisions that were deployed can be rolled back to. A revision that
+// failed, or that was uninstalled, may only hold the objects that were created
+// before it stopped.
+func Rollback(name, home string, revision int, configMap bool, client kubectl.Runner) {
+	dir := helm.ReleaseDirectory(home)
+
+	cur, err := record.Live(dir, name)
+	if err == record.ErrNoRelease {
+		log.Die("No release named %q.", name)
+	} else if err != nil {
+		log.Die("Failed to load release %s: %s", name, err)
+	}
+
+	target, err := record.LoadRevision(dir, name, revision)
+	if err == record.ErrNoRevision {
+		log.Die("Release %s has no revision %d. Run `helmc history %s` to see all revisions.", name, revision, name)
+	} else if err != nil {
+		log.Die("Failed to load revision %d of release %s: %s", revision, name, err)
+	}
+	if target.Status != record.StatusDeployed && target.Status != record.StatusSuperseded {
+		log.Die("Revision %d of release %s is %s, and its record may not hold every object of the chart. Roll back to a DEPLOYED or SUPERSEDED revision.", revision, name, target.Status)
+	}
+
+	if cur.Revision == revision && cur.Status == record.StatusDeployed {
+		log.Info("Release %s is already at revision %d. Nothing to do.", name, revision)
+		return
+	}
+	if cur.Status != record.StatusDeleted && cur.Namespace != target.Namespace {
+		log.Die("Release %s is installed in namespace %q, but revision %d was in %q. Uninstall it first.", name, cur.Namespace, revision, target.Namespace)
+	}
+
+	CheckKubePrereqs()
+
+	rel := target.Revise()
+	next := rel.Manifests
+	rel.Manifests = nil
+
+	log.Info("Rolling back %s to revision %d (version %s) ...", name, revision, target.Version)
+	if err := syncRelease(next, cur, rel.Namespace, client, rel); err != nil {
+		rel.Status = record.StatusFailed
+		rel.Description = fmt.Sprintf("Rollback to %d failed: %s", revision, err)
+		saveRelease(home, rel, configMap, client)
+		log.Die("Failed to roll back: %s", err)
+	}
+	rel.Status = record.StatusDeployed
+	rel.Description = fmt.Sprintf("Rolled back to %d", revision)
+	saveRelease(home, rel, configMap, client)
+	log.Info("Done")
+}
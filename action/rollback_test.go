@@ -0,0 +1,79 @@
+package action
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/helm/helm-classic/record"
+	"github.com/helm/helm-classic/test"
+	"github.com/helm/helm-classic/util"
+)
+
+func TestRollback(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	defer os.RemoveAll(tmpHome)
+	test.FakeUpdate(tmpHome)
+
+	pp := os.Getenv("PATH")
+	defer os.Setenv("PATH", pp)
+	os.Setenv("PATH", filepath.Join(test.HelmRoot, "testdata")+":"+pp)
+
+	actual := test.CaptureOutput(func() {
+		Rollback("redis", tmpHome, 1, false, TestRunner{})
+	})
+	test.ExpectContains(t, actual, "No release named \"redis\".")
+
+	test.CaptureOutput(func() {
+		Install("redis", tmpHome, &InstallOptions{Namespace: "default"}, TestRunner{})
+	})
+
+	svc := util.WorkspaceChartDirectory(tmpHome, "redis", "manifests", "redis-svc.yaml")
+	if err := ioutil.WriteFile(svc, []byte(upgradeService), 0644); err != nil {
+		t.Fatal(err)
+	}
+	test.CaptureOutput(func() {
+		Upgrade("redis", tmpHome, "", false, false, TestRunner{})
+	})
+
+	actual = test.CaptureOutput(func() {
+		Rollback("redis", tmpHome, 5, false, TestRunner{})
+	})
+	test.ExpectContains(t, actual, "Release redis has no revision 5.")
+
+	actual = test.CaptureOutput(func() {
+		Rollback("redis", tmpHome, 1, false, TestRunner{})
+	})
+	test.ExpectContains(t, actual, "Rolling back redis to revision 1")
+	test.ExpectContains(t, actual, "Deleting Service/redis")
+
+	actual = test.CaptureOutput(func() {
+		Rollback("redis", tmpHome, 3, false, TestRunner{})
+	})
+	test.ExpectContains(t, actual, "Release redis is already at revision 3.")
+
+	rel, err := record.LoadRevision(util.ReleaseDirectory(tmpHome), "redis", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel.Status = record.StatusFailed
+	if err := record.Save(util.ReleaseDirectory(tmpHome), rel); err != nil {
+		t.Fatal(err)
+	}
+	actual = test.CaptureOutput(func() {
+		Rollback("redis", tmpHome, 2, false, TestRunner{})
+	})
+	test.ExpectContains(t, actual, "Revision 2 of release redis is FAILED")
+	rel.Status = record.StatusSuperseded
+	if err := record.Save(util.ReleaseDirectory(tmpHome), rel); err != nil {
+		t.Fatal(err)
+	}
+
+	actual = test.CaptureOutput(func() {
+		History("redis", tmpHome)
+	})
+	test.ExpectMatches(t, actual, `1\s+\S+\s+SUPERSEDED\s+0.0.1\s+default\s+Install complete`)
+	test.ExpectMatches(t, actual, `2\s+\S+\s+SUPERSEDED\s+0.0.1\s+default\s+Upgraded from version 0.0.1`)
+	test.ExpectMatches(t, actual, `3\s+\S+\s+DEPLOYED\s+0.0.1\s+default\s+Rolled back to 1`)
+}
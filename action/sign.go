@@ -0,0 +1,91 @@
+package action
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/helm/helm-classic/log"
+	"github.com/helm/helm-classic/provenance"
+	helm "github.com/helm/helm-classic/util"
+)
+
+// keyExt is the extension of private key files.
+const keyExt = ".key"
+
+// GenerateKey creates a signing key named name.
+//
+// The private key is written to $HELMC_HOME/keys, and the public key is added
+// to the keyring in $HELMC_HOME/keyring so that charts signed with it can be
+// verified. Existing keys are never overwritten.
+func GenerateKey(name, home string) {
+	k, err := provenance.GenerateKey(name)
+	if err != nil {
+		log.Die("Could not generate a key: %s", err)
+	}
+	for _, dir := range []string{helm.KeyDirectory(home), helm.KeyringDirectory(home)} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			log.Die("Could not create %s: %s", dir, err)
+		}
+	}
+	priv := helm.KeyDirectory(home, name+keyExt)
+	pub := helm.KeyringDirectory(home, name+provenance.PublicKeyExt)
+	if err := k.SavePrivate(priv); err != nil {
+		log.Die("Could not save the private key: %s", err)
+	}
+	if err := k.SavePublic(pub); err != nil {
+		log.Die("Could not save the public key: %s", err)
+	}
+	log.Info("Created signing key %s in %s", name, priv)
+	log.Info("Share %s with the people who install your charts.", pub)
+}
+
+// Sign signs a chart in the workspace.
+//
+// keyfile is the private key to sign with. If it is empty, the only key in
+// $HELMC_HOME/keys is used.
+func Sign(chartName, home, keyfile string) {
+	if keyfile == "" {
+		keys, _ := filepath.Glob(helm.KeyDirectory(home, "*"+keyExt))
+		if len(keys) != 1 {
+			log.Die("Found %d keys in %s. Choose one with --key, or create one with --generate-key.", len(keys), helm.KeyDirectory(home))
+		}
+		keyfile = keys[0]
+	}
+	k, err := provenance.LoadKey(keyfile)
+	if err != nil {
+		log.Die("Could not load key: %s", err)
+	}
+
+	cd := helm.WorkspaceChartDirectory(home, chartName)
+	sig, err := provenance.Sign(cd, k)
+	if err != nil {
+		log.Die("Could not sign %s: %s", chartName, err)
+	}
+	log.Info("Signed %s with %s (%s)", chartName, k.Name, sig.Digest)
+}
+
+// checkSignature verifies the signature of the chart in dir against the
+// keyring in $HELMC_HOME/keyring.
+func checkSignature(dir, home string) (*provenance.Signature, error) {
+	ring, err := provenance.LoadKeyring(helm.KeyringDirectory(home))
+	if err != nil {
+		return nil, err
+	}
+	return provenance.Verify(dir, ring)
+}
+
+// verifyChart dies unless the chart in dir is signed by a key in the keyring
+// and has not changed since it was signed.
+func verifyChart(dir, name, home string) {
+	sig, err := checkSignature(dir, home)
+	if err != nil {
+		log.Die("Refusing chart %s: %s.", name, err)
+	}
+	log.Info("Verified %s, signed by %s", name, sig.Key)
+}
+
+// tableVerifies reports whether a table is configured to verify signatures.
+func tableVerifies(home, table string) bool {
+	t := mustConfig(home).Repos.Table(table)
+	return t != nil && t.Verify
+}
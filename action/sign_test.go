@@ -0,0 +1,41 @@
+package action
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/helm/helm-classic/provenance"
+	"github.com/helm/helm-classic/test"
+	"github.com/helm/helm-classic/util"
+)
+
+func TestSignVerify(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	test.FakeUpdate(tmpHome)
+
+	test.CaptureOutput(func() {
+		GenerateKey("alice", tmpHome)
+		Fetch("redis", "", tmpHome, nil)
+	})
+	cd := util.WorkspaceChartDirectory(tmpHome, "redis")
+	if _, err := checkSignature(cd, tmpHome); err == nil {
+		t.Error("Expected an unsigned chart to fail verification")
+	}
+
+	// Sign the chart in the table, then fetch it again under another name.
+	test.CaptureOutput(func() {
+		Sign("redis", tmpHome, util.KeyDirectory(tmpHome, "alice.key"))
+	})
+	if _, err := checkSignature(cd, tmpHome); err != nil {
+		t.Fatalf("Expected the signed chart to verify: %s", err)
+	}
+	src := filepath.Join(tmpHome, "cache", "charts", "redis")
+	if err := util.CopyFile(filepath.Join(cd, provenance.SignatureFile), filepath.Join(src, provenance.SignatureFile)); err != nil {
+		t.Fatal(err)
+	}
+
+	actual := test.CaptureOutput(func() {
+		Fetch("redis", "cache", tmpHome, &FetchOptions{Verify: true})
+	})
+	test.ExpectContains(t, actual, "Verified redis, signed by alice")
+}
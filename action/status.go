@@ -0,0 +1,61 @@
+package action
+
+import (
+	"text/template"
+
+	"github.com/helm/helm-classic/kubectl"
+	"github.com/helm/helm-classic/log"
+	"github.com/helm/helm-classic/record"
+	helm "github.com/helm/helm-classic/util"
+)
+
+const defaultStatusFormat = `Name: {{.Name}}
+Revision: {{.Revision}}
+Chart: {{.Chart}} {{.Version}}
+{{if .From}}From: {{.From.Name}} {{.From.Version}} {{.From.Repo}}
+{{end}}Namespace: {{.Namespace}}
+Installed: {{.Timestamp}}
+Status: {{.Status}}
+{{if .Description}}Description: {{.Description}}
+{{end}}Manifests:
+{{range .Manifests}}	{{.Kind}}/{{.Name}}
+{{end}}`
+
+// Status prints the record of an installed release.
+//
+// - name is the name of the release
+// - homedir is the helm home directory for the user
+// - namespace, if set, reads the release from a ConfigMap in that namespace
+//   instead of from $HELMC_HOME
+// - format is an optional Go template
+func Status(name, homedir, namespace, format string, client kubectl.Runner) {
+	var rel *record.Release
+	var err error
+
+	if namespace == "" {
+		rel, err = record.Load(helm.ReleaseDirectory(homedir), name)
+	} else {
+		var out []byte
+		out, err = client.Get(record.ConfigMapRef(name), namespace)
+		if err != nil {
+			log.Die("Could not get release %s from namespace %s: %s %s", name, namespace, err, out)
+		}
+		rel, err = record.FromConfigMap(out)
+	}
+	if err != nil {
+		log.Die("Could not load release %s: %s", name, err)
+	}
+
+	if format == "" {
+		format = defaultStatusFormat
+	}
+
+	tmpl, err := template.New("status").Parse(format)
+	if err != nil {
+		log.Die("%s", err)
+	}
+
+	if err = tmpl.Execute(log.Stdout, rel); err != nil {
+		log.Die("%s", err)
+	}
+}
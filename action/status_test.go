@@ -0,0 +1,50 @@
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/record"
+	"github.com/helm/helm-classic/test"
+)
+
+func TestStatus(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	defer os.RemoveAll(tmpHome)
+	test.FakeUpdate(tmpHome)
+
+	pp := os.Getenv("PATH")
+	defer os.Setenv("PATH", pp)
+	os.Setenv("PATH", filepath.Join(test.HelmRoot, "testdata")+":"+pp)
+
+	test.CaptureOutput(func() {
+		Install("redis", tmpHome, &InstallOptions{Namespace: "default"}, TestRunner{})
+	})
+
+	actual := test.CaptureOutput(func() {
+		Status("redis", tmpHome, "", "", TestRunner{})
+	})
+
+	test.ExpectContains(t, actual, "Chart: redis 0.0.1")
+	test.ExpectContains(t, actual, "From: redis-standalone 0.0.1")
+	test.ExpectContains(t, actual, "Namespace: default")
+	test.ExpectContains(t, actual, "Status: DEPLOYED")
+	test.ExpectContains(t, actual, "Pod/redis")
+}
+
+func TestStatusConfigMap(t *testing.T) {
+	rel := record.New("redis", "prod", &chart.Chartfile{Name: "redis-standalone", Version: "0.0.2"})
+	rel.Status = record.StatusDeployed
+	data, err := rel.ConfigMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := test.CaptureOutput(func() {
+		Status("redis", "", "prod", "{{.Version}} in {{.Namespace}}", TestRunner{out: data})
+	})
+
+	test.ExpectContains(t, actual, "0.0.2 in prod")
+}
@@ -10,32 +10,127 @@ import (
 	"github.com/helm/helm-classic/kubectl"
 	"github.com/helm/helm-classic/log"
 	"github.com/helm/helm-classic/manifest"
+	"github.com/helm/helm-classic/record"
 	helm "github.com/helm/helm-classic/util"
 )
 
 // Uninstall removes a chart from Kubernetes.
 //
-// Manifests are removed from Kubernetes in the order specified by
-// chart.UninstallOrder. Any unknown types are removed before that sequence
-// is run.
-func Uninstall(chartName, home, namespace string, force bool, client kubectl.Runner) {
-	// This is a stop-gap until kubectl respects namespaces in manifests.
-	if namespace == "" {
-		log.Die("This command requires a namespace. Did you mean '-n default'?")
-	}
-	if !chartFetched(chartName, home) {
-		log.Info("No chart named %q in your workspace. Nothing to delete.", chartName)
-		return
+// The objects to delete are found in Kubernetes, by the chart.AnnRelease
+// annotation that every installed object carries, or by the chart.AnnChartName
+// annotation for objects installed before there was a release annotation. See
+// findObjects. This works even if the chart has been changed or removed from
+// the workspace. If namespace is empty, the namespace of the release record is
+// searched, and if there is no record, every namespace is. The objects are
+// listed, and must be confirmed unless force is true.
+//
+// Objects are removed in the order specified by UninstallOrder, unless the
+// chart in the workspace overrides it. Any unknown types are removed before
+// that sequence is run. Objects with a higher chart.AnnWeight are removed
+// first.
+//
+// If the objects cannot be listed, the manifests of the chart in the workspace
+// are deleted from namespace instead.
+//
+// If the chart is in the workspace, its predelete hooks run first, and the
+// uninstall is aborted if they fail. Its postdelete hooks run after everything
+// has been deleted. Hooks that run commands on this machine require allowHooks
+// to be true. See checkHookCommands.
+func Uninstall(chartName, home, namespace string, force, allowHooks bool, client kubectl.Runner) {
+	var c *chart.Chart
+	cd := helm.WorkspaceChartDirectory(home, chartName)
+	if chartFetched(chartName, home) {
+		var err error
+		if c, err = chart.Load(cd); err != nil {
+			log.Warn("Could not load chart %s from your workspace: %s", chartName, err)
+			c = nil
+		}
+	}
+	rel, err := record.Load(helm.ReleaseDirectory(home), chartName)
+	if err != nil {
+		log.Debug("No release for %s: %s", chartName, err)
+		rel = nil
+	}
+	if namespace == "" && rel != nil {
+		namespace = rel.Namespace
 	}
 
-	cd := helm.WorkspaceChartDirectory(home, chartName)
-	c, err := chart.Load(cd)
+	objs, err := findObjects(chartName, namespace, c, rel, client)
 	if err != nil {
-		log.Die("Failed to load chart: %s", err)
+		if c == nil {
+			log.Die("Could not find the objects of %s in Kubernetes: %s", chartName, err)
+		}
+		// This is a stop-gap until kubectl respects namespaces in manifests.
+		if namespace == "" {
+			log.Die("Could not find the objects of %s in Kubernetes: %s. Pass a namespace to delete the manifests in your workspace instead. Did you mean '-n default'?", chartName, err)
+		}
+		log.Warn("Could not find the objects of %s in Kubernetes: %s", chartName, err)
+		log.Info("Deleting the manifests of the chart in your workspace instead.")
+		uninstallChart(c, cd, chartName, home, namespace, force, allowHooks, client)
+		return
+	}
+	if len(objs) == 0 {
+		if namespace == "" {
+			log.Info("No objects from %q found in Kubernetes. Nothing to delete.", chartName)
+		} else {
+			log.Info("No objects from %q found in namespace %q. Nothing to delete.", chartName, namespace)
+		}
+		return
+	}
+
+	for _, o := range objs {
+		if o.Namespace == "" {
+			log.Msg("%s/%s", o.Kind, o.Name)
+		} else {
+			log.Msg("%s/%s (namespace %s)", o.Kind, o.Name, o.Namespace)
+		}
+	}
+	if c != nil {
+		checkHookCommands(c, allowHooks, chart.HookPreDelete, chart.HookPostDelete)
+	}
+	if !force && !promptConfirm("Uninstall the listed objects?") {
+		log.Info("Aborted uninstall")
+		return
+	}
+
+	CheckKubePrereqs()
+
+	namespaces := objectNamespaces(objs, namespace)
+	if c != nil {
+		for _, ns := range namespaces {
+			if err := runHooks(chart.HookPreDelete, c, cd, home, ns, false, allowHooks, 0, client); err != nil {
+				log.Die("Aborting uninstall. The predelete hook failed: %s", err)
+			}
+		}
+	} else {
+		log.Warn("Chart %s is not in your workspace. Its hooks will not run.", chartName)
+	}
+
+	log.Info("Running `kubectl delete` ...")
+	for _, o := range objs {
+		deleteObject(&o.Object, o.Namespace, client)
 	}
+	for _, ns := range namespaces {
+		markDeleted(chartName, home, ns)
+	}
+
+	if c != nil {
+		for _, ns := range namespaces {
+			if err := runHooks(chart.HookPostDelete, c, cd, home, ns, false, allowHooks, 0, client); err != nil {
+				log.Err("The postdelete hook failed: %s", err)
+			}
+		}
+	}
+	log.Info("Done")
+}
+
+// uninstallChart deletes the manifests of a chart in the workspace from a
+// namespace.
+func uninstallChart(c *chart.Chart, cd, chartName, home, namespace string, force, allowHooks bool, client kubectl.Runner) {
 	if err := deleteChart(c, namespace, true, client); err != nil {
 		log.Die("Failed to list charts: %s", err)
 	}
+	checkHookCommands(c, allowHooks, chart.HookPreDelete, chart.HookPostDelete)
 	if !force && !promptConfirm("Uninstall the listed objects?") {
 		log.Info("Aborted uninstall")
 		return
@@ -43,13 +138,43 @@ func Uninstall(chartName, home, namespace string, force bool, client kubectl.Run
 
 	CheckKubePrereqs()
 
+	if err := runHooks(chart.HookPreDelete, c, cd, home, namespace, false, allowHooks, 0, client); err != nil {
+		log.Die("Aborting uninstall. The predelete hook failed: %s", err)
+	}
+
 	log.Info("Running `kubectl delete` ...")
 	if err := deleteChart(c, namespace, false, client); err != nil {
 		log.Die("Failed to completely delete chart: %s", err)
 	}
+	markDeleted(chartName, home, namespace)
+
+	if err := runHooks(chart.HookPostDelete, c, cd, home, namespace, false, allowHooks, 0, client); err != nil {
+		log.Err("The postdelete hook failed: %s", err)
+	}
 	log.Info("Done")
 }
 
+// markDeleted updates the release record for a chart, if there is one.
+func markDeleted(name, home, namespace string) {
+	dir := helm.ReleaseDirectory(home)
+	rel, err := record.Load(dir, name)
+	if err == record.ErrNoRelease {
+		log.Debug("No release recorded for %s", name)
+		return
+	} else if err != nil {
+		log.Warn("Could not load release %s: %s", name, err)
+		return
+	}
+	if rel.Namespace != namespace {
+		log.Debug("Release %s is in namespace %q, not %q", name, rel.Namespace, namespace)
+		return
+	}
+	rel.Status = record.StatusDeleted
+	if err := record.Save(dir, rel); err != nil {
+		log.Warn("Could not update release %s: %s", name, err)
+	}
+}
+
 // promptConfirm prompts a user to confirm (or deny) something.
 //
 // True is returned iff the prompt is confirmed.
@@ -107,23 +232,56 @@ func (x *rw) Write(b []byte) (int, error) {
 }
 
 // deleteChart deletes all of the Kubernetes manifests associated with this chart.
+//
+// Manifests are deleted in the order computed by uninstallPlan.
 func deleteChart(c *chart.Chart, ns string, dry bool, client kubectl.Runner) error {
-	// Unknown kinds get uninstalled first because we know that core kinds
-	// do not depend on them.
-	for _, kind := range c.UnknownKinds(UninstallOrder) {
-		uninstallKind(c.Kind[kind], ns, kind, dry, client)
+	plan, err := uninstallPlan(c)
+	if err != nil {
+		return err
 	}
+	uninstallManifests(plan, ns, dry, client)
+	return nil
+}
 
-	// Uninstall all of the known kinds in a particular order.
-	for _, kind := range UninstallOrder {
-		uninstallKind(c.Kind[kind], ns, kind, dry, client)
+// uninstallKinds sorts the given kinds into the order in which they are uninstalled.
+//
+// The kinds in first come first, in that order. Unknown kinds come next,
+// followed by the kinds in UninstallOrder. Duplicates are removed.
+func uninstallKinds(kinds, first []string) []string {
+	known := make(map[string]bool, len(UninstallOrder))
+	for _, k := range UninstallOrder {
+		known[k] = true
 	}
 
-	return nil
+	present := map[string]bool{}
+	for _, k := range kinds {
+		present[k] = true
+	}
+
+	res := []string{}
+	for _, k := range first {
+		if present[k] {
+			res = append(res, k)
+			delete(present, k)
+		}
+	}
+	for _, k := range kinds {
+		if present[k] && !known[k] {
+			res = append(res, k)
+			delete(present, k)
+		}
+	}
+	for _, k := range UninstallOrder {
+		if present[k] {
+			res = append(res, k)
+		}
+	}
+	return res
 }
 
-func uninstallKind(kind []*manifest.Manifest, ns, ktype string, dry bool, client kubectl.Runner) {
-	for _, o := range kind {
+func uninstallManifests(manifests []*manifest.Manifest, ns string, dry bool, client kubectl.Runner) {
+	for _, o := range manifests {
+		ktype := o.Kind
 		if dry {
 			log.Msg("%s/%s", ktype, o.Name)
 		} else {
@@ -135,7 +293,7 @@ func uninstallKind(kind []*manifest.Manifest, ns, ktype string, dry bool, client
 					continue
 				}
 			}
-			out, err := client.Delete(o.Name, ktype, ns)
+			out, err := client.Delete(o.Name, kubectl.QualifiedKind(o.Version, ktype), ns)
 			if err != nil {
 				log.Warn("Could not delete %s %s (Skipping): %s", ktype, o.Name, err)
 			}
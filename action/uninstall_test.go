@@ -2,7 +2,11 @@ package action
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/helm/helm-classic/kubectl"
@@ -41,6 +45,7 @@ func TestUninstall(t *testing.T) {
 			force: true,
 			expected: []string{"Running `kubectl delete` ...", "Not uninstalling",
 				"because of \"helm-keep\" annotation"},
+			client: TestRunner{},
 		},
 	}
 
@@ -49,10 +54,10 @@ func TestUninstall(t *testing.T) {
 	test.FakeUpdate(tmpHome)
 
 	for _, tt := range tests {
-		Fetch(tt.chart, "", tmpHome)
+		Fetch(tt.chart, "", tmpHome, nil)
 
 		actual := test.CaptureOutput(func() {
-			Uninstall(tt.chart, tmpHome, "default", tt.force, tt.client)
+			Uninstall(tt.chart, tmpHome, "default", tt.force, false, tt.client)
 		})
 
 		for _, exp := range tt.expected {
@@ -60,3 +65,108 @@ func TestUninstall(t *testing.T) {
 		}
 	}
 }
+
+func TestInstallUninstallReplay(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	defer os.RemoveAll(tmpHome)
+	test.FakeUpdate(tmpHome)
+
+	pp := os.Getenv("PATH")
+	defer os.Setenv("PATH", pp)
+	os.Setenv("PATH", filepath.Join(test.HelmRoot, "testdata")+":"+pp)
+
+	f, err := os.Open(filepath.Join(test.HelmRoot, "testdata", "redis-transcript.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	client, err := kubectl.NewReplayer(f)
+	if err != nil {
+		t.Fatalf("Failed to read transcript: %s", err)
+	}
+
+	actual := test.CaptureOutput(func() {
+		Install("redis", tmpHome, &InstallOptions{Namespace: "default"}, client)
+	})
+	test.ExpectContains(t, actual, `pod "redis" created`)
+
+	actual = test.CaptureOutput(func() {
+		Uninstall("redis", tmpHome, "default", true, false, client)
+	})
+	test.ExpectContains(t, actual, `pod "redis" deleted`)
+
+	if r := client.Remaining(); len(r) > 0 {
+		t.Errorf("Expected every recorded call to be made, %d were not", len(r))
+	}
+}
+
+// listRunner is a TestRunner that lists fixed objects, and records what it is
+// asked to delete.
+type listRunner struct {
+	TestRunner
+	items   []string
+	deleted *[]string
+}
+
+func (r listRunner) List(kinds []string, ns string) ([]byte, error) {
+	return []byte(`{"kind": "List", "items": [` + strings.Join(r.items, ",") + `]}`), nil
+}
+
+func (r listRunner) Delete(name, ktype, ns string) ([]byte, error) {
+	*r.deleted = append(*r.deleted, ns+":"+ktype+"/"+name)
+	return nil, nil
+}
+
+func TestUninstallWithoutChart(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	defer os.RemoveAll(tmpHome)
+	test.FakeUpdate(tmpHome)
+
+	// Every object comes from the chart web, but they belong to two releases.
+	object := func(kind, name, ns, release string, annotations string) string {
+		if release != "" {
+			annotations = fmt.Sprintf(`, "chart.helm.sh/release": %q`, release) + annotations
+		}
+		return fmt.Sprintf(`{"kind": %q, "metadata": {"name": %q, "namespace": %q, "annotations": {"chart.helm.sh/name": "web"%s}}}`,
+			kind, name, ns, annotations)
+	}
+	client := listRunner{
+		items: []string{
+			object("Pod", "web", "staging", "web", ""),
+			object("Secret", "web", "staging", "web", ""),
+			object("Pod", "web2", "staging", "web2", ""),
+			object("Pod", "old", "staging", "", ""),
+			object("ConfigMap", "late", "staging", "web", `, "chart.helm.sh/weight": "5"`),
+			object("PersistentVolume", "disk", "", "web", `, "helm-keep": "true"`),
+		},
+		deleted: &[]string{},
+	}
+
+	// Without a namespace or a release record, every namespace is searched.
+	actual := test.CaptureOutput(func() {
+		Uninstall("web", tmpHome, "", true, false, client)
+	})
+	test.ExpectContains(t, actual, "Pod/web (namespace staging)")
+	test.ExpectContains(t, actual, "Not deleting PersistentVolume disk")
+
+	expected := []string{"staging:ConfigMap/late", "staging:Pod/web", "staging:Secret/web"}
+	if !reflect.DeepEqual(*client.deleted, expected) {
+		t.Errorf("Expected %v to be deleted, got %v", expected, *client.deleted)
+	}
+
+	// Objects installed before the release annotation are found by chart
+	// name, unless they belong to another release.
+	client.items = []string{
+		object("Pod", "web2", "staging", "web2", ""),
+		object("Pod", "old", "staging", "", ""),
+		object("Service", "old", "staging", "", ""),
+	}
+	client.deleted = &[]string{}
+	test.CaptureOutput(func() {
+		Uninstall("web", tmpHome, "staging", true, false, client)
+	})
+	expected = []string{"staging:Service/old", "staging:Pod/old"}
+	if !reflect.DeepEqual(*client.deleted, expected) {
+		t.Errorf("Expected %v to be deleted, got %v", expected, *client.deleted)
+	}
+}
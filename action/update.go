@@ -9,8 +9,11 @@ import (
 	"github.com/helm/helm-classic/release"
 )
 
-// Update fetches the remote repo into the home directory.
-func Update(home string) {
+// Update fetches the remote repos into the home directory.
+//
+// Up to workers repos are updated at once. Every repo is tried, even if some
+// fail; Update only dies after that.
+func Update(home string, workers int) {
 	home, err := filepath.Abs(home)
 	if err != nil {
 		log.Die("Could not generate absolute path for %q: %s", home, err)
@@ -19,7 +22,7 @@ func Update(home string) {
 	CheckLocalPrereqs(home)
 
 	rc := mustConfig(home).Repos
-	if err := rc.UpdateAll(); err != nil {
+	if err := rc.UpdateAll(workers); err != nil {
 		log.Die("Not all repos could be updated: %s", err)
 	}
 	log.Info("Done")
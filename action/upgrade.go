@@ -0,0 +1,196 @@
+package action
+
+import (
+	"encoding/json"
+
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/kubectl"
+	"github.com/helm/helm-classic/log"
+	"github.com/helm/helm-classic/manifest"
+	"github.com/helm/helm-classic/record"
+	helm "github.com/helm/helm-classic/util"
+)
+
+// Upgrade applies the changes in a workspace chart to an installed release.
+//
+// The chart in the workspace is compared to the objects that the history of
+// the release says are in Kubernetes. See record.Live. Changed objects are sent
+// to Kubernetes with `kubectl apply`, new objects are created in the order
+// specified by InstallOrder, and objects that are no longer in the chart are
+// deleted in the order specified by UninstallOrder.
+//
+// If namespace is empty, the namespace of the release is used.
+func Upgrade(chartName, home, namespace string, force, configMap bool, client kubectl.Runner) {
+	if !chartFetched(chartName, home) {
+		log.Die("No chart named %q in your workspace.", chartName)
+	}
+
+	old, err := record.Live(helm.ReleaseDirectory(home), chartName)
+	if err == record.ErrNoRelease {
+		log.Die("No release named %q. Run `helmc install %s` first.", chartName, chartName)
+	} else if err != nil {
+		log.Die("Failed to load release %s: %s", chartName, err)
+	}
+	if old.Status == record.StatusDeleted {
+		log.Die("Release %s was uninstalled. Run `helmc install %s` instead.", chartName, chartName)
+	}
+
+	if namespace == "" {
+		namespace = old.Namespace
+	} else if namespace != old.Namespace {
+		log.Die("Release %s is installed in namespace %q, not %q.", chartName, old.Namespace, namespace)
+	}
+
+	c, err := chart.Load(helm.WorkspaceChartDirectory(home, chartName))
+	if err != nil {
+		log.Die("Failed to load chart: %s", err)
+	}
+
+	checkDependencies(c.Chartfile, home, "upgrade", force)
+
+	CheckKubePrereqs()
+
+	rel := record.New(chartName, namespace, c.Chartfile)
+
+	log.Info("Upgrading %s from version %s to %s ...", chartName, old.Version, c.Chartfile.Version)
+	if err := upgradeManifests(c, old, namespace, client, rel); err != nil {
+		rel.Status = record.StatusFailed
+		rel.Description = "Upgrade failed: " + err.Error()
+		saveRelease(home, rel, configMap, client)
+		log.Die("Failed to upgrade manifests: %s", err)
+	}
+	rel.Status = record.StatusDeployed
+	rel.Description = "Upgraded from version " + old.Version
+	saveRelease(home, rel, configMap, client)
+	log.Info("Done")
+}
+
+// upgradeManifests sends the differences between a chart and a previous
+// release to Kubernetes.
+//
+// Each manifest in the chart is added to the new release once it is in
+// Kubernetes.
+func upgradeManifests(c *chart.Chart, old *record.Release, namespace string, client kubectl.Runner, rel *record.Release) error {
+	plan, err := installPlan(c)
+	if err != nil {
+		return err
+	}
+	next := make([]*record.Object, 0, len(plan))
+	for _, m := range plan {
+		data, err := render(c, m.Manifest, rel.Name, namespace)
+		if err != nil {
+			return err
+		}
+		next = append(next, record.NewObject(m.Kind, m.Name, data))
+	}
+	return syncRelease(next, old, namespace, client, rel)
+}
+
+// syncRelease sends the differences between a list of objects and a previous
+// release to Kubernetes.
+//
+// Objects in next that are not in prev are created, in the order in which
+// they appear in next. Objects that differ are updated with `kubectl apply`.
+// Objects in prev that are not in next are deleted in the order specified by
+// UninstallOrder.
+//
+// Each object is added to rel only when it was sent successfully or was
+// unchanged, so that a failed revision records what is in Kubernetes.
+//
+// If prev was deleted, or was installed into a different namespace, every
+// object in next is created. Objects whose data was not recorded, such as
+// Secrets from an earlier revision, cannot be sent again, and are skipped.
+func syncRelease(next []*record.Object, prev *record.Release, namespace string, client kubectl.Runner, rel *record.Release) error {
+	previous := map[string]*record.Object{}
+	if prev.Status != record.StatusDeleted && prev.Namespace == namespace {
+		for _, o := range prev.Manifests {
+			previous[o.Kind+"/"+o.Name] = o
+		}
+	}
+
+	for _, o := range next {
+		key := o.Kind + "/" + o.Name
+		old, ok := previous[key]
+		delete(previous, key)
+
+		data := o.Raw()
+		if data == nil {
+			if !ok || old.Data != o.Data {
+				log.Warn("Not restoring %s, because the data of Secrets is not recorded.", key)
+				continue
+			}
+			rel.Manifests = append(rel.Manifests, o)
+			continue
+		}
+		var action func([]byte, string) ([]byte, error)
+		switch {
+		case !ok:
+			log.Info("Creating %s", key)
+			action = client.Create
+			// If it's a keeper manifest, do "kubectl apply" instead of "create."
+			if manifest.IsKeeper(data) {
+				action = client.Apply
+			}
+		case old.Data != o.Data:
+			log.Info("Updating %s", key)
+			action = client.Apply
+		default:
+			log.Debug("%s is unchanged", key)
+			rel.Manifests = append(rel.Manifests, o)
+			continue
+		}
+
+		out, err := action(data, namespace)
+		log.Msg(string(out))
+		if err != nil {
+			return err
+		}
+		rel.Manifests = append(rel.Manifests, o)
+	}
+
+	// Anything left over is no longer part of the release.
+	byKind := map[string][]*record.Object{}
+	kinds := []string{}
+	for _, o := range prev.Manifests {
+		if _, ok := previous[o.Kind+"/"+o.Name]; ok {
+			byKind[o.Kind] = append(byKind[o.Kind], o)
+			kinds = append(kinds, o.Kind)
+		}
+	}
+	for _, k := range uninstallKinds(kinds, nil) {
+		for _, o := range byKind[k] {
+			deleteObject(o, namespace, client)
+		}
+	}
+
+	return nil
+}
+
+// deleteObject deletes a recorded object from Kubernetes.
+//
+// Keeper manifests are never deleted. Failures are reported, but do not stop
+// the operation. It returns true if the object was deleted.
+func deleteObject(o *record.Object, namespace string, client kubectl.Runner) bool {
+	if manifest.IsKeeper([]byte(o.Data)) {
+		log.Warn("Not deleting %s %s because of \"helm-keep\" annotation.", o.Kind, o.Name)
+		return false
+	}
+	log.Info("Deleting %s/%s", o.Kind, o.Name)
+	out, err := client.Delete(o.Name, objectKind(o), namespace)
+	log.Msg(string(out))
+	if err != nil {
+		log.Warn("Could not delete %s %s (Skipping): %s", o.Kind, o.Name, err)
+		return false
+	}
+	return true
+}
+
+// objectKind returns the kind of a recorded object, qualified by its
+// apiVersion. See kubectl.QualifiedKind.
+func objectKind(o *record.Object) string {
+	meta := struct {
+		APIVersion string `json:"apiVersion"`
+	}{}
+	json.Unmarshal([]byte(o.Data), &meta)
+	return kubectl.QualifiedKind(meta.APIVersion, o.Kind)
+}
@@ -0,0 +1,81 @@
+package action
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/helm/helm-classic/test"
+	"github.com/helm/helm-classic/util"
+)
+
+const upgradeService = `apiVersion: v1
+kind: Service
+metadata:
+  name: redis
+spec:
+  ports:
+  - port: 6379
+`
+
+func TestUpgrade(t *testing.T) {
+	tmpHome := test.CreateTmpHome()
+	defer os.RemoveAll(tmpHome)
+	test.FakeUpdate(tmpHome)
+
+	pp := os.Getenv("PATH")
+	defer os.Setenv("PATH", pp)
+	os.Setenv("PATH", filepath.Join(test.HelmRoot, "testdata")+":"+pp)
+
+	actual := test.CaptureOutput(func() {
+		Upgrade("redis", tmpHome, "", false, false, TestRunner{})
+	})
+	test.ExpectContains(t, actual, "No chart named \"redis\" in your workspace.")
+
+	Fetch("redis", "", tmpHome, nil)
+
+	actual = test.CaptureOutput(func() {
+		Upgrade("redis", tmpHome, "", false, false, TestRunner{})
+	})
+	test.ExpectContains(t, actual, "No release named \"redis\"")
+
+	test.CaptureOutput(func() {
+		Install("redis", tmpHome, &InstallOptions{Namespace: "default"}, TestRunner{})
+	})
+
+	manifests := util.WorkspaceChartDirectory(tmpHome, "redis", "manifests")
+	svc := filepath.Join(manifests, "redis-svc.yaml")
+	if err := ioutil.WriteFile(svc, []byte(upgradeService), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	actual = test.CaptureOutput(func() {
+		Upgrade("redis", tmpHome, "", false, false, TestRunner{})
+	})
+	test.ExpectContains(t, actual, "Creating Service/redis")
+	test.ExpectMatches(t, actual, "Pod/redis is unchanged")
+
+	actual = test.CaptureOutput(func() {
+		Upgrade("redis", tmpHome, "other", false, false, TestRunner{})
+	})
+	test.ExpectContains(t, actual, "Release redis is installed in namespace \"default\", not \"other\".")
+
+	if err := os.Remove(svc); err != nil {
+		t.Fatal(err)
+	}
+	pod := filepath.Join(manifests, "redis-pod.yaml")
+	data, err := ioutil.ReadFile(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(pod, append(data, []byte("  imagePullPolicy: Always\n")...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	actual = test.CaptureOutput(func() {
+		Upgrade("redis", tmpHome, "", false, false, TestRunner{})
+	})
+	test.ExpectContains(t, actual, "Updating Pod/redis")
+	test.ExpectContains(t, actual, "Deleting Service/redis")
+}
@@ -0,0 +1,311 @@
+package action
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/helm/helm-classic/kubectl"
+	"github.com/helm/helm-classic/log"
+	"github.com/helm/helm-classic/record"
+)
+
+// waitInterval is how often waitForReady polls Kubernetes.
+var waitInterval = 2 * time.Second
+
+// readyFunc reports whether a live object is ready, along with a short
+// description of its state.
+//
+// An error is returned if the object has failed and will never become ready.
+type readyFunc func(obj map[string]interface{}) (bool, string, error)
+
+// readiness maps the kinds that can be waited for to their readiness checks.
+var readiness = map[string]readyFunc{
+	"Deployment":            deploymentReady,
+	"ReplicationController": rcReady,
+	"DaemonSet":             daemonSetReady,
+	"Pod":                   podReady,
+	"Job":                   jobReady,
+}
+
+// podCounts maps the kinds whose pods are checked as well to the number of
+// ready pods they need.
+//
+// Older servers do not report how many of their pods are ready, and only count
+// the pods that exist, even if they are crashing.
+var podCounts = map[string]func(obj map[string]interface{}) int{
+	"ReplicationController": func(obj map[string]interface{}) int {
+		return nestedInt(obj, 1, "spec", "replicas")
+	},
+	"DaemonSet": func(obj map[string]interface{}) int {
+		return nestedInt(obj, 0, "status", "desiredNumberScheduled")
+	},
+}
+
+// waitForReady polls the workloads among objs until all of them are ready, or
+// until the timeout expires.
+//
+// Deployments, ReplicationControllers, and DaemonSets are ready when all of
+// their replicas are. For ReplicationControllers and DaemonSets, this is
+// checked on the pods that their selector matches. Pods are ready when they
+// are running and all of their containers are ready, or when they have
+// completed. Jobs are ready when they
+// have completed. Other kinds are not waited for.
+//
+// If the timeout expires, the state of each object that is not ready is logged,
+// and an error is returned.
+func waitForReady(objs []*record.Object, namespace string, timeout time.Duration, client kubectl.Runner) error {
+	pending := []*record.Object{}
+	for _, o := range objs {
+		if _, ok := readiness[o.Kind]; ok {
+			pending = append(pending, o)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	log.Info("Waiting up to %s for %d objects to become ready ...", timeout, len(pending))
+	deadline := time.Now().Add(timeout)
+	state := map[*record.Object]string{}
+	for {
+		waiting := []*record.Object{}
+		for _, o := range pending {
+			ready, msg, err := checkReady(o, namespace, client)
+			if err != nil {
+				return fmt.Errorf("%s/%s failed: %s", o.Kind, o.Name, err)
+			}
+			if ready {
+				log.Info("%s/%s is ready: %s", o.Kind, o.Name, msg)
+				continue
+			}
+			state[o] = msg
+			waiting = append(waiting, o)
+		}
+		pending = waiting
+
+		if len(pending) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		log.Debug("Still waiting for %d objects", len(pending))
+		time.Sleep(waitInterval)
+	}
+
+	log.Err("Timed out after %s. These objects are not ready:", timeout)
+	for _, o := range pending {
+		log.Msg("\t%s/%s: %s", o.Kind, o.Name, state[o])
+	}
+	return fmt.Errorf("timed out waiting for %d objects to become ready", len(pending))
+}
+
+// checkReady gets the live state of an object and checks whether it is ready.
+//
+// Problems getting the object are reported as not ready, since they may be
+// temporary.
+func checkReady(o *record.Object, namespace string, client kubectl.Runner) (bool, string, error) {
+	out, err := client.Get([]byte(o.Data), namespace)
+	if err != nil {
+		log.Debug("Could not get %s/%s: %s %s", o.Kind, o.Name, err, out)
+		return false, fmt.Sprintf("could not get status: %s", err), nil
+	}
+	obj, err := decodeObject(out)
+	if err != nil {
+		return false, fmt.Sprintf("could not decode status: %s", err), nil
+	}
+	ready, msg, err := readiness[o.Kind](obj)
+	if want, ok := podCounts[o.Kind]; ok && ready && err == nil {
+		if ns, ok := nested(obj, "metadata", "namespace"); ok {
+			namespace = fmt.Sprint(ns)
+		}
+		return podsReady(selector(obj), want(obj), namespace, client)
+	}
+	return ready, msg, err
+}
+
+// podsReady checks whether at least want of the pods that match a selector are
+// running and ready.
+//
+// Problems listing the pods are reported as not ready.
+func podsReady(sel map[string]string, want int, namespace string, client kubectl.Runner) (bool, string, error) {
+	if len(sel) == 0 {
+		return false, "no pod selector", nil
+	}
+	out, err := client.List([]string{"Pod"}, namespace)
+	if err != nil {
+		log.Debug("Could not list pods: %s %s", err, out)
+		return false, fmt.Sprintf("could not list pods: %s", err), nil
+	}
+	list, err := decodeObject(out)
+	if err != nil {
+		return false, fmt.Sprintf("could not decode pods: %s", err), nil
+	}
+
+	ready := 0
+	items, _ := list["items"].([]interface{})
+	for _, item := range items {
+		pod, ok := item.(map[string]interface{})
+		if !ok || !matches(pod, sel) {
+			continue
+		}
+		if _, deleting := nested(pod, "metadata", "deletionTimestamp"); deleting {
+			continue
+		}
+		if phase, _ := nested(pod, "status", "phase"); phase == "Running" && conditionTrue(pod, "Ready") {
+			ready++
+		}
+	}
+	return ready >= want, fmt.Sprintf("%d/%d pods ready", ready, want), nil
+}
+
+// selector returns the labels that select the pods of a controller.
+//
+// It reads spec.selector.matchLabels, or spec.selector if it is a plain map,
+// and otherwise the labels of the pod template.
+func selector(obj map[string]interface{}) map[string]string {
+	for _, path := range [][]string{
+		{"spec", "selector", "matchLabels"},
+		{"spec", "selector"},
+		{"spec", "template", "metadata", "labels"},
+	} {
+		v, _ := nested(obj, path...)
+		m, _ := v.(map[string]interface{})
+		sel := map[string]string{}
+		for k, val := range m {
+			s, ok := val.(string)
+			if !ok {
+				sel = nil
+				break
+			}
+			sel[k] = s
+		}
+		if len(sel) > 0 {
+			return sel
+		}
+	}
+	return nil
+}
+
+// matches returns true if an object has all of the given labels.
+func matches(obj map[string]interface{}, sel map[string]string) bool {
+	v, _ := nested(obj, "metadata", "labels")
+	labels, _ := v.(map[string]interface{})
+	for k, val := range sel {
+		if labels[k] != val {
+			return false
+		}
+	}
+	return true
+}
+
+func deploymentReady(obj map[string]interface{}) (bool, string, error) {
+	if !observed(obj) {
+		return false, "waiting for rollout to start", nil
+	}
+	want := nestedInt(obj, 1, "spec", "replicas")
+	updated := nestedInt(obj, 0, "status", "updatedReplicas")
+	available := nestedInt(obj, 0, "status", "availableReplicas")
+	msg := fmt.Sprintf("%d/%d replicas updated, %d/%d available", updated, want, available, want)
+	return updated >= want && available >= want, msg, nil
+}
+
+func rcReady(obj map[string]interface{}) (bool, string, error) {
+	if !observed(obj) {
+		return false, "waiting for controller", nil
+	}
+	want := nestedInt(obj, 1, "spec", "replicas")
+	// Older servers do not report readyReplicas, only replicas. checkReady
+	// then looks at the pods.
+	ready := nestedInt(obj, nestedInt(obj, 0, "status", "replicas"), "status", "readyReplicas")
+	return ready >= want, fmt.Sprintf("%d/%d replicas ready", ready, want), nil
+}
+
+func daemonSetReady(obj map[string]interface{}) (bool, string, error) {
+	if _, ok := nested(obj, "status", "desiredNumberScheduled"); !ok {
+		return false, "waiting for controller", nil
+	}
+	want := nestedInt(obj, 0, "status", "desiredNumberScheduled")
+	// Older servers do not report numberReady, only currentNumberScheduled.
+	// checkReady then looks at the pods.
+	ready := nestedInt(obj, nestedInt(obj, 0, "status", "currentNumberScheduled"), "status", "numberReady")
+	return ready >= want, fmt.Sprintf("%d/%d pods ready", ready, want), nil
+}
+
+func podReady(obj map[string]interface{}) (bool, string, error) {
+	phase, _ := nested(obj, "status", "phase")
+	switch phase {
+	case "Succeeded":
+		return true, "completed", nil
+	case "Failed":
+		if reason, ok := nested(obj, "status", "reason"); ok {
+			return false, "", fmt.Errorf("pod failed: %v", reason)
+		}
+		return false, "", fmt.Errorf("pod failed")
+	case "Running":
+		if conditionTrue(obj, "Ready") {
+			return true, "running", nil
+		}
+		return false, "running, containers not ready", nil
+	case nil:
+		return false, "waiting for scheduler", nil
+	}
+	return false, fmt.Sprintf("%v", phase), nil
+}
+
+func jobReady(obj map[string]interface{}) (bool, string, error) {
+	if conditionTrue(obj, "Failed") {
+		return false, "", fmt.Errorf("job failed")
+	}
+	want := nestedInt(obj, 1, "spec", "completions")
+	done := nestedInt(obj, 0, "status", "succeeded")
+	msg := fmt.Sprintf("%d/%d completions", done, want)
+	return done >= want || conditionTrue(obj, "Complete"), msg, nil
+}
+
+// observed returns true if the controller has seen the latest spec of an object.
+func observed(obj map[string]interface{}) bool {
+	if _, ok := nested(obj, "status"); !ok {
+		return false
+	}
+	return nestedInt(obj, 0, "status", "observedGeneration") >= nestedInt(obj, 0, "metadata", "generation")
+}
+
+// conditionTrue returns true if an object has a status condition of the given
+// type, and that condition is true.
+func conditionTrue(obj map[string]interface{}, ctype string) bool {
+	conds, _ := nested(obj, "status", "conditions")
+	list, _ := conds.([]interface{})
+	for _, c := range list {
+		cond, ok := c.(map[string]interface{})
+		if ok && cond["type"] == ctype && cond["status"] == "True" {
+			return true
+		}
+	}
+	return false
+}
+
+// nested returns the value at a path of keys inside of a decoded object.
+func nested(obj map[string]interface{}, path ...string) (interface{}, bool) {
+	var v interface{} = obj
+	for _, k := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[k]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// nestedInt returns the number at a path of keys inside of a decoded object, or
+// def if there is no number there.
+func nestedInt(obj map[string]interface{}, def int, path ...string) int {
+	v, _ := nested(obj, path...)
+	if n, ok := v.(float64); ok {
+		return int(n)
+	}
+	return def
+}
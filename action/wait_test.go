@@ -0,0 +1,78 @@
+package action
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/helm/helm-classic/record"
+)
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		kind, live string
+		ready      bool
+		failed     bool
+	}{
+		{"Pod", `{}`, false, false},
+		{"Pod", `{"status": {"phase": "Running"}}`, false, false},
+		{"Pod", `{"status": {"phase": "Running", "conditions": [{"type": "Ready", "status": "True"}]}}`, true, false},
+		{"Pod", `{"status": {"phase": "Succeeded"}}`, true, false},
+		{"Pod", `{"status": {"phase": "Failed"}}`, false, true},
+		{"Deployment", `{"metadata": {"generation": 2}, "spec": {"replicas": 3}, "status": {"observedGeneration": 1, "updatedReplicas": 3, "availableReplicas": 3}}`, false, false},
+		{"Deployment", `{"metadata": {"generation": 2}, "spec": {"replicas": 3}, "status": {"observedGeneration": 2, "updatedReplicas": 3, "availableReplicas": 2}}`, false, false},
+		{"Deployment", `{"metadata": {"generation": 2}, "spec": {"replicas": 3}, "status": {"observedGeneration": 2, "updatedReplicas": 3, "availableReplicas": 3}}`, true, false},
+		{"ReplicationController", `{"spec": {"replicas": 2}, "status": {"replicas": 2, "readyReplicas": 1}}`, false, false},
+		{"ReplicationController", `{"spec": {"replicas": 2}, "status": {"replicas": 2}}`, true, false},
+		{"DaemonSet", `{"status": {}}`, false, false},
+		{"DaemonSet", `{"status": {"desiredNumberScheduled": 3, "currentNumberScheduled": 3, "numberReady": 2}}`, false, false},
+		{"DaemonSet", `{"status": {"desiredNumberScheduled": 3, "currentNumberScheduled": 3}}`, true, false},
+		{"Job", `{"spec": {"completions": 2}, "status": {"succeeded": 1}}`, false, false},
+		{"Job", `{"spec": {"completions": 2}, "status": {"succeeded": 2}}`, true, false},
+		{"Job", `{"status": {"conditions": [{"type": "Failed", "status": "True"}]}}`, false, true},
+	}
+
+	for _, tt := range tests {
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.live), &obj); err != nil {
+			t.Fatal(err)
+		}
+		ready, msg, err := readiness[tt.kind](obj)
+		if ready != tt.ready || (err != nil) != tt.failed {
+			t.Errorf("%s %s: expected ready=%t failed=%t, got ready=%t (%s) err=%v", tt.kind, tt.live, tt.ready, tt.failed, ready, msg, err)
+		}
+	}
+}
+
+func TestCheckReadyPods(t *testing.T) {
+	rc := &record.Object{Kind: "ReplicationController", Name: "redis"}
+	live := `{"kind": "ReplicationController", "metadata": {"namespace": "default"}, "spec": {"replicas": 2, "selector": {"app": "redis"}}, "status": {"replicas": 2}}`
+	pod := func(app string, ready bool) string {
+		status := "False"
+		if ready {
+			status = "True"
+		}
+		return `{"metadata": {"labels": {"app": "` + app + `"}}, "status": {"phase": "Running", "conditions": [{"type": "Ready", "status": "` + status + `"}]}}`
+	}
+
+	tests := []struct {
+		pods  []string
+		ready bool
+	}{
+		{[]string{pod("redis", true), pod("redis", false), pod("web", true)}, false},
+		{[]string{pod("redis", true), pod("redis", true)}, true},
+	}
+	for _, tt := range tests {
+		client := getRunner{
+			TestRunner: TestRunner{out: []byte(`{"kind": "List", "items": [` + strings.Join(tt.pods, ",") + `]}`)},
+			get:        func([]byte) ([]byte, error) { return []byte(live), nil },
+		}
+		ready, msg, err := checkReady(rc, "default", client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ready != tt.ready {
+			t.Errorf("Expected ready=%t, got %t (%s)", tt.ready, ready, msg)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package chart
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Unpack extracts a gzipped tar archive of a chart into dest.
+//
+// The archive must contain a single top-level directory, which holds the
+// chart. Its contents are written to dest, which is created if necessary.
+func Unpack(r io.Reader, dest string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	found := false
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		name := path.Clean(filepath.ToSlash(h.Name))
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("illegal path in chart archive: %s", h.Name)
+		}
+		parts := strings.SplitN(name, "/", 2)
+		if len(parts) < 2 {
+			continue
+		}
+		rel := filepath.FromSlash(parts[1])
+		found = true
+
+		p := filepath.Join(dest, rel)
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(p, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(h.Mode)&0777)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("chart archive is empty")
+	}
+	return nil
+}
+
+// IgnoreFile is the name of the file in a chart that lists the files to leave
+// out of its archive.
+const IgnoreFile = ".helmcignore"
+
+// LoadIgnore reads the patterns in a chart's ignore file.
+//
+// Each line holds a pattern, in the syntax of filepath.Match. Blank lines and
+// lines starting with '#' are skipped. If the chart has no ignore file, no
+// patterns are returned.
+func LoadIgnore(dir string) ([]string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, IgnoreFile))
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	patterns := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := filepath.Match(line, ""); err != nil {
+			return nil, fmt.Errorf("bad pattern %q in %s: %s", line, IgnoreFile, err)
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, nil
+}
+
+// ignored reports whether a path in a chart matches one of the patterns.
+//
+// A pattern matches either the whole path, relative to the chart, or its last
+// element. A pattern that ends in '/' only matches directories.
+func ignored(rel string, isDir bool, patterns []string) bool {
+	for _, p := range patterns {
+		if strings.HasSuffix(p, "/") {
+			if !isDir {
+				continue
+			}
+			p = strings.TrimSuffix(p, "/")
+		}
+		if ok, _ := filepath.Match(p, rel); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(p, filepath.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Pack writes a gzipped tar archive of the chart in dir to w.
+//
+// The files are stored in a top-level directory called name, as Unpack
+// expects. Files and directories that match the patterns are left out, as
+// are .git directories.
+func Pack(dir, name string, patterns []string, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if fi.Name() == ".git" || ignored(rel, fi.IsDir(), patterns) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !fi.IsDir() && !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		h, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		h.Name = path.Join(name, filepath.ToSlash(rel))
+		if fi.IsDir() {
+			h.Name += "/"
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,92 @@
+package chart
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/helm/helm-classic/test"
+)
+
+func TestUnpack(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "helmc-unpack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	data, _ := test.ChartArchive("redis", map[string]string{
+		"Chart.yaml":         "name: redis\nversion: 1.0.0\n",
+		"manifests/pod.yaml": "kind: Pod\n",
+	})
+	dest := filepath.Join(tmp, "redis")
+	if err := Unpack(bytes.NewReader(data), dest); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := LoadChartfile(filepath.Join(dest, "Chart.yaml"))
+	if err != nil || cf.Version != "1.0.0" {
+		t.Errorf("Expected Chart.yaml to be unpacked, got %+v (%v)", cf, err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "manifests", "pod.yaml")); err != nil {
+		t.Errorf("Expected the manifests to be unpacked: %s", err)
+	}
+
+	data, _ = test.ChartArchive("..", map[string]string{"escape": "boo"})
+	if err := Unpack(bytes.NewReader(data), filepath.Join(tmp, "evil")); err == nil {
+		t.Error("Expected an error for a path outside the chart")
+	}
+}
+
+func TestPack(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "helmc-pack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	src := filepath.Join(tmp, "src")
+	files := map[string]string{
+		"Chart.yaml":          "name: redis\nversion: 1.0.0\n",
+		"manifests/pod.yaml":  "kind: Pod\n",
+		"manifests/pod.yaml~": "kind: Pod\n",
+		"notes/todo.txt":      "ship it\n",
+		"docs/notes/keep.txt": "keep\n",
+		".git/config":         "[core]\n",
+		IgnoreFile:            "# editor backups\n*~\n\nnotes/\n",
+	}
+	for p, data := range files {
+		os.MkdirAll(filepath.Dir(filepath.Join(src, p)), 0755)
+		ioutil.WriteFile(filepath.Join(src, p), []byte(data), 0644)
+	}
+
+	patterns, err := LoadIgnore(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Pack(src, "redis", patterns, &buf); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(tmp, "dest")
+	if err := Unpack(&buf, dest); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{"Chart.yaml", "manifests/pod.yaml", IgnoreFile} {
+		if _, err := os.Stat(filepath.Join(dest, p)); err != nil {
+			t.Errorf("Expected %s in the archive: %s", p, err)
+		}
+	}
+	for _, p := range []string{"manifests/pod.yaml~", "notes", "docs/notes", ".git"} {
+		if _, err := os.Stat(filepath.Join(dest, p)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be left out, got %v", p, err)
+		}
+	}
+
+	ioutil.WriteFile(filepath.Join(src, IgnoreFile), []byte("[\n"), 0644)
+	if _, err := LoadIgnore(src); err == nil {
+		t.Error("Expected an error for a bad pattern")
+	}
+}
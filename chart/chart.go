@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/helm/helm-classic/manifest"
 )
@@ -29,6 +31,11 @@ type Chart struct {
 
 	// Manifests is an array of Manifest objects.
 	Manifests []*manifest.Manifest
+
+	// Hooks maps a hook event to the Job manifests that run for it.
+	//
+	// Hook Jobs are annotated with AnnHook, and are not included in Kind.
+	Hooks map[string][]*manifest.Manifest
 }
 
 // Load loads an entire chart.
@@ -52,6 +59,7 @@ func Load(chart string) (*Chart, error) {
 	c := &Chart{
 		Chartfile: cf,
 		Kind:      map[string][]*manifest.Manifest{},
+		Hooks:     map[string][]*manifest.Manifest{},
 	}
 
 	ms, err := manifest.ParseDir(chart)
@@ -76,16 +84,87 @@ const (
 
 	// AnnChartName is the annotation key for a chart name.
 	AnnChartName = "chart.helm.sh/name"
+
+	// AnnRelease is the annotation key for the name of the release that an
+	// object was installed by, which is the name of the chart in the workspace.
+	AnnRelease = "chart.helm.sh/release"
+
+	// AnnHook is the annotation key that marks a Job as a hook. Its value is a
+	// comma-separated list of hook events.
+	AnnHook = "chart.helm.sh/hook"
+
+	// AnnWeight is the annotation key for the weight of a manifest. Its value
+	// is an integer. Manifests with a lower weight are installed first, and
+	// uninstalled last.
+	AnnWeight = "chart.helm.sh/weight"
 )
 
+// Hook events. Commands for each event are declared in Chart.yaml, and Jobs
+// can be attached to them with the AnnHook annotation.
+const (
+	HookPreInstall  = "preinstall"
+	HookPostInstall = "postinstall"
+	HookPreDelete   = "predelete"
+	HookPostDelete  = "postdelete"
+)
+
+// HookEvents lists every hook event.
+var HookEvents = []string{HookPreInstall, HookPostInstall, HookPreDelete, HookPostDelete}
+
 // attachManifests sorts manifests into their respective categories, adding to the Chart.
 func (c *Chart) attachManifests(manifests []*manifest.Manifest) {
 	c.Manifests = manifests
 	for _, m := range manifests {
+		if events := hookEvents(m); len(events) > 0 {
+			for _, e := range events {
+				c.Hooks[e] = append(c.Hooks[e], m)
+			}
+			continue
+		}
 		c.Kind[m.Kind] = append(c.Kind[m.Kind], m)
 	}
 }
 
+// hookEvents returns the hook events that a Job manifest is annotated with.
+//
+// Only Jobs can be hooks. Other kinds are never treated as hooks.
+func hookEvents(m *manifest.Manifest) []string {
+	if m.Kind != "Job" {
+		return nil
+	}
+	md, err := m.VersionedObject.Meta()
+	if err != nil || md.Annotations[AnnHook] == "" {
+		return nil
+	}
+	events := []string{}
+	for _, e := range strings.Split(md.Annotations[AnnHook], ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Weight returns the weight that a manifest is annotated with, or 0 if it has
+// none.
+//
+// An error is returned if the weight is not an integer.
+func Weight(m *manifest.Manifest) (int, error) {
+	md, err := m.VersionedObject.Meta()
+	if err != nil {
+		return 0, err
+	}
+	w, ok := md.Annotations[AnnWeight]
+	if !ok {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(w))
+	if err != nil {
+		return 0, fmt.Errorf("%s %s: weight %q is not an integer", m.Kind, m.Name, w)
+	}
+	return n, nil
+}
+
 // UnknownKinds returns a list of kinds that this chart contains, but which were not in the passed in array.
 //
 // A Chart will store all kinds that are given to it. This makes it possible to get a list of kinds that are not
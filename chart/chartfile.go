@@ -23,6 +23,29 @@ type Chartfile struct {
 	Details      string            `yaml:"details,omitempty"`
 	Dependencies []*Dependency     `yaml:"dependencies,omitempty"`
 	PreInstall   map[string]string `yaml:"preinstall,omitempty"`
+	PostInstall  map[string]string `yaml:"postinstall,omitempty"`
+	PreDelete    map[string]string `yaml:"predelete,omitempty"`
+	PostDelete   map[string]string `yaml:"postdelete,omitempty"`
+	// InstallOrder and UninstallOrder override the order in which kinds are
+	// installed and uninstalled. Kinds that are not listed come after the
+	// listed kinds, in the default order.
+	InstallOrder   []string `yaml:"installOrder,omitempty"`
+	UninstallOrder []string `yaml:"uninstallOrder,omitempty"`
+}
+
+// HookCommands returns the commands declared for a hook event, by name.
+func (c *Chartfile) HookCommands(event string) map[string]string {
+	switch event {
+	case HookPreInstall:
+		return c.PreInstall
+	case HookPostInstall:
+		return c.PostInstall
+	case HookPreDelete:
+		return c.PreDelete
+	case HookPostDelete:
+		return c.PostDelete
+	}
+	return nil
 }
 
 // Dependency describes a specific dependency.
@@ -30,6 +53,10 @@ type Dependency struct {
 	Name    string `yaml:"name"`
 	Version string `yaml:"version"`
 	Repo    string `yaml:"repo,omitempty"`
+	// Commit is the commit of the chart table that a chart was fetched from.
+	// It is only recorded in the From of a chart that was fetched at a
+	// specific version, or from a lock.
+	Commit string `yaml:"commit,omitempty"`
 }
 
 // LoadChartfile loads a Chart.yaml file into a *Chart.
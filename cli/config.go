@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"github.com/codegangsta/cli"
+	"github.com/helm/helm-classic/action"
+)
+
+const configViewDescription = `Show the effective configuration, and the file each value came from.
+
+The configuration is read from $HELMC_HOME/config.yaml. If a .helmc.yaml file
+is found in the current directory or one of its parents, it is merged on top:
+its repository tables are added to the home tables, replacing any of the same
+name, and every other value it sets replaces the home value.
+
+A project file can set:
+
+	repos:
+	  default: mycharts
+	  tables:
+	    - name: mycharts
+	      repo: https://github.com/example/charts
+	namespace: dev          # used when no --namespace is given
+	kubeContext: staging    # used when no --kube-context is given
+	values: values.toml     # used by 'helmc template' when no --values is given
+	exclude:                # always skipped by the generator
+	  - tpl
+
+Commands that change the configuration, such as 'helmc repo add', only
+change $HELMC_HOME/config.yaml.`
+
+const configKeysDescription = `Keys are the ones listed by 'helmc config view':
+
+	repos.default                 the default repository table
+	repos.tables.NAME.repo        the URL of table NAME
+	repos.tables.NAME.type        'git' or 'http'
+	repos.tables.NAME.ref         the branch, tag, or commit a Git table is pinned to
+	repos.tables.NAME.verify      'true' to verify the signatures of charts from NAME
+	namespace                     the default Kubernetes namespace
+	kubeContext                   the default kubeconfig context
+	values                        the default values file for 'helmc template'
+	exclude                       a comma-separated list of paths the generator skips`
+
+const configSetDescription = `Set a value in $HELMC_HOME/config.yaml.
+
+Setting a field of a table that does not exist adds the table; run
+'helmc update' to download it. The configuration is validated before it is
+saved, and is left unchanged if it is not valid. Project files (.helmc.yaml)
+are never changed.
+
+` + configKeysDescription
+
+const configUnsetDescription = `Remove a value from $HELMC_HOME/config.yaml.
+
+Unsetting 'repos.tables.NAME' removes the whole table from the configuration.
+Its local copy is left in place; use 'helmc repo rm' to remove both.
+
+` + configKeysDescription
+
+const configValidateDescription = `Check the configuration, and any project file, for problems.
+
+Every key must be known, every table must have a name made of letters, digits,
+'-' and '_', a known type, and a valid Git or HTTP URL, and the default table
+must exist. Every problem found is listed.
+
+Configuration files carry a schema version. Files written by an older version
+of Helm Classic are migrated to the current schema the next time they are
+used, and files written by a newer version are refused. The configuration is
+always written atomically, and is only readable by its owner.`
+
+var configCmd = cli.Command{
+	Name:  "config",
+	Usage: "Show and change the Helm Classic configuration.",
+	Subcommands: []cli.Command{
+		{
+			Name:        "view",
+			Usage:       "Show the effective configuration and where each value came from.",
+			Description: configViewDescription,
+			Action: func(c *cli.Context) {
+				action.ConfigView(home(c))
+			},
+		},
+		{
+			Name:        "get",
+			Usage:       "Print the effective value of a key.",
+			Description: configKeysDescription,
+			ArgsUsage:   "[key]",
+			Action: func(c *cli.Context) {
+				minArgs(c, 1, "get")
+				action.ConfigGet(home(c), c.Args()[0])
+			},
+		},
+		{
+			Name:        "set",
+			Usage:       "Set a value in the home configuration.",
+			Description: configSetDescription,
+			ArgsUsage:   "[key] [value]",
+			Action: func(c *cli.Context) {
+				minArgs(c, 2, "set")
+				action.ConfigSet(home(c), c.Args()[0], c.Args()[1])
+			},
+		},
+		{
+			Name:        "unset",
+			Usage:       "Remove a value from the home configuration.",
+			Description: configUnsetDescription,
+			ArgsUsage:   "[key]",
+			Action: func(c *cli.Context) {
+				minArgs(c, 1, "unset")
+				action.ConfigUnset(home(c), c.Args()[0])
+			},
+		},
+		{
+			Name:        "validate",
+			Usage:       "Check the configuration for problems.",
+			Description: configValidateDescription,
+			Action: func(c *cli.Context) {
+				action.ConfigValidate(home(c))
+			},
+		},
+	},
+}
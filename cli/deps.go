@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"github.com/codegangsta/cli"
+	"github.com/helm/helm-classic/action"
+)
+
+const depsDescription = `Print the dependency tree of a chart in your workspace.
+
+Each dependency is shown with its version range, the version that satisfies
+it, and whether that chart is in your workspace, can be fetched from one of
+your repository tables, or is missing. The dependencies of each chart are
+listed beneath it.
+
+With '--reverse', list every chart in your repository tables that depends on
+the named chart instead. The chart does not need to be in your workspace.
+
+Use '--json' to print either view as JSON.`
+
+const depsUpdateDescription = `Resolve the dependencies of a chart in your workspace, and their
+dependencies in turn, against your repository tables, and pin the result in
+the chart's Chart.lock.
+
+For every chart that is needed, Chart.lock records its name, exact version,
+the table it comes from, the table's Git URL, and the commit that your copy of
+the table is at. Run 'helmc update' first to pin the latest charts.
+
+When a chart has a Chart.lock, 'helmc fetch --with-deps' and
+'helmc install --with-deps' fetch exactly the pinned charts, from the pinned
+commits, even if the tables have changed since. Commit Chart.lock with the
+chart so that everyone installs the same dependencies.`
+
+var depsCmd = cli.Command{
+	Name:        "deps",
+	Usage:       "Show or update the dependencies of a chart.",
+	Description: depsDescription,
+	ArgsUsage:   "[chart-name]",
+	Action:      deps,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "reverse, r",
+			Usage: "List the charts in the repository tables that depend on the chart.",
+		},
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print JSON.",
+		},
+	},
+	Subcommands: []cli.Command{
+		{
+			Name:        "update",
+			Usage:       "Resolve a chart's dependencies and write its Chart.lock.",
+			Description: depsUpdateDescription,
+			ArgsUsage:   "[chart-name]",
+			Action: func(c *cli.Context) {
+				minArgs(c, 1, "deps update")
+				action.UpdateDeps(c.Args()[0], home(c))
+			},
+		},
+	},
+}
+
+func deps(c *cli.Context) {
+	minArgs(c, 1, "deps")
+	if c.Bool("reverse") {
+		action.DepsReverse(c.Args()[0], home(c), c.Bool("json"))
+		return
+	}
+	action.DepsTree(c.Args()[0], home(c), c.Bool("json"))
+}
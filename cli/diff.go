@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"github.com/codegangsta/cli"
+	"github.com/helm/helm-classic/action"
+	"github.com/helm/helm-classic/kubectl"
+)
+
+const diffDescription = `Compare a chart in your workspace to the objects running in Kubernetes.
+
+For every manifest in the chart, the live object of the same kind and name is
+fetched with 'kubectl get', and the differences are printed as a unified diff.
+Objects are reported as:
+
+- added: in the chart, but not in the cluster.
+- changed: in both, but different.
+- removed: recorded in the chart's last release, no longer in the chart, but
+  still in the cluster.
+
+Fields that Kubernetes populates (status, uid, resourceVersion, and so on) and
+fields that the chart does not set are ignored. The values of Secrets are
+never printed; the diff only shows which of their keys changed.
+
+If any differences are found, 'helmc diff' exits with a non-zero status, which
+makes it suitable for use in CI.
+`
+
+var diffCmd = cli.Command{
+	Name:        "diff",
+	Usage:       "Show differences between a chart and the cluster.",
+	Description: diffDescription,
+	ArgsUsage:   "[chart-name]",
+	Action: func(c *cli.Context) {
+		minArgs(c, 1, "diff")
+		action.Diff(c.Args()[0], home(c), namespace(c), kubectl.Client)
+	},
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "namespace, n",
+			Value: "",
+			Usage: "The Kubernetes namespace. Defaults to the namespace of the chart's release.",
+		},
+	},
+}
@@ -8,9 +8,30 @@ import (
 const fetchDescription = `Copy a chart from the Chart repository to a local workspace.
 From this point, the copied chart may be safely modified to your needs.
 
+The chart may also be given as the path or HTTP URL of a chart archive built
+by 'helmc package'. If a digest file is found next to the archive, the archive
+is verified against it.
+
+To fetch an earlier version, follow the chart with '@' and a version or a
+semver constraint, as in 'helmc fetch redis@0.3.0' or 'helmc fetch redis@~0.3'.
+The history of the chart's table is searched for the highest matching version,
+and the chart is copied as it was at the newest commit with that version. The
+commit is recorded in the 'from' section of the chart's Chart.yaml.
+
 If an optional 'chart-name' is specified, the chart will be copied to a directory
 of that name. For example, 'helmc fetch nginx www' will copy the the contents of
-the 'nginx' chart into a directory named 'www' in your workspace.`
+the 'nginx' chart into a directory named 'www' in your workspace.
+
+With '--with-deps', the chart's dependencies are fetched too, and their
+dependencies in turn. Each dependency is fetched from the first repository
+table that has a chart of that name at a matching version, starting with the
+default table. Dependencies that are already in your workspace are not fetched
+again.
+
+With '--verify', the chart must be signed with 'helmc sign' by a key in your
+keyring ($HELMC_HOME/keyring), and must not have changed since it was signed.
+Unsigned or modified charts are refused. Charts from a repository table with
+'verify: true' in config.yaml are always verified.`
 
 var fetchCmd = cli.Command{
 	Name:        "fetch",
@@ -24,6 +45,14 @@ var fetchCmd = cli.Command{
 			Value: "default",
 			Usage: "The Kubernetes destination namespace.",
 		},
+		cli.BoolFlag{
+			Name:  "with-deps",
+			Usage: "Also fetch the chart's dependencies, recursively.",
+		},
+		cli.BoolFlag{
+			Name:  "verify",
+			Usage: "Refuse charts that are not signed by a key in the keyring.",
+		},
 	},
 }
 
@@ -39,5 +68,8 @@ func fetch(c *cli.Context) {
 		lname = a[1]
 	}
 
-	action.Fetch(chart, lname, home)
+	action.Fetch(chart, lname, home, &action.FetchOptions{
+		WithDeps: c.Bool("with-deps"),
+		Verify:   c.Bool("verify"),
+	})
 }
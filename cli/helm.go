@@ -2,9 +2,11 @@ package cli
 
 import (
 	"errors"
+	"os"
 
 	"github.com/codegangsta/cli"
 	"github.com/helm/helm-classic/action"
+	"github.com/helm/helm-classic/kubectl"
 	"github.com/helm/helm-classic/log"
 )
 
@@ -34,6 +36,15 @@ For more information on Helm Classic, go to http://helm.sh.
 ENVIRONMENT:
 $HELMC_HOME:     Set an alternative location for Helm files. By default, these
 				are stored in ~/.helmc
+$HELMC_KUBE_CLIENT: Set to 'api' to talk to the Kubernetes API server directly
+				instead of running kubectl.
+$HELMC_KUBECONFIG: The kubectl configuration file to use for every command.
+$HELMC_KUBE_CONTEXT: The kubeconfig context to use for every command.
+$HELMC_KUBECTL:  The path of the kubectl binary.
+$HELMC_KUBE_TRANSCRIPT: Append every call made to Kubernetes to this file, as
+				one line of JSON per call. The values of Secrets are
+				redacted, but other objects are recorded verbatim, and
+				may contain sensitive content.
 
 `
 
@@ -62,25 +73,61 @@ func Cli() *cli.App {
 			Name:  "debug",
 			Usage: "Enable verbose debugging output",
 		},
+		cli.StringFlag{
+			Name:   "kube-client",
+			Value:  "kubectl",
+			Usage:  "How to talk to Kubernetes: 'kubectl' runs kubectl, 'api' calls the API server using your kubeconfig",
+			EnvVar: "HELMC_KUBE_CLIENT",
+		},
+		cli.StringFlag{
+			Name:   "kubeconfig",
+			Usage:  "The kubectl configuration file to use",
+			EnvVar: "HELMC_KUBECONFIG",
+		},
+		cli.StringFlag{
+			Name:   "kube-context",
+			Usage:  "The kubeconfig context to use, instead of the current context",
+			EnvVar: "HELMC_KUBE_CONTEXT",
+		},
+		cli.StringFlag{
+			Name:   "kubectl",
+			Value:  "kubectl",
+			Usage:  "The path of the kubectl binary",
+			EnvVar: "HELMC_KUBECTL",
+		},
+		cli.StringFlag{
+			Name:   "kube-transcript",
+			Usage:  "Append a JSON record of every call made to Kubernetes to this file. Secret values are redacted, but it may contain other sensitive content",
+			EnvVar: "HELMC_KUBE_TRANSCRIPT",
+		},
 	}
 
 	app.Commands = []cli.Command{
+		configCmd,
 		createCmd,
+		depsCmd,
+		diffCmd,
 		doctorCmd,
 		editCmd,
 		fetchCmd,
+		historyCmd,
 		homeCmd,
 		infoCmd,
 		installCmd,
 		lintCmd,
 		listCmd,
+		packageCmd,
 		publishCmd,
 		removeCmd,
 		repositoryCmd,
+		rollbackCmd,
 		searchCmd,
+		signCmd,
+		statusCmd,
 		targetCmd,
 		uninstallCmd,
 		updateCmd,
+		upgradeCmd,
 		generateCmd,
 		tplCmd,
 	}
@@ -97,8 +144,67 @@ func Cli() *cli.App {
 
 	app.Before = func(c *cli.Context) error {
 		log.IsDebugging = c.Bool("debug")
+		setKubeClient(c.String("kube-client"), kubeOptions(c))
+		if t := c.String("kube-transcript"); t != "" {
+			recordKubeCalls(t)
+		}
 		return nil
 	}
 
 	return app
 }
+
+// setKubeClient selects the kubectl.Runner that commands use to talk to Kubernetes.
+func setKubeClient(name string, opts kubectl.Options) {
+	switch name {
+	case "kubectl", "":
+		kubectl.Client = kubectl.RealRunner{Options: opts}
+	case "api":
+		r, err := kubectl.NewAPIRunner(opts)
+		if err != nil {
+			log.Die("Could not configure the Kubernetes API client: %s", err)
+		}
+		kubectl.Client = r
+	default:
+		log.Die("Unknown Kubernetes client %q. Use 'kubectl' or 'api'.", name)
+	}
+}
+
+// recordKubeCalls wraps kubectl.Client so that every call is appended to a
+// transcript file.
+func recordKubeCalls(path string) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		log.Die("Could not open transcript: %s", err)
+	}
+	kubectl.Client = kubectl.NewRecorder(kubectl.Client, f)
+}
+
+// kubeOptions returns the kubectl options set by global flags.
+func kubeOptions(c *cli.Context) kubectl.Options {
+	return kubectl.Options{
+		Path:       c.GlobalString("kubectl"),
+		Kubeconfig: c.GlobalString("kubeconfig"),
+		Context:    kubeContext(c),
+	}
+}
+
+// kubeContext returns the --kube-context flag, or the configured context if
+// the flag is not set.
+func kubeContext(c *cli.Context) string {
+	if ctx := c.GlobalString("kube-context"); ctx != "" {
+		return ctx
+	}
+	return settings(c).KubeContext
+}
+
+// kubeClient returns the client that a command uses to talk to Kubernetes.
+//
+// If the command's --dry-run flag is set, kubectl commands are printed instead
+// of being run.
+func kubeClient(c *cli.Context) kubectl.Runner {
+	if c.Bool("dry-run") {
+		return kubectl.PrintRunner{Options: kubeOptions(c)}
+	}
+	return kubectl.Client
+}
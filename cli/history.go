@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"github.com/codegangsta/cli"
+	"github.com/helm/helm-classic/action"
+)
+
+const historyDescription = `List every recorded revision of a release.
+
+Each install, upgrade, and rollback of a release is stored as a numbered
+revision in '$HELMC_HOME/releases'. Use 'helmc rollback' to return a release
+to one of these revisions.
+`
+
+var historyCmd = cli.Command{
+	Name:        "history",
+	Usage:       "Show the revision history of a release.",
+	Description: historyDescription,
+	ArgsUsage:   "[release-name]",
+	Action: func(c *cli.Context) {
+		minArgs(c, 1, "history")
+		action.History(c.Args()[0], home(c))
+	},
+}
@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"time"
+
 	"github.com/codegangsta/cli"
 	"github.com/helm/helm-classic/action"
 	"github.com/helm/helm-classic/kubectl"
@@ -11,8 +13,53 @@ will be uploaded into Kubernetes. If no chart named 'chart-name' is found in
 your workspace, Helm Classic will look for a chart with that name, install it into the
 workspace, and then immediately upload it to Kubernetes.
 
+A chart may also be given as the path or HTTP URL of a chart archive built by
+'helmc package'. The archive is unpacked into your workspace first, replacing
+any chart of the same name.
+
 When multiple charts are specified, Helm Classic will attempt to install all of them,
 following the resolution process described above.
+
+Each install is recorded as a release named after the chart in your workspace.
+Use 'helmc status' to see what was installed. The values of Secrets are not
+recorded, only their digests. With '--record-configmap', the record is also
+stored in a ConfigMap, which fails if the record is larger than 1MiB.
+
+With '--namespace', every manifest is installed into that namespace, except for
+cluster-wide kinds such as Namespaces and PersistentVolumes. If a manifest
+declares a different namespace, nothing is installed. Use '--create-namespace'
+to create the namespace first if it does not exist. The namespace is not deleted
+when the chart is uninstalled, but a failed '--atomic' install deletes it again.
+
+Objects are created after the objects in the chart that they reference. For
+example, a Deployment whose pods mount a Secret and a PersistentVolumeClaim is
+created after that Secret and claim. Use '--dry-run' to see the computed order.
+
+By default, an install that fails part way through leaves the objects it
+already created in place. With '--atomic', those objects are deleted again
+(in uninstall order, skipping 'helm-keep' manifests) and listed, along with
+any hook Job that failed and the namespace created by '--create-namespace'.
+
+With '--wait', Helm Classic waits until the Deployments, ReplicationControllers,
+DaemonSets, Pods, and Jobs in the chart are ready or complete. If they are not
+ready within '--timeout', the install fails and the state of each object that
+is not ready is printed. Combine '--wait' with '--atomic' to delete the chart's
+objects again when it does not become ready.
+
+With '--with-deps', the chart's dependencies are fetched as with
+'helmc fetch --with-deps', and installed before the chart, each after the
+charts it depends on. Dependencies that are already deployed in the
+destination namespace are skipped. A dependency cycle, or a dependency that no
+repository table can satisfy, stops the install before anything is sent to
+Kubernetes.
+
+With '--verify', the chart in your workspace must be signed by a key in your
+keyring, as with 'helmc fetch --verify'. Charts from a repository table with
+'verify: true' in config.yaml are always verified.
+
+Hooks declared in the chart's Chart.yaml run commands on this machine. If the
+chart has any, they are listed, and nothing is installed unless '--run-hooks'
+is given. Hook Jobs run in Kubernetes, and do not need '--run-hooks'.
 `
 
 var installCmd = cli.Command{
@@ -43,20 +90,68 @@ var installCmd = cli.Command{
 			Name:  "exclude,x",
 			Usage: "Files or directories to exclude from the generator (if -g is set).",
 		},
+		cli.BoolFlag{
+			Name:  "record-configmap",
+			Usage: "Also store the release record as a ConfigMap in the destination namespace.",
+		},
+		cli.BoolFlag{
+			Name:  "atomic",
+			Usage: "If any manifest fails to install, delete everything this install created.",
+		},
+		cli.BoolFlag{
+			Name:  "create-namespace",
+			Usage: "Create the destination namespace if it does not exist.",
+		},
+		cli.BoolFlag{
+			Name:  "wait",
+			Usage: "Wait until the chart's workloads are ready before finishing.",
+		},
+		cli.DurationFlag{
+			Name:  "timeout",
+			Value: 5 * time.Minute,
+			Usage: "How long to wait for workloads to become ready (if --wait is set).",
+		},
+		cli.BoolFlag{
+			Name:  "with-deps",
+			Usage: "Fetch and install the chart's dependencies first.",
+		},
+		cli.BoolFlag{
+			Name:  "verify",
+			Usage: "Refuse charts that are not signed by a key in the keyring.",
+		},
+		cli.BoolFlag{
+			Name:  "run-hooks",
+			Usage: "Allow the chart's hooks to run commands on this machine.",
+		},
 	},
 }
 
 func install(c *cli.Context) {
 	minArgs(c, 1, "install")
 	h := home(c)
-	force := c.Bool("force")
+	opts := &action.InstallOptions{
+		Namespace: namespace(c),
+		Force:     c.Bool("force"),
+		Generate:  c.Bool("generate"),
+		Exclude:   c.StringSlice("exclude"),
+		ConfigMap: c.Bool("record-configmap"),
+		Atomic:    c.Bool("atomic"),
 
-	client := kubectl.Client
-	if c.Bool("dry-run") {
-		client = kubectl.PrintRunner{}
+		CreateNamespace: c.Bool("create-namespace"),
+		WithDeps:        c.Bool("with-deps"),
+		Verify:          c.Bool("verify"),
+		RunHooks:        c.Bool("run-hooks"),
 	}
+	if opts.Namespace == "" {
+		opts.DefaultNamespace = kubectl.ContextNamespace(kubeOptions(c))
+	}
+	if c.Bool("wait") {
+		opts.Wait = c.Duration("timeout")
+	}
+
+	client := kubeClient(c)
 
 	for _, chart := range c.Args() {
-		action.Install(chart, h, c.String("namespace"), force, c.Bool("generate"), c.StringSlice("exclude"), client)
+		action.Install(chart, h, opts, client)
 	}
 }
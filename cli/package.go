@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"github.com/codegangsta/cli"
+	"github.com/helm/helm-classic/action"
+)
+
+const packageDescription = `Build a versioned archive of a chart in your workspace.
+
+The chart is linted first, and is not packaged if it fails any necessary
+checks. The archive is written as NAME-VERSION.tgz, using the name and version
+in Chart.yaml, and its SHA-256 digest is written next to it as
+NAME-VERSION.tgz.sha256.
+
+Files and directories listed in the chart's .helmcignore are left out. Each
+line of .helmcignore is a pattern, such as '*.swp' or 'tmp/', that is matched
+against each path in the chart and against its last element. A pattern that
+ends in '/' only matches directories.
+
+'helmc fetch' and 'helmc install' accept the path or HTTP URL of an archive in
+place of a chart name. If a digest file is found next to the archive, the
+archive is verified against it.`
+
+var packageCmd = cli.Command{
+	Name:        "package",
+	Usage:       "Build a versioned archive of a chart.",
+	Description: packageDescription,
+	ArgsUsage:   "[chart-name]",
+	Action: func(c *cli.Context) {
+		minArgs(c, 1, "package")
+		action.Package(c.Args()[0], home(c), c.String("output-dir"))
+	},
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "output-dir, d",
+			Value: ".",
+			Usage: "The directory to write the archive to.",
+		},
+	},
+}
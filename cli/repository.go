@@ -3,21 +3,53 @@ package cli
 import (
 	"github.com/codegangsta/cli"
 	"github.com/helm/helm-classic/action"
+	"github.com/helm/helm-classic/config"
 )
 
+const repoAddDescription = `Add a Git repository of charts as a new table, and clone it.
+
+By default, a table follows the default branch of its repository, and
+'helmc update' fast-forwards it. With '--ref', the table is pinned to a
+branch, tag, or commit instead. 'helmc update' fast-forwards a pinned branch,
+and leaves a tag or commit where it is. The ref is stored in config.yaml,
+where it can be changed or removed by hand.
+
+With '--type http', the URL points to a web server instead, which serves an
+index.yaml and the chart archives that it lists. The URL may name the index
+itself, or the directory that holds it. 'helmc update' downloads the index and
+the latest version of each chart, and checks the digest of each archive. HTTP
+tables cannot be pinned with '--ref'.`
+
 var repositoryCmd = cli.Command{
 	Name:    "repository",
 	Aliases: []string{"repo"},
 	Usage:   "Work with other Chart repositories.",
 	Subcommands: []cli.Command{
 		{
-			Name:      "add",
-			Usage:     "Add a remote chart repository.",
-			ArgsUsage: "[name] [git url]",
+			Name:        "add",
+			Usage:       "Add a remote chart repository.",
+			Description: repoAddDescription,
+			ArgsUsage:   "[name] [url]",
 			Action: func(c *cli.Context) {
 				minArgs(c, 2, "add")
 				a := c.Args()
-				action.AddRepo(home(c), a[0], a[1])
+				action.AddRepo(home(c), &config.Table{
+					Name: a[0],
+					Repo: a[1],
+					Type: c.String("type"),
+					Ref:  c.String("ref"),
+				})
+			},
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "ref",
+					Usage: "Pin the repository to a branch, tag, or commit.",
+				},
+				cli.StringFlag{
+					Name:  "type",
+					Value: config.TableGit,
+					Usage: "The type of repository: 'git', or 'http' for a server with an index.yaml.",
+				},
 			},
 		},
 		{
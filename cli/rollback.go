@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"strconv"
+
+	"github.com/codegangsta/cli"
+	"github.com/helm/helm-classic/action"
+	"github.com/helm/helm-classic/log"
+)
+
+const rollbackDescription = `Return a release to the manifests of a previous revision.
+
+The manifests that were recorded for the given revision are re-applied to
+Kubernetes, and objects that did not exist in that revision are deleted
+(unless they carry the 'helm-keep' annotation). The rollback is recorded as
+a new revision.
+
+Only DEPLOYED and SUPERSEDED revisions can be rolled back to. Use
+'helmc history' to list the revisions of a release.
+`
+
+var rollbackCmd = cli.Command{
+	Name:        "rollback",
+	Usage:       "Roll a release back to a previous revision.",
+	Description: rollbackDescription,
+	ArgsUsage:   "[release-name] [revision]",
+	Action:      rollback,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Only display the underlying kubectl commands.",
+		},
+		cli.BoolFlag{
+			Name:  "record-configmap",
+			Usage: "Also store the release record as a ConfigMap in the destination namespace.",
+		},
+	},
+}
+
+func rollback(c *cli.Context) {
+	minArgs(c, 2, "rollback")
+	a := c.Args()
+
+	rev, err := strconv.Atoi(a[1])
+	if err != nil || rev < 1 {
+		log.Die("Revision must be a positive number, got %q", a[1])
+	}
+
+	client := kubeClient(c)
+
+	action.Rollback(a[0], home(c), rev, c.Bool("record-configmap"), client)
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"github.com/codegangsta/cli"
+	"github.com/helm/helm-classic/action"
+)
+
+const signDescription = `Sign a chart in your workspace.
+
+The signature covers every file in the chart, including generator scripts,
+templates, and hooks, and is written to the chart's Chart.sig. Commit it
+alongside the chart, and sign again after every change.
+
+Charts are signed with ed25519 keys. Create one with
+'helmc sign --generate-key NAME': the private key is written to
+$HELMC_HOME/keys/NAME.key, and the public key to $HELMC_HOME/keyring/NAME.pub.
+Give the public key to the people who install your charts; they add it to
+their own keyring and use 'helmc fetch --verify' or 'helmc install --verify'.
+
+If '--key' is not given, the only key in $HELMC_HOME/keys is used.`
+
+var signCmd = cli.Command{
+	Name:        "sign",
+	Usage:       "Sign a chart, or create a signing key.",
+	Description: signDescription,
+	ArgsUsage:   "[chart-name]",
+	Action:      sign,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key, k",
+			Usage: "The private key file to sign with.",
+		},
+		cli.StringFlag{
+			Name:  "generate-key",
+			Usage: "Create a signing key with this name instead of signing a chart.",
+		},
+	},
+}
+
+func sign(c *cli.Context) {
+	h := home(c)
+	if name := c.String("generate-key"); name != "" {
+		action.GenerateKey(name, h)
+		return
+	}
+	minArgs(c, 1, "sign")
+	action.Sign(c.Args()[0], h, c.String("key"))
+}
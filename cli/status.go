@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"github.com/codegangsta/cli"
+	"github.com/helm/helm-classic/action"
+	"github.com/helm/helm-classic/kubectl"
+)
+
+const statusDescription = `Print the record of an installed release.
+
+Every 'helmc install' records the chart name and version, where the chart
+came from, the target namespace, and the manifests that were uploaded. By
+default, records are read from '$HELMC_HOME/releases'.
+
+If '--namespace' is given, the record is read from the ConfigMap that
+'helmc install --record-configmap' stored in that namespace.
+`
+
+var statusCmd = cli.Command{
+	Name:        "status",
+	Usage:       "Show the status of an installed release.",
+	Description: statusDescription,
+	ArgsUsage:   "[release-name]",
+	Action: func(c *cli.Context) {
+		minArgs(c, 1, "status")
+		action.Status(c.Args()[0], home(c), namespace(c), c.String("format"), kubectl.Client)
+	},
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "namespace, n",
+			Value: "",
+			Usage: "Read the release from a ConfigMap in this Kubernetes namespace.",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Usage: "Print using a Go template",
+		},
+	},
+}
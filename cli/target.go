@@ -3,7 +3,6 @@ package cli
 import (
 	"github.com/codegangsta/cli"
 	"github.com/helm/helm-classic/action"
-	"github.com/helm/helm-classic/kubectl"
 )
 
 var targetCmd = cli.Command{
@@ -11,10 +10,7 @@ var targetCmd = cli.Command{
 	Usage:     "Displays information about cluster.",
 	ArgsUsage: "",
 	Action: func(c *cli.Context) {
-		client := kubectl.Client
-		if c.Bool("dry-run") {
-			client = kubectl.PrintRunner{}
-		}
+		client := kubeClient(c)
 		action.Target(client)
 	},
 	Flags: []cli.Flag{
@@ -21,7 +21,8 @@ substitution inside of a chart. In addition to the built-in template commands,
 library (https://github.com/Masterminds/sprig).
 
 If a values data file is provided, 'helmc template' will use that as a source
-for values. If none is specified, only default values will be used. Helm Classic uses
+for values. If none is specified, the values file set in the configuration is
+used (see 'helmc config view'), or else only default values. Helm Classic uses
 simple extension scanning to determine the file type of the values data file.
 
 - YAML: .yaml, .yml
@@ -60,7 +61,11 @@ var tplCmd = cli.Command{
 		a := c.Args()
 		force := c.Bool("force")
 		filename := a[0]
-		err := action.Template(c.String("out"), filename, c.String("values"), force)
+		values := c.String("values")
+		if values == "" {
+			values = settings(c).Values
+		}
+		err := action.Template(c.String("out"), filename, values, force)
 		if err != nil {
 			log.Die(err.Error())
 		}
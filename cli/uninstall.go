@@ -3,11 +3,26 @@ package cli
 import (
 	"github.com/codegangsta/cli"
 	"github.com/helm/helm-classic/action"
-	"github.com/helm/helm-classic/kubectl"
 )
 
 const uninstallDescription = `For each supplied 'chart-name', this will connect to Kubernetes
-and remove all of the manifests specified.
+and remove all of the objects that were installed from that chart.
+
+The objects are found by the 'chart.helm.sh/release' annotation that install
+stamps on them, so the chart does not need to be in your workspace, and changes
+to the workspace copy do not matter. Objects installed by older versions of
+Helm Classic do not carry that annotation, and are found by their
+'chart.helm.sh/name' annotation instead. Without '--namespace', the namespace
+of the release record is searched, and if there is no record, every namespace
+is. The objects that will be deleted are listed before you are asked to
+confirm.
+
+If the objects cannot be listed, the manifests of the chart in your workspace
+are deleted from '--namespace' instead.
+
+Hooks declared in the Chart.yaml of the chart in your workspace run commands
+on this machine. If it has any, they are listed, and nothing is uninstalled
+unless '--run-hooks' is given.
 
 This will not alter the charts in your workspace.
 `
@@ -20,20 +35,24 @@ var uninstallCmd = cli.Command{
 	Action: func(c *cli.Context) {
 		minArgs(c, 1, "uninstall")
 
-		client := kubectl.Client
+		client := kubeClient(c)
 		for _, chart := range c.Args() {
-			action.Uninstall(chart, home(c), c.String("namespace"), c.Bool("force"), client)
+			action.Uninstall(chart, home(c), c.String("namespace"), c.Bool("force"), c.Bool("run-hooks"), client)
 		}
 	},
 	Flags: []cli.Flag{
 		cli.StringFlag{
 			Name:  "namespace, n",
 			Value: "",
-			Usage: "The Kubernetes destination namespace.",
+			Usage: "The namespace of the release. Defaults to the namespace in its release record, or every namespace.",
 		},
 		cli.BoolFlag{
 			Name:  "force, aye-aye, y",
 			Usage: "Do not ask for confirmation.",
 		},
+		cli.BoolFlag{
+			Name:  "run-hooks",
+			Usage: "Allow the chart's hooks to run commands on this machine.",
+		},
 	},
 }
@@ -3,6 +3,7 @@ package cli
 import (
 	"github.com/codegangsta/cli"
 	"github.com/helm/helm-classic/action"
+	"github.com/helm/helm-classic/config"
 )
 
 const updateDescription = `This will synchronize the local repository with the upstream GitHub project.
@@ -13,7 +14,12 @@ The first time 'helmc update' is run, the necessary directory structures are
 created and then the Git repository is pulled in full.
 
 Subsequent calls to 'helmc update' will simply synchronize the local cache
-with the remote.`
+with the remote.
+
+Repository tables are updated concurrently, up to '--workers' at a time. A
+table that cannot be updated, for example because its local copy has
+uncommitted changes, does not stop the others. The result is printed for each
+table, and 'helmc update' exits with an error if any table failed.`
 
 // updateCmd represents the CLI command for fetching the latest version of all charts from Github.
 var updateCmd = cli.Command{
@@ -26,12 +32,17 @@ var updateCmd = cli.Command{
 		if !c.Bool("no-version-check") {
 			action.CheckLatest(version)
 		}
-		action.Update(home(c))
+		action.Update(home(c), c.Int("workers"))
 	},
 	Flags: []cli.Flag{
 		cli.BoolFlag{
 			Name:  "no-version-check",
 			Usage: "Disable Helm Classic's automatic check for newer versions of itself.",
 		},
+		cli.IntFlag{
+			Name:  "workers, w",
+			Value: config.DefaultUpdateWorkers,
+			Usage: "The number of repositories to update at once.",
+		},
 	},
 }
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"github.com/codegangsta/cli"
+	"github.com/helm/helm-classic/action"
+)
+
+const upgradeDescription = `Apply the changes made to a chart in your workspace to a release
+that is already installed in Kubernetes.
+
+The chart is compared to the manifests that were recorded when the release
+was last installed or upgraded:
+
+- Objects that changed are updated with 'kubectl apply'.
+- Objects that are new to the chart are created.
+- Objects that were removed from the chart are deleted, unless they carry
+  the 'helm-keep' annotation.
+
+The release keeps its namespace. Use 'helmc status' to see what is installed.
+`
+
+var upgradeCmd = cli.Command{
+	Name:        "upgrade",
+	Usage:       "Upgrade an installed release to the chart in your workspace.",
+	Description: upgradeDescription,
+	ArgsUsage:   "[chart-name...]",
+	Action:      upgrade,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "namespace, n",
+			Value: "",
+			Usage: "The Kubernetes namespace of the release. Defaults to the namespace it was installed into.",
+		},
+		cli.BoolFlag{
+			Name:  "force, aye-aye",
+			Usage: "Perform upgrade even if dependencies are unsatisfied.",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Only display the underlying kubectl commands.",
+		},
+		cli.BoolFlag{
+			Name:  "record-configmap",
+			Usage: "Also store the release record as a ConfigMap in the destination namespace.",
+		},
+	},
+}
+
+func upgrade(c *cli.Context) {
+	minArgs(c, 1, "upgrade")
+	h := home(c)
+
+	client := kubeClient(c)
+
+	for _, chart := range c.Args() {
+		action.Upgrade(chart, h, namespace(c), c.Bool("force"), c.Bool("record-configmap"), client)
+	}
+}
@@ -2,9 +2,12 @@ package cli
 
 import (
 	"os"
+	"path/filepath"
 
 	"github.com/codegangsta/cli"
+	"github.com/helm/helm-classic/config"
 	"github.com/helm/helm-classic/log"
+	"github.com/helm/helm-classic/util"
 )
 
 // home runs the --home flag through os.ExpandEnv.
@@ -12,6 +15,26 @@ func home(c *cli.Context) string {
 	return os.ExpandEnv(c.GlobalString("home"))
 }
 
+// settings returns the effective configuration, with any project file merged
+// in. If the configuration cannot be loaded, an empty one is returned, and the
+// command reports the problem when it loads the configuration itself.
+func settings(c *cli.Context) *config.Configfile {
+	cfg, err := config.Load(filepath.Join(home(c), util.Configfile))
+	if err != nil {
+		return &config.Configfile{}
+	}
+	return cfg
+}
+
+// namespace returns the --namespace flag, or the configured namespace if the
+// flag is empty.
+func namespace(c *cli.Context) string {
+	if ns := c.String("namespace"); ns != "" {
+		return ns
+	}
+	return settings(c).Namespace
+}
+
 // minArgs checks to see if the right number of args are passed.
 //
 // If not, it prints an error and quits.
@@ -111,6 +111,32 @@ func (m *Object) AddAnnotations(ann map[string]string) error {
 	return m.addMDItem("annotations", ann)
 }
 
+// SetNamespace sets the namespace in an object's metadata, regardless of kind.
+//
+// Any namespace that the object already declares is replaced.
+func (m *Object) SetNamespace(namespace string) error {
+	var d interface{}
+	if err := m.dec(m.data, &d); err != nil {
+		return err
+	}
+	val, ok := d.(map[string]interface{})
+	if !ok {
+		return errors.New("Top level object is not a map")
+	}
+	if md, ok := val["metadata"].(map[string]interface{}); ok {
+		md["namespace"] = namespace
+	} else {
+		val["metadata"] = map[string]interface{}{"namespace": namespace}
+	}
+
+	var b bytes.Buffer
+	if err := YAML.Encode(&b).One(d); err != nil {
+		return err
+	}
+	m.data = b.Bytes()
+	return nil
+}
+
 // addMDItem adds the given key/hash combo to a generic object.
 //
 // TODO: In the future we might want to make this more flexible. If it turns
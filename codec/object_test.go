@@ -144,3 +144,30 @@ func TestAddLabels(t *testing.T) {
 		t.Errorf("Failed to decode into pod: %s", err)
 	}
 }
+
+func TestSetNamespace(t *testing.T) {
+	d, err := ioutil.ReadFile(path.Join(testdata, "pod.yaml"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	m, err := YAML.Decode(d).One()
+	if err != nil {
+		t.Errorf("Failed parse: %s", err)
+	}
+
+	if err := m.SetNamespace("planet-express"); err != nil {
+		t.Errorf("Failed to set namespace: %s", err)
+	}
+
+	md, err := m.Meta()
+	if err != nil {
+		t.Errorf("Failed to read metadata: %s", err)
+	}
+	if md.Namespace != "planet-express" {
+		t.Errorf("Expected namespace 'planet-express', got %q", md.Namespace)
+	}
+	if md.Name != "cassandra" {
+		t.Errorf("Expected name 'cassandra', got %q", md.Name)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/Masterminds/vcs"
 	"github.com/helm/helm-classic/log"
@@ -23,10 +24,38 @@ type Configfile struct {
 	// filename may contain a reference back to the file that was read into
 	// this object.
 	filename string
-
+	// project is the absolute path of the project file that was merged into
+	// this object, if any.
+	project string
+	// base is the configuration as it was before a project file was merged.
+	base *Configfile
+	// sources maps the keys of merged values to the file they came from.
+	// Keys that are not in it came from filename.
+	sources map[string]string
+	// fromVersion is the schema version that the file had when it was read.
+	fromVersion int
+	// unknown lists the keys that were read but are not in the schema. They
+	// are reported by Validate, and Save refuses to drop them.
+	unknown []string
+
+	// Version is the schema version of the file. See SchemaVersion.
+	Version int `yaml:"version"`
 	// Repos points to the repository configuration
 	Repos     *Repos     `yaml:"repos"`
-	Workspace *Workspace `yaml:"workspace"`
+	Workspace *Workspace `yaml:"-"`
+
+	// Namespace is the Kubernetes namespace that commands use when no
+	// --namespace is given.
+	Namespace string `yaml:"namespace,omitempty"`
+	// KubeContext is the kubeconfig context that is used when no
+	// --kube-context is given.
+	KubeContext string `yaml:"kubeContext,omitempty"`
+	// Values is the values file that 'helmc template' uses when no --values
+	// is given.
+	Values string `yaml:"values,omitempty"`
+	// Exclude lists files and directories, relative to a chart, that the
+	// generator always skips.
+	Exclude []string `yaml:"exclude,omitempty"`
 }
 
 // Repos describes a collection of repository (table) mappings.
@@ -48,16 +77,59 @@ type Workspace struct {
 	Dir string `yaml:"-"`
 }
 
+const (
+	// TableGit is the type of a table that is a Git repository.
+	TableGit = "git"
+	// TableHTTP is the type of a table that is served over HTTP, with an
+	// index.yaml that lists its chart archives.
+	TableHTTP = "http"
+)
+
 // Table describes a single table entry.
 type Table struct {
 	// Name is the local name of the repository.
 	Name string `yaml:"name"`
-	// Repo is the remote Git URL to the repository.
+	// Repo is the remote Git URL to the repository. For an HTTP table, it is
+	// the URL of the index, or of the directory that holds it.
 	Repo string `yaml:"repo"`
+	// Type is TableGit or TableHTTP. If it is empty, the table is a Git
+	// repository.
+	Type string `yaml:"type,omitempty"`
+	// Ref pins the repository to a branch, tag, or commit. If it is empty,
+	// the remote's default branch is used. HTTP tables do not have refs.
+	Ref string `yaml:"ref,omitempty"`
+	// Verify refuses charts from this table that are not signed by a key in
+	// the keyring.
+	Verify bool `yaml:"verify,omitempty"`
+}
+
+// IsHTTP reports whether the table is served over HTTP.
+func (t *Table) IsHTTP() bool {
+	return t.Type == TableHTTP
 }
 
 // Load loads a configuration by filename.
+//
+// If a ProjectFile is found in the current directory or one of its parents,
+// it is merged on top of the configuration. See Merge.
 func Load(filename string) (*Configfile, error) {
+	cfg, err := LoadFile(filename)
+	if err != nil {
+		return cfg, err
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if p := FindProject(wd); p != "" {
+			if err := cfg.Merge(p); err != nil {
+				return cfg, fmt.Errorf("could not load %s: %s", p, err)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// LoadFile loads a configuration by filename, without merging a project file
+// into it. Use it to change the configuration in that file.
+func LoadFile(filename string) (*Configfile, error) {
 	b, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
@@ -73,6 +145,9 @@ func Load(filename string) (*Configfile, error) {
 		return cfg, err
 	}
 	cfg.filename = abs
+	if cfg.Repos == nil {
+		cfg.Repos = &Repos{}
+	}
 	if cfg.Repos.Dir == "" {
 		cfg.Repos.Dir = filepath.Join(filepath.Dir(abs), "cache")
 	}
@@ -89,27 +164,46 @@ func Load(filename string) (*Configfile, error) {
 }
 
 // Parse parses a byte slice into a *Configfile.
+//
+// A document with an older schema version is migrated to SchemaVersion. Keys
+// that are not part of the schema are not an error, but are reported by
+// Validate.
 func Parse(data []byte) (*Configfile, error) {
 	r := &Configfile{
 		filename: "config.yaml",
 	}
 
-	if err := yaml.Unmarshal(data, r); err != nil {
+	if err := r.decode(data); err != nil {
 		return r, err
 	}
 	return r, nil
 }
 
 // Save writes the Configfile as YAML into the named file.
+//
+// Values that were merged from a project file are not written. The file is
+// written at SchemaVersion, atomically, and is only readable by its owner.
+//
+// Keys that are not part of the schema cannot be written back, so Save
+// returns an error rather than drop them. Remove them with Unset first.
 func (c *Configfile) Save(filename string) error {
 	if filename == "" {
 		filename = c.filename
 	}
-	b, err := yaml.Marshal(c)
+	h := c.home()
+	if len(h.unknown) > 0 {
+		return fmt.Errorf("not saving, because unknown keys would be lost: %s. Remove them with 'helmc config unset KEY'", strings.Join(h.unknown, ", "))
+	}
+	h.Version = SchemaVersion
+	b, err := yaml.Marshal(h)
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(filename, b, 0755)
+	if err := writeFile(filename, b); err != nil {
+		return err
+	}
+	c.fromVersion = SchemaVersion
+	return nil
 }
 
 // RepoChart takes a fully qualified name and returns a repo name and a chart name.
@@ -124,27 +218,46 @@ func (r *Repos) RepoChart(name string) (string, string) {
 	return res[0], res[1]
 }
 
-// Add adds the named remote and then fetches it.
-func (r *Repos) Add(name, repo string) error {
+// Add validates a table, adds it, and then fetches it.
+func (r *Repos) Add(t *Table) error {
 	for _, r := range r.Tables {
-		if r.Name == name {
-			return fmt.Errorf("Remote %s already exists, and is pointed to %s", name, r.Repo)
+		if r.Name == t.Name {
+			return fmt.Errorf("Remote %s already exists, and is pointed to %s", t.Name, r.Repo)
 		}
 	}
 
-	nt := &Table{
-		Name: name,
-		Repo: repo,
+	if err := t.Validate(); err != nil {
+		return err
 	}
 
-	r.Tables = append(r.Tables, nt)
-	if err := r.Update(name); err != nil {
+	r.Tables = append(r.Tables, t)
+	if err := r.Update(t.Name); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// Table returns the named table, or nil if there is none.
+func (r *Repos) Table(name string) *Table {
+	for _, t := range r.Tables {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// index returns the position of the named table, or -1 if there is none.
+func (r *Repos) index(name string) int {
+	for i, t := range r.Tables {
+		if t.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
 // Exists checks if a repo exists by name
 func (r *Repos) Exists(name string) bool {
 	for _, r := range r.Tables {
@@ -157,17 +270,12 @@ func (r *Repos) Exists(name string) bool {
 
 // Update performs an update of the local copy.
 //
-// This does a Git fast-forward pull from the remote repo.
+// This does a Git fast-forward pull from the remote repo, and then checks out
+// the table's ref, if it has one. For an HTTP table, the index and the latest
+// archive of each chart are downloaded instead.
 func (r *Repos) Update(name string) error {
-	for _, t := range r.Tables {
-		if t.Name == name {
-			rpath := filepath.Join(r.Dir, name)
-			g, err := ensureRepo(t.Repo, rpath)
-			if err != nil {
-				return err
-			}
-			return g.Update()
-		}
+	if t := r.Table(name); t != nil {
+		return r.update(t).Err
 	}
 	return ErrNotFound
 }
@@ -195,37 +303,132 @@ func ensureRepo(repo, dir string) (*vcs.GitRepo, error) {
 	return git, nil
 }
 
-// UpdateAll does a git fast-forward pull from each remote repo.
-func (r *Repos) UpdateAll() error {
-	for _, table := range r.Tables {
-		log.Info("Checking repository %s", table.Name)
-		rpath := filepath.Join(r.Dir, table.Name)
-		g, err := ensureRepo(table.Repo, rpath)
-		if err != nil {
-			return err
-		}
-
-		if g.IsDirty() {
-			return fmt.Errorf("Repository '%s' is dirty.  Commit changes before updating", table.Name)
-		}
+// ErrDirty indicates that a local copy of a Git table has uncommitted changes.
+var ErrDirty = errors.New("Repository is dirty. Commit changes before updating")
+
+// DefaultUpdateWorkers is the number of tables that are updated at once when
+// no worker count is given.
+const DefaultUpdateWorkers = 4
+
+const (
+	// StatusUpdated indicates that a table changed.
+	StatusUpdated = "updated"
+	// StatusCurrent indicates that a table was already up-to-date.
+	StatusCurrent = "current"
+	// StatusDirty indicates that a table was not updated because its local
+	// copy has uncommitted changes.
+	StatusDirty = "dirty"
+	// StatusFailed indicates that a table could not be updated.
+	StatusFailed = "failed"
+)
 
-		initialVersion, err := g.Version()
-		if err != nil {
-			return fmt.Errorf("Could not get current sha of repository '%s'.", table.Name)
-		}
+// UpdateResult is the outcome of updating a single table.
+type UpdateResult struct {
+	// Table is the name of the table.
+	Table string
+	// Status is one of StatusUpdated, StatusCurrent, StatusDirty, or
+	// StatusFailed.
+	Status string
+	// Diff lists the charts that changed, in the format of
+	// 'git diff-tree --name-status'.
+	Diff string
+	// Err is the reason a table is dirty or failed.
+	Err error
+}
 
-		if err := g.Update(); err != nil {
-			return err
-		}
-		diff, err := repoChartDiff(rpath, initialVersion)
-		if err != nil {
-			return err
+// UpdateAll updates every table, and prints a summary of each.
+//
+// Up to workers tables are updated at once; if workers is less than one,
+// DefaultUpdateWorkers is used. A table that cannot be updated does not stop
+// the others. If any table could not be updated, an error naming those tables
+// is returned after all of them were tried.
+func (r *Repos) UpdateAll(workers int) error {
+	failed := []string{}
+	for _, res := range r.UpdateTables(workers) {
+		printSummary(res)
+		if res.Err != nil {
+			failed = append(failed, res.Table)
 		}
-		printSummary(diff)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d repositories could not be updated: %s", len(failed), len(r.Tables), strings.Join(failed, ", "))
 	}
 	return nil
 }
 
+// UpdateTables updates every table, up to workers at once, and returns the
+// result for each table in the order of r.Tables. See UpdateAll.
+func (r *Repos) UpdateTables(workers int) []*UpdateResult {
+	if workers < 1 {
+		workers = DefaultUpdateWorkers
+	}
+	results := make([]*UpdateResult, len(r.Tables))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results[j] = r.update(r.Tables[j])
+			}
+		}()
+	}
+	for i := range r.Tables {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// update updates a single table.
+//
+// This does a Git fast-forward pull from the remote repo, and then checks out
+// the table's ref, if it has one. For an HTTP table, the index and the latest
+// archive of each chart are downloaded instead.
+func (r *Repos) update(t *Table) *UpdateResult {
+	res := &UpdateResult{Table: t.Name}
+	rpath := filepath.Join(r.Dir, t.Name)
+	if t.IsHTTP() {
+		res.Diff, res.Err = updateHTTP(t, rpath)
+	} else {
+		res.Diff, res.Err = updateGit(t, rpath)
+	}
+
+	switch {
+	case res.Err == ErrDirty:
+		res.Status = StatusDirty
+	case res.Err != nil:
+		res.Status = StatusFailed
+	case res.Diff == "":
+		res.Status = StatusCurrent
+	default:
+		res.Status = StatusUpdated
+	}
+	return res
+}
+
+// updateGit updates a Git table, and returns the charts that changed in the
+// format of repoChartDiff.
+func updateGit(t *Table, rpath string) (string, error) {
+	g, err := ensureRepo(t.Repo, rpath)
+	if err != nil {
+		return "", err
+	}
+	if g.IsDirty() {
+		return "", ErrDirty
+	}
+	initialVersion, err := g.Version()
+	if err != nil {
+		return "", fmt.Errorf("Could not get current sha of repository '%s'.", t.Name)
+	}
+	if err := updateTable(g, t); err != nil {
+		return "", err
+	}
+	return repoChartDiff(rpath, initialVersion)
+}
+
 func repoChartDiff(rpath, initialVersion string) (string, error) {
 	// build git diff-tree command
 	cmd := exec.Command("git", "-C", rpath, "diff-tree", "--name-status", fmt.Sprintf("%s..HEAD", initialVersion))
@@ -253,17 +456,23 @@ func (s repoSummary) add(status, chart string) {
 	s[status] = append(s[status], chart)
 }
 
-// printSummary prints a diff of charts after upate
-func printSummary(diff string) {
-	if len(diff) == 0 {
-		log.Msg("Already up-to-date.")
+// printSummary prints the result of updating a table, with a diff of its
+// charts.
+func printSummary(res *UpdateResult) {
+	switch res.Status {
+	case StatusDirty, StatusFailed:
+		log.Err("Repository %s was not updated: %s", res.Table, res.Err)
+		return
+	case StatusCurrent:
+		log.Info("Repository %s is already up-to-date.", res.Table)
 		return
 	}
+	log.Info("Updated repository %s", res.Table)
 
 	s := make(repoSummary)
 
 	// parse git diff-tree
-	for _, line := range strings.Split(diff, "\n") {
+	for _, line := range strings.Split(res.Diff, "\n") {
 		kv := strings.Split(line, "\t")
 		st, chart := kv[0], kv[1]
 
@@ -52,6 +52,9 @@ func TestLoadConfigfile(t *testing.T) {
 	if len(cfg.Repos.Tables) != 3 {
 		t.Errorf("Expected 3 remotes.")
 	}
+	if !cfg.Migrated() {
+		t.Errorf("Expected an unversioned file to be migrated.")
+	}
 }
 
 func TestSave(t *testing.T) {
@@ -90,11 +93,12 @@ M	mysql
 M	owncloud`
 
 	expected := []string{
+		"Updated repository charts",
 		"Updated 3 charts\ncassandra                    mysql                        owncloud",
 		"Added 1 charts\njenkins",
 	}
 
-	printSummary(diff)
+	printSummary(&UpdateResult{Table: "charts", Status: StatusUpdated, Diff: diff})
 	actual := b.String()
 
 	for _, exp := range expected {
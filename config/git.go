@@ -0,0 +1,185 @@
+package config
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/Masterminds/vcs"
+	"github.com/helm/helm-classic/chart"
+	"gopkg.in/yaml.v2"
+)
+
+// Commit returns the SHA of the commit that the local copy of a table is at.
+//
+// HTTP tables have no commits, so an empty string is returned for them.
+func (r *Repos) Commit(name string) (string, error) {
+	t := r.Table(name)
+	if t == nil {
+		return "", ErrNotFound
+	} else if t.IsHTTP() {
+		return "", nil
+	}
+	out, err := exec.Command("git", "-C", filepath.Join(r.Dir, name), "rev-parse", "HEAD").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ExportChart writes the files of a chart, as they were at the given commit of
+// a table, into dest.
+//
+// The commit must be in the history of the local copy of the table.
+func (r *Repos) ExportChart(name, commit, chart, dest string) error {
+	if !r.Exists(name) {
+		return ErrNotFound
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "-C", filepath.Join(r.Dir, name), "archive", "--format=tar", commit, chart)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("could not read %s at commit %s: %s", chart, commit, strings.TrimSpace(stderr.String()))
+	}
+
+	prefix := chart + "/"
+	found := false
+	tr := tar.NewReader(bytes.NewReader(out))
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(h.Name, prefix) {
+			continue
+		}
+		found = true
+		p := filepath.Join(dest, filepath.FromSlash(strings.TrimPrefix(h.Name, prefix)))
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(p, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(h.Mode)&0777)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("no chart %s at commit %s", chart, commit)
+	}
+	return nil
+}
+
+// FindVersion searches the history of a table for a version of a chart that
+// matches a semver constraint, such as "0.3.0" or "~0.3".
+//
+// The highest matching version is chosen, and the SHA of the newest commit
+// whose Chart.yaml has that version is returned with it. Only the history of
+// the local copy of the table is searched. HTTP tables have no history.
+func (r *Repos) FindVersion(name, chartName, constraint string) (string, string, error) {
+	t := r.Table(name)
+	if t == nil {
+		return "", "", ErrNotFound
+	} else if t.IsHTTP() {
+		return "", "", fmt.Errorf("%s is an HTTP table, which has no history", name)
+	}
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid version %q: %s", constraint, err)
+	}
+
+	dir := filepath.Join(r.Dir, name)
+	cfpath := chartName + "/Chart.yaml"
+	out, err := exec.Command("git", "-C", dir, "log", "--format=%H", "--", cfpath).CombinedOutput()
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	var commit string
+	var best *semver.Version
+	for _, sha := range strings.Fields(string(out)) {
+		data, err := exec.Command("git", "-C", dir, "show", sha+":"+cfpath).Output()
+		if err != nil {
+			// The chart was removed in this commit.
+			continue
+		}
+		cf := &chart.Chartfile{}
+		if err := yaml.Unmarshal(data, cf); err != nil {
+			continue
+		}
+		v, err := semver.NewVersion(cf.Version)
+		if err != nil || !c.Check(v) {
+			continue
+		}
+		if best == nil || v.GreaterThan(best) {
+			commit, best = sha, v
+		}
+	}
+	if best == nil {
+		return "", "", fmt.Errorf("no version of %s in %s matches %s", chartName, name, constraint)
+	}
+	return commit, best.String(), nil
+}
+
+// updateTable fetches the latest changes to a table and checks out its ref.
+//
+// A branch is fast-forwarded to the remote branch. A tag or commit is resolved
+// to a commit, which is checked out as a detached HEAD. A table without a ref follows the branch it is on,
+// or returns to the remote's default branch if it was pinned before.
+func updateTable(g *vcs.GitRepo, t *Table) error {
+	if err := g.Update(); err != nil {
+		return err
+	}
+
+	ref := t.Ref
+	if ref == "" {
+		if _, err := g.RunFromDir("git", "symbolic-ref", "-q", "HEAD"); err == nil {
+			return nil
+		}
+		out, err := g.RunFromDir("git", "symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+		if err != nil {
+			return fmt.Errorf("could not find the default branch of %s: %s", t.Name, strings.TrimSpace(string(out)))
+		}
+		ref = strings.TrimPrefix(strings.TrimSpace(string(out)), "origin/")
+	}
+
+	if !validRef(ref) {
+		return fmt.Errorf("%q is not a branch, tag, or commit name of %s", ref, t.Name)
+	}
+
+	if _, err := g.RunFromDir("git", "rev-parse", "--verify", "-q", "refs/remotes/origin/"+ref); err == nil {
+		if out, err := g.RunFromDir("git", "checkout", "-q", "-B", ref, "--track", "origin/"+ref); err != nil {
+			return fmt.Errorf("could not check out branch %s of %s: %s", ref, t.Name, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	out, err := g.RunFromDir("git", "rev-parse", "--verify", "-q", ref+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("%s has no branch, tag, or commit %s", t.Name, ref)
+	}
+	commit := strings.TrimSpace(string(out))
+	if out, err := g.RunFromDir("git", "checkout", "-q", "--detach", commit); err != nil {
+		return fmt.Errorf("could not check out %s of %s: %s", ref, t.Name, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
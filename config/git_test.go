@@ -0,0 +1,235 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func git(t *testing.T, dir string, args ...string) string {
+	args = append([]string{"-C", dir, "-c", "user.name=helmc", "-c", "user.email=helmc@example.com"}, args...)
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %s %s", args, err, out)
+	}
+	return string(out)
+}
+
+func TestExportChart(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "helmc-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	table := filepath.Join(tmp, "cache", "charts")
+	os.MkdirAll(filepath.Join(table, "redis", "manifests"), 0755)
+	git(t, table, "init", "-q")
+	ioutil.WriteFile(filepath.Join(table, "redis", "Chart.yaml"), []byte("name: redis\nversion: 1.0.0\n"), 0644)
+	ioutil.WriteFile(filepath.Join(table, "redis", "manifests", "pod.yaml"), []byte("kind: Pod\n"), 0644)
+	git(t, table, "add", ".")
+	git(t, table, "commit", "-q", "-m", "Add redis")
+
+	r := &Repos{Dir: filepath.Join(tmp, "cache"), Tables: []*Table{{Name: "charts", Repo: "https://example.com/charts.git"}}}
+	first, err := r.Commit("charts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 40 {
+		t.Errorf("Expected a SHA, got %q", first)
+	}
+
+	ioutil.WriteFile(filepath.Join(table, "redis", "Chart.yaml"), []byte("name: redis\nversion: 2.0.0\n"), 0644)
+	git(t, table, "commit", "-q", "-a", "-m", "Bump redis")
+
+	dest := filepath.Join(tmp, "redis")
+	if err := r.ExportChart("charts", first, "redis", dest); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dest, "Chart.yaml"))
+	if err != nil || string(data) != "name: redis\nversion: 1.0.0\n" {
+		t.Errorf("Expected the first version of Chart.yaml, got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "manifests", "pod.yaml")); err != nil {
+		t.Errorf("Expected the manifests to be exported: %s", err)
+	}
+
+	if err := r.ExportChart("charts", first, "nginx", dest); err == nil {
+		t.Error("Expected an error for a chart that is not in the table")
+	}
+	if _, err := r.Commit("nope"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestUpdateRef(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "helmc-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	origin := filepath.Join(tmp, "origin")
+	os.MkdirAll(origin, 0755)
+	git(t, origin, "init", "-q")
+	git(t, origin, "checkout", "-q", "-b", "main")
+	commit := func(msg string) string {
+		ioutil.WriteFile(filepath.Join(origin, "README.md"), []byte(msg), 0644)
+		git(t, origin, "add", ".")
+		git(t, origin, "commit", "-q", "-m", msg)
+		return strings.TrimSpace(git(t, origin, "rev-parse", "HEAD"))
+	}
+	v1 := commit("one")
+	git(t, origin, "tag", "v1")
+	git(t, origin, "checkout", "-q", "-b", "release")
+	rel := commit("two")
+	git(t, origin, "checkout", "-q", "main")
+	tip := commit("three")
+
+	r := &Repos{Dir: filepath.Join(tmp, "cache")}
+	if err := r.Add(&Table{Name: "charts", Repo: origin, Ref: "v1"}); err != nil {
+		t.Fatal(err)
+	}
+	expect := func(sha string) {
+		if head, err := r.Commit("charts"); err != nil || head != sha {
+			t.Errorf("Expected %s to be checked out, got %s (%v)", sha, head, err)
+		}
+	}
+	expect(v1)
+
+	r.Tables[0].Ref = "release"
+	if err := r.Update("charts"); err != nil {
+		t.Fatal(err)
+	}
+	expect(rel)
+
+	git(t, origin, "checkout", "-q", "release")
+	rel = commit("four")
+	if err := r.UpdateAll(0); err != nil {
+		t.Fatal(err)
+	}
+	expect(rel)
+
+	r.Tables[0].Ref = v1
+	if err := r.Update("charts"); err != nil {
+		t.Fatal(err)
+	}
+	expect(v1)
+
+	r.Tables[0].Ref = ""
+	if err := r.Update("charts"); err != nil {
+		t.Fatal(err)
+	}
+	expect(tip)
+
+	r.Tables[0].Ref = "nope"
+	if err := r.Update("charts"); err == nil {
+		t.Error("Expected an error for an unknown ref")
+	}
+
+	r.Tables[0].Ref = "--orphan=x"
+	if err := r.Update("charts"); err == nil {
+		t.Error("Expected an error for a ref that looks like an option")
+	}
+}
+
+func TestUpdateAllResults(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "helmc-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	origin := filepath.Join(tmp, "origin")
+	os.MkdirAll(filepath.Join(origin, "redis"), 0755)
+	git(t, origin, "init", "-q")
+	commit := func(msg string) {
+		ioutil.WriteFile(filepath.Join(origin, "redis", "Chart.yaml"), []byte("name: redis\nversion: "+msg+"\n"), 0644)
+		git(t, origin, "add", ".")
+		git(t, origin, "commit", "-q", "-m", msg)
+	}
+	commit("1.0.0")
+
+	r := &Repos{Dir: filepath.Join(tmp, "cache")}
+	for _, name := range []string{"current", "updated", "dirty"} {
+		if err := r.Add(&Table{Name: name, Repo: origin}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Tables = append(r.Tables, &Table{Name: "missing", Repo: filepath.Join(tmp, "nope")})
+	commit("1.1.0")
+	git(t, filepath.Join(r.Dir, "current"), "pull", "-q")
+	ioutil.WriteFile(filepath.Join(r.Dir, "dirty", "redis", "Chart.yaml"), []byte("name: redis\n"), 0644)
+
+	results := r.UpdateTables(2)
+	expect := map[string]string{
+		"current": StatusCurrent,
+		"updated": StatusUpdated,
+		"dirty":   StatusDirty,
+		"missing": StatusFailed,
+	}
+	for i, res := range results {
+		if res.Table != r.Tables[i].Name {
+			t.Errorf("Expected result %d to be for %s, got %s", i, r.Tables[i].Name, res.Table)
+		}
+		if res.Status != expect[res.Table] {
+			t.Errorf("Expected %s to be %s, got %s (%v)", res.Table, expect[res.Table], res.Status, res.Err)
+		}
+	}
+	if results[1].Diff != "M\tredis" {
+		t.Errorf("Expected redis to be modified, got %q", results[1].Diff)
+	}
+
+	err = r.UpdateAll(0)
+	if err == nil || !strings.Contains(err.Error(), "2 of 4 repositories could not be updated: dirty, missing") {
+		t.Errorf("Expected dirty and missing to fail, got %v", err)
+	}
+}
+
+func TestFindVersion(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "helmc-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	table := filepath.Join(tmp, "cache", "charts")
+	os.MkdirAll(filepath.Join(table, "redis"), 0755)
+	git(t, table, "init", "-q")
+	shas := []string{}
+	for _, v := range []string{"0.2.0", "0.3.0", "0.3.2", "0.3.1", "1.0.0"} {
+		ioutil.WriteFile(filepath.Join(table, "redis", "Chart.yaml"), []byte("name: redis\nversion: "+v+"\n"), 0644)
+		git(t, table, "add", ".")
+		git(t, table, "commit", "-q", "-m", v)
+		shas = append(shas, strings.TrimSpace(git(t, table, "rev-parse", "HEAD")))
+	}
+
+	r := &Repos{Dir: filepath.Join(tmp, "cache"), Tables: []*Table{{Name: "charts", Repo: "https://example.com/charts.git"}}}
+	tests := []struct {
+		constraint, version, commit string
+	}{
+		{"0.3.0", "0.3.0", shas[1]},
+		{"~0.3", "0.3.2", shas[2]},
+		{">=0.1.0", "1.0.0", shas[4]},
+	}
+	for _, tt := range tests {
+		commit, version, err := r.FindVersion("charts", "redis", tt.constraint)
+		if err != nil {
+			t.Errorf("%s: %s", tt.constraint, err)
+			continue
+		}
+		if version != tt.version || commit != tt.commit {
+			t.Errorf("%s: expected %s at %s, got %s at %s", tt.constraint, tt.version, tt.commit, version, commit)
+		}
+	}
+
+	if _, _, err := r.FindVersion("charts", "redis", "~2.0"); err == nil {
+		t.Error("Expected an error for a version that was never in the table")
+	}
+	if _, _, err := r.FindVersion("charts", "nginx", "1.0.0"); err == nil {
+		t.Error("Expected an error for a chart that was never in the table")
+	}
+}
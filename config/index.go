@@ -0,0 +1,227 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/log"
+	"gopkg.in/yaml.v2"
+)
+
+// IndexFile is the name of the file that lists the charts of an HTTP table.
+const IndexFile = "index.yaml"
+
+// packageDir is the directory in the cache of an HTTP table that holds the
+// downloaded chart archives.
+const packageDir = ".packages"
+
+// httpClient is the client used to download HTTP tables.
+var httpClient = &http.Client{Timeout: 5 * time.Minute}
+
+// Index lists the charts that an HTTP table serves.
+type Index struct {
+	// Charts lists every version of every chart in the table.
+	Charts []*IndexEntry `yaml:"charts"`
+}
+
+// IndexEntry describes one version of a chart in an Index.
+type IndexEntry struct {
+	// Name is the name of the chart.
+	Name string `yaml:"name"`
+	// Version is the version of the chart.
+	Version string `yaml:"version"`
+	// Description is a one-line description of the chart.
+	Description string `yaml:"description,omitempty"`
+	// Digest is the SHA-256 digest of the chart archive, as "sha256:HEX".
+	Digest string `yaml:"digest"`
+	// URL is the location of the chart archive, relative to the index.
+	URL string `yaml:"url"`
+}
+
+// indexURL returns the URL of the index of an HTTP table.
+//
+// The table's repo may name the index itself, or the directory that holds it.
+func indexURL(repo string) (*url.URL, error) {
+	u, err := url.Parse(repo)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%s is not an HTTP URL", repo)
+	}
+	if !strings.HasSuffix(u.Path, "/"+IndexFile) {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + IndexFile
+	}
+	return u, nil
+}
+
+// updateHTTP downloads the index of an HTTP table and the latest version of
+// each of its charts, and unpacks the charts into dir.
+//
+// Charts that are no longer in the index are removed from dir. Archives are
+// kept in dir/.packages, and only downloaded again if their digest changes.
+//
+// The charts that changed are returned in the format of repoChartDiff.
+func updateHTTP(t *Table, dir string) (string, error) {
+	iu, err := indexURL(t.Repo)
+	if err != nil {
+		return "", err
+	}
+	data, err := download(iu.String())
+	if err != nil {
+		return "", err
+	}
+	idx := &Index{}
+	if err := yaml.Unmarshal(data, idx); err != nil {
+		return "", fmt.Errorf("could not parse %s: %s", iu, err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, packageDir), 0755); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, IndexFile), data, 0644); err != nil {
+		return "", err
+	}
+
+	latest := map[string]*IndexEntry{}
+	for _, e := range idx.Charts {
+		v, err := semver.NewVersion(e.Version)
+		if err != nil {
+			log.Warn("Skipping %s %s in %s: %s", e.Name, e.Version, t.Name, err)
+			continue
+		}
+		if cur, ok := latest[e.Name]; ok {
+			if cv, _ := semver.NewVersion(cur.Version); !v.GreaterThan(cv) {
+				continue
+			}
+		}
+		latest[e.Name] = e
+	}
+
+	diff := []string{}
+	for name, e := range latest {
+		status := "M"
+		if _, err := os.Stat(filepath.Join(dir, name)); os.IsNotExist(err) {
+			status = "A"
+		}
+		changed, err := fetchPackage(iu, e, dir)
+		if err != nil {
+			return "", fmt.Errorf("could not fetch %s %s from %s: %s", name, e.Version, t.Name, err)
+		}
+		if changed {
+			diff = append(diff, status+"\t"+name)
+		}
+	}
+
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, fi := range fis {
+		if fi.IsDir() && !strings.HasPrefix(fi.Name(), ".") && latest[fi.Name()] == nil {
+			if err := os.RemoveAll(filepath.Join(dir, fi.Name())); err != nil {
+				return "", err
+			}
+			diff = append(diff, "D\t"+fi.Name())
+		}
+	}
+
+	sort.Strings(diff)
+	return strings.Join(diff, "\n"), nil
+}
+
+// fetchPackage makes sure that the chart of an index entry is unpacked in dir,
+// and reports whether it had to be unpacked.
+func fetchPackage(index *url.URL, e *IndexEntry, dir string) (bool, error) {
+	if strings.ContainsAny(e.Name, `/\`) || strings.HasPrefix(e.Name, ".") {
+		return false, fmt.Errorf("illegal chart name %q", e.Name)
+	}
+	archive := filepath.Join(dir, packageDir, fmt.Sprintf("%s-%s.tgz", e.Name, e.Version))
+	chartDir := filepath.Join(dir, e.Name)
+
+	if checkDigest(archive, e.Digest) == nil {
+		if cf, err := chart.LoadChartfile(filepath.Join(chartDir, "Chart.yaml")); err == nil && cf.Version == e.Version {
+			return false, nil
+		}
+	} else {
+		u, err := index.Parse(e.URL)
+		if err != nil {
+			return false, err
+		}
+		data, err := download(u.String())
+		if err != nil {
+			return false, err
+		}
+		if err := ioutil.WriteFile(archive, data, 0644); err != nil {
+			return false, err
+		}
+		if err := checkDigest(archive, e.Digest); err != nil {
+			os.Remove(archive)
+			return false, err
+		}
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	tmp := filepath.Join(dir, "."+e.Name+".tmp")
+	os.RemoveAll(tmp)
+	if err := chart.Unpack(f, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return false, err
+	}
+	cf, err := chart.LoadChartfile(filepath.Join(tmp, "Chart.yaml"))
+	if err != nil || cf.Name != e.Name || cf.Version != e.Version {
+		os.RemoveAll(tmp)
+		return false, fmt.Errorf("archive does not contain %s %s", e.Name, e.Version)
+	}
+	if err := os.RemoveAll(chartDir); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp, chartDir)
+}
+
+// checkDigest verifies that a file has the given "sha256:HEX" digest.
+func checkDigest(filename, digest string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if want := strings.TrimPrefix(digest, "sha256:"); !strings.EqualFold(sum, want) {
+		return fmt.Errorf("digest mismatch: expected %s, got sha256:%s", digest, sum)
+	}
+	return nil
+}
+
+// download gets the body of a URL.
+func download(u string) ([]byte, error) {
+	log.Debug("Downloading %s", u)
+	res, err := httpClient.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", u, res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/helm/helm-classic/test"
+)
+
+func TestUpdateHTTP(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "helmc-http")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	old, oldSum := test.ChartArchive("redis", map[string]string{"Chart.yaml": "name: redis\nversion: 1.0.0\n"})
+	cur, curSum := test.ChartArchive("redis", map[string]string{
+		"Chart.yaml":         "name: redis\nversion: 1.1.0\n",
+		"manifests/pod.yaml": "kind: Pod\n",
+	})
+	nginx, nginxSum := test.ChartArchive("nginx", map[string]string{"Chart.yaml": "name: nginx\nversion: 0.1.0\n"})
+	files := map[string][]byte{
+		"/charts/redis-1.0.0.tgz":          old,
+		"/charts/packages/redis-1.1.0.tgz": cur,
+		"/charts/nginx-0.1.0.tgz":          nginx,
+	}
+	index := `charts:
+- name: redis
+  version: 1.0.0
+  digest: ` + oldSum + `
+  url: redis-1.0.0.tgz
+- name: redis
+  version: 1.1.0
+  description: Redis
+  digest: ` + curSum + `
+  url: packages/redis-1.1.0.tgz
+- name: nginx
+  version: 0.1.0
+  digest: ` + nginxSum + `
+  url: nginx-0.1.0.tgz
+`
+	downloads := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/charts/index.yaml" {
+			w.Write([]byte(index))
+			return
+		}
+		if data, ok := files[r.URL.Path]; ok {
+			downloads++
+			w.Write(data)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	r := &Repos{Dir: tmp}
+	if err := r.Add(&Table{Name: "remote", Repo: ts.URL + "/charts", Type: TableHTTP}); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 2 {
+		t.Errorf("Expected the latest version of each chart to be downloaded, got %d downloads", downloads)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "remote", "redis", "manifests", "pod.yaml")); err != nil {
+		t.Errorf("Expected redis 1.1.0 to be unpacked: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "remote", IndexFile)); err != nil {
+		t.Errorf("Expected the index to be cached: %s", err)
+	}
+	if sha, err := r.Commit("remote"); sha != "" || err != nil {
+		t.Errorf("Expected no commit for an HTTP table, got %q (%v)", sha, err)
+	}
+
+	// Unchanged archives are not downloaded again, and removed charts are
+	// removed from the cache.
+	index = index[:strings.Index(index, "- name: nginx")]
+	if err := r.UpdateAll(0); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 2 {
+		t.Errorf("Expected no new downloads, got %d", downloads)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "remote", "nginx")); !os.IsNotExist(err) {
+		t.Errorf("Expected nginx to be removed, got %v", err)
+	}
+
+	// Archives must match their digest.
+	files["/charts/packages/redis-1.1.0.tgz"] = old
+	os.RemoveAll(filepath.Join(tmp, "remote", packageDir))
+	if err := r.Update("remote"); err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Errorf("Expected a digest mismatch, got %v", err)
+	}
+
+	if err := r.Add(&Table{Name: "pinned", Repo: ts.URL, Type: TableHTTP, Ref: "v1"}); err == nil {
+		t.Error("Expected an error for an HTTP table with a ref")
+	}
+	if err := r.Add(&Table{Name: "ftp", Repo: "ftp://example.com", Type: TableHTTP}); err == nil {
+		t.Error("Expected an error for a URL that is not HTTP")
+	}
+}
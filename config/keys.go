@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tableFields are the fields of a table that can be addressed by a key, as in
+// repos.tables.NAME.repo.
+var tableFields = []string{"repo", "type", "ref", "verify"}
+
+// splitKey parses a configuration key, such as those listed by Settings.
+//
+// For a table key, the table name is returned with the field, which is empty
+// if the key names the whole table.
+func splitKey(key string) (string, string, error) {
+	switch key {
+	case "repos.default", "namespace", "kubeContext", "values", "exclude":
+		return "", key, nil
+	}
+	if rest := strings.TrimPrefix(key, "repos.tables."); rest != key {
+		parts := strings.Split(rest, ".")
+		switch {
+		case len(parts) == 1 && parts[0] != "":
+			return parts[0], "", nil
+		case len(parts) == 2 && parts[0] != "" && contains(tableFields, parts[1]):
+			return parts[0], parts[1], nil
+		}
+	}
+	return "", "", fmt.Errorf("unknown key %q", key)
+}
+
+// Get returns the value of a key, as listed by Settings. A key that is not
+// set has an empty value.
+func (c *Configfile) Get(key string) (string, error) {
+	name, field, err := splitKey(key)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		switch field {
+		case "repos.default":
+			return c.Repos.Default, nil
+		case "namespace":
+			return c.Namespace, nil
+		case "kubeContext":
+			return c.KubeContext, nil
+		case "values":
+			return c.Values, nil
+		case "exclude":
+			return strings.Join(c.Exclude, ","), nil
+		}
+	}
+
+	t := c.Repos.Table(name)
+	if t == nil {
+		return "", fmt.Errorf("no table named %s", name)
+	}
+	switch field {
+	case "repo":
+		return t.Repo, nil
+	case "type":
+		return t.Type, nil
+	case "ref":
+		return t.Ref, nil
+	case "verify":
+		return strconv.FormatBool(t.Verify), nil
+	}
+	return "", fmt.Errorf("key %q names a table; use %s.repo, %s.ref, and so on", key, key, key)
+}
+
+// Set sets the value of a key. Setting a field of a table that does not exist
+// adds the table. The exclude key takes a comma-separated list.
+//
+// Set does not validate the configuration. See Validate.
+func (c *Configfile) Set(key, value string) error {
+	name, field, err := splitKey(key)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		switch field {
+		case "repos.default":
+			c.Repos.Default = value
+		case "namespace":
+			c.Namespace = value
+		case "kubeContext":
+			c.KubeContext = value
+		case "values":
+			c.Values = value
+		case "exclude":
+			c.Exclude = nil
+			for _, e := range strings.Split(value, ",") {
+				if e = strings.TrimSpace(e); e != "" {
+					c.Exclude = append(c.Exclude, e)
+				}
+			}
+		}
+		return nil
+	}
+
+	if field == "" {
+		return fmt.Errorf("key %q names a table; set %s.repo, %s.ref, and so on", key, key, key)
+	}
+	t := c.Repos.Table(name)
+	if t == nil {
+		t = &Table{Name: name}
+		c.Repos.Tables = append(c.Repos.Tables, t)
+	}
+	switch field {
+	case "repo":
+		t.Repo = value
+	case "type":
+		t.Type = value
+	case "ref":
+		t.Ref = value
+	case "verify":
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false", key)
+		}
+		t.Verify = v
+	}
+	return nil
+}
+
+// Unset clears the value of a key. Unsetting a table removes it from the
+// configuration, but leaves its local copy in place. Unsetting a key that is
+// not in the schema, as Validate reports it, removes it from the file.
+func (c *Configfile) Unset(key string) error {
+	for i, k := range c.unknown {
+		if k == key {
+			c.unknown = append(c.unknown[:i], c.unknown[i+1:]...)
+			return nil
+		}
+	}
+	name, field, err := splitKey(key)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		if field == "exclude" {
+			c.Exclude = nil
+			return nil
+		}
+		return c.Set(key, "")
+	}
+
+	i := c.Repos.index(name)
+	if i < 0 {
+		return fmt.Errorf("no table named %s", name)
+	}
+	if field == "" {
+		c.Repos.Tables = append(c.Repos.Tables[:i], c.Repos.Tables[i+1:]...)
+		return nil
+	}
+	if field == "verify" {
+		c.Repos.Tables[i].Verify = false
+		return nil
+	}
+	return c.Set(key, "")
+}
@@ -0,0 +1,312 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ProjectFile is the name of a project configuration file.
+//
+// A project file is found by walking up from the current directory, and is
+// merged on top of the configuration in $HELMC_HOME. It has the same format as
+// the home configuration.
+const ProjectFile = ".helmc.yaml"
+
+// Setting is a single value of a configuration, and the file it came from.
+type Setting struct {
+	// Key is the dotted name of the value, such as "repos.default" or
+	// "repos.tables.charts.repo".
+	Key string
+	// Value is the value, as a string.
+	Value string
+	// Source is the file that the value came from.
+	Source string
+}
+
+// FindProject looks for a ProjectFile in dir and each of its parents, and
+// returns the path of the first one found. If there is none, it returns an
+// empty string.
+func FindProject(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		p := filepath.Join(dir, ProjectFile)
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			return p
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Project returns the path of the project file that was merged into the
+// configuration, or an empty string if there was none.
+func (c *Configfile) Project() string {
+	return c.project
+}
+
+// Merge reads a project file and merges it on top of the configuration.
+//
+// Tables are merged by name. Tables that are only in the project are added,
+// but only a table of the home configuration may be made the default. For a
+// table that is also in the home configuration, the project may set its
+// ref, and may turn verify on, but an error is returned if it changes the
+// table's repo or type, or turns verify off. Every other value that is set in
+// the project replaces the home value. A relative values file is resolved
+// against the directory of the project file.
+//
+// Merged values are not written by Save, so that the home configuration can
+// still be changed and saved from within a project.
+func (c *Configfile) Merge(filename string) error {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(abs)
+	if err != nil {
+		return err
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	unverified, err := unverifiedTables(data)
+	if err != nil {
+		return err
+	}
+
+	if c.base == nil {
+		c.base = c.copy()
+	}
+	if c.sources == nil {
+		c.sources = map[string]string{}
+	}
+	c.project = abs
+
+	if p.Repos != nil {
+		if d := p.Repos.Default; d != "" {
+			if c.Repos.index(d) < 0 {
+				return fmt.Errorf("repos.default names table %s, which is not in %s. A project file may only make a table of the home configuration the default", d, c.filename)
+			}
+			c.Repos.Default = d
+			c.sources["repos.default"] = abs
+		}
+		for _, t := range p.Repos.Tables {
+			if t.Ref != "" && !validRef(t.Ref) {
+				return fmt.Errorf("repos.tables.%s.ref: %q is not a branch, tag, or commit name", t.Name, t.Ref)
+			}
+			i := c.Repos.index(t.Name)
+			if i < 0 {
+				c.Repos.Tables = append(c.Repos.Tables, t)
+				c.sources["repos.tables."+t.Name] = abs
+				continue
+			}
+			if err := c.mergeTable(c.Repos.Tables[i], t, unverified[t.Name], abs); err != nil {
+				return err
+			}
+		}
+	}
+	if p.Namespace != "" {
+		c.Namespace = p.Namespace
+		c.sources["namespace"] = abs
+	}
+	if p.KubeContext != "" {
+		c.KubeContext = p.KubeContext
+		c.sources["kubeContext"] = abs
+	}
+	if p.Values != "" {
+		c.Values = p.Values
+		if !filepath.IsAbs(c.Values) {
+			c.Values = filepath.Join(filepath.Dir(abs), c.Values)
+		}
+		c.sources["values"] = abs
+	}
+	if len(p.Exclude) > 0 {
+		c.Exclude = p.Exclude
+		c.sources["exclude"] = abs
+	}
+	for _, k := range p.unknown {
+		c.unknown = append(c.unknown, k+projectSuffix(abs))
+	}
+	return nil
+}
+
+// mergeTable merges the fields of a project table into the home table of the
+// same name. A project may not point a home table elsewhere, or stop verifying
+// it, since that would change where charts come from without the home
+// configuration saying so.
+func (c *Configfile) mergeTable(home, t *Table, unverified bool, project string) error {
+	key := "repos.tables." + t.Name
+	if t.Repo != "" && t.Repo != home.Repo {
+		return fmt.Errorf("%s.repo is %s in %s. A project file may not change the repo of a table", key, home.Repo, c.Source(key+".repo"))
+	}
+	if t.Type != "" && t.Type != home.Type {
+		return fmt.Errorf("%s.type is %q in %s. A project file may not change the type of a table", key, home.Type, c.Source(key+".type"))
+	}
+	if unverified && home.Verify {
+		return fmt.Errorf("%s.verify is true in %s. A project file may not turn verification off", key, c.Source(key+".verify"))
+	}
+	if t.Ref != "" {
+		home.Ref = t.Ref
+		c.sources[key+".ref"] = project
+	}
+	if t.Verify && !home.Verify {
+		home.Verify = true
+		c.sources[key+".verify"] = project
+	}
+	return nil
+}
+
+// unverifiedTables returns the names of the tables that set verify to false
+// in a configuration file, which Parse cannot tell apart from leaving it out.
+func unverifiedTables(data []byte) (map[string]bool, error) {
+	doc := struct {
+		Repos struct {
+			Tables []struct {
+				Name   string `yaml:"name"`
+				Verify *bool  `yaml:"verify"`
+			} `yaml:"tables"`
+		} `yaml:"repos"`
+	}{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	res := map[string]bool{}
+	for _, t := range doc.Repos.Tables {
+		if t.Verify != nil && !*t.Verify {
+			res[t.Name] = true
+		}
+	}
+	return res, nil
+}
+
+// Settings lists the values that are set in the configuration, with the file
+// that each one came from.
+func (c *Configfile) Settings() []Setting {
+	s := []Setting{}
+	add := func(key, value string) {
+		if value != "" {
+			s = append(s, Setting{Key: key, Value: value, Source: c.Source(key)})
+		}
+	}
+
+	if c.Repos != nil {
+		add("repos.default", c.Repos.Default)
+		for _, t := range c.Repos.Tables {
+			p := "repos.tables." + t.Name
+			add(p+".repo", t.Repo)
+			add(p+".type", t.Type)
+			add(p+".ref", t.Ref)
+			if t.Verify {
+				add(p+".verify", strconv.FormatBool(t.Verify))
+			}
+		}
+	}
+	add("namespace", c.Namespace)
+	add("kubeContext", c.KubeContext)
+	add("values", c.Values)
+	add("exclude", strings.Join(c.Exclude, ","))
+	return s
+}
+
+// Source returns the file that the value of a key came from.
+func (c *Configfile) Source(key string) string {
+	for k := key; ; {
+		if s, ok := c.sources[k]; ok {
+			return s
+		}
+		i := strings.LastIndex(k, ".")
+		if i < 0 {
+			return c.filename
+		}
+		k = k[:i]
+	}
+}
+
+// copy returns a copy of the configuration that shares no tables with it.
+func (c *Configfile) copy() *Configfile {
+	cp := *c
+	if c.Repos != nil {
+		r := *c.Repos
+		r.Tables = make([]*Table, len(c.Repos.Tables))
+		for i, t := range c.Repos.Tables {
+			tc := *t
+			r.Tables[i] = &tc
+		}
+		cp.Repos = &r
+	}
+	cp.Exclude = append([]string(nil), c.Exclude...)
+	cp.unknown = append([]string(nil), c.unknown...)
+	return &cp
+}
+
+// home returns the configuration without the values that were merged from a
+// project. Values that the project replaced are restored from the home
+// configuration, and tables that only the project has are left out.
+func (c *Configfile) home() *Configfile {
+	if c.base == nil {
+		return c
+	}
+	h := c.copy()
+	fromProject := func(key string) bool {
+		return c.project != "" && c.sources[key] == c.project
+	}
+
+	if h.Repos != nil {
+		if fromProject("repos.default") {
+			h.Repos.Default = c.base.Repos.Default
+		}
+		tables := []*Table{}
+		for _, t := range h.Repos.Tables {
+			key := "repos.tables." + t.Name
+			if fromProject(key) {
+				continue
+			}
+			if i := c.base.Repos.index(t.Name); i >= 0 {
+				if fromProject(key + ".ref") {
+					t.Ref = c.base.Repos.Tables[i].Ref
+				}
+				if fromProject(key + ".verify") {
+					t.Verify = c.base.Repos.Tables[i].Verify
+				}
+			}
+			tables = append(tables, t)
+		}
+		h.Repos.Tables = tables
+	}
+	if fromProject("namespace") {
+		h.Namespace = c.base.Namespace
+	}
+	if fromProject("kubeContext") {
+		h.KubeContext = c.base.KubeContext
+	}
+	if fromProject("values") {
+		h.Values = c.base.Values
+	}
+	if fromProject("exclude") {
+		h.Exclude = c.base.Exclude
+	}
+	h.unknown = nil
+	for _, k := range c.unknown {
+		if !strings.HasSuffix(k, projectSuffix(c.project)) {
+			h.unknown = append(h.unknown, k)
+		}
+	}
+	return h
+}
+
+// projectSuffix marks the unknown keys that were read from a project file.
+func projectSuffix(project string) string {
+	return " (in " + project + ")"
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProject(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "helmc-project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	home := filepath.Join(tmp, "home", "config.yaml")
+	os.MkdirAll(filepath.Dir(home), 0755)
+	ioutil.WriteFile(home, []byte(`repos:
+  default: charts
+  tables:
+    - name: charts
+      repo: https://github.com/helm/charts
+    - name: team
+      repo: https://github.com/team/charts
+    - name: signed
+      repo: https://github.com/signed/charts
+      verify: true
+namespace: default
+`), 0644)
+
+	project := filepath.Join(tmp, "app")
+	nested := filepath.Join(project, "src", "web")
+	os.MkdirAll(nested, 0755)
+	pfile := filepath.Join(project, ProjectFile)
+	ioutil.WriteFile(pfile, []byte(`repos:
+  default: team
+  tables:
+    - name: app
+      repo: https://github.com/app/charts
+    - name: team
+      repo: https://github.com/team/charts
+      ref: v2
+namespace: dev
+kubeContext: staging
+values: values.toml
+exclude:
+  - tpl
+kubeContxt: typo
+`), 0644)
+
+	if p := FindProject(nested); p != pfile {
+		t.Errorf("Expected to find %s, got %q", pfile, p)
+	}
+	if p := FindProject(filepath.Dir(home)); p != "" {
+		t.Errorf("Expected no project file, got %s", p)
+	}
+
+	cfg, err := LoadFile(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Merge(pfile); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Repos.Default != "team" || cfg.Namespace != "dev" || cfg.KubeContext != "staging" {
+		t.Errorf("Expected project values, got %q, %q, %q", cfg.Repos.Default, cfg.Namespace, cfg.KubeContext)
+	}
+	if cfg.Values != filepath.Join(project, "values.toml") {
+		t.Errorf("Expected the values file to be relative to the project, got %s", cfg.Values)
+	}
+	if len(cfg.Repos.Tables) != 4 || cfg.Repos.Table("team").Ref != "v2" {
+		t.Errorf("Expected the project tables to be merged by name, got %d tables", len(cfg.Repos.Tables))
+	}
+	if !cfg.Repos.Table("signed").Verify {
+		t.Error("Expected the project to leave verification on")
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "unknown key kubeContxt (in "+pfile+")") {
+		t.Errorf("Expected the unknown project key to be reported, got %v", err)
+	}
+
+	sources := map[string]string{}
+	for _, s := range cfg.Settings() {
+		sources[s.Key] = s.Source
+	}
+	expect := map[string]string{
+		"repos.default":            pfile,
+		"repos.tables.charts.repo": home,
+		"repos.tables.team.ref":    pfile,
+		"namespace":                pfile,
+		"exclude":                  pfile,
+	}
+	for k, v := range expect {
+		if sources[k] != v {
+			t.Errorf("Expected %s to come from %s, got %q", k, v, sources[k])
+		}
+	}
+
+	// Saving only writes the home configuration, including home changes
+	// made from within the project.
+	cfg.Repos.Tables = append(cfg.Repos.Tables, &Table{Name: "new", Repo: "https://github.com/new/charts"})
+	if err := cfg.Save(""); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(home)
+	saved := string(data)
+	for _, s := range []string{"default: charts", "namespace: default", "name: new"} {
+		if !strings.Contains(saved, s) {
+			t.Errorf("Expected saved configuration to contain %q:\n%s", s, saved)
+		}
+	}
+	for _, s := range []string{"app", "staging", "v2", "tpl"} {
+		if strings.Contains(saved, s) {
+			t.Errorf("Expected saved configuration not to contain %q:\n%s", s, saved)
+		}
+	}
+}
+
+func TestProjectMayNotWeakenTables(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "helmc-project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	home := filepath.Join(tmp, "config.yaml")
+	ioutil.WriteFile(home, []byte(`repos:
+  default: charts
+  tables:
+    - name: charts
+      repo: https://github.com/helm/charts
+      verify: true
+`), 0644)
+
+	tests := []struct {
+		project, expected string
+	}{
+		{"  tables:\n    - name: charts\n      repo: https://example.com/evil\n", "may not change the repo"},
+		{"  tables:\n    - name: charts\n      type: http\n", "may not change the type"},
+		{"  tables:\n    - name: charts\n      verify: false\n", "may not turn verification off"},
+		{"  tables:\n    - name: charts\n      ref: --upload-pack=evil\n", "is not a branch, tag, or commit name"},
+		{"  default: evil\n  tables:\n    - name: evil\n      repo: https://example.com/evil\n", "may only make a table of the home configuration the default"},
+	}
+	for _, tt := range tests {
+		pfile := filepath.Join(tmp, ProjectFile)
+		ioutil.WriteFile(pfile, []byte("repos:\n"+tt.project), 0644)
+
+		cfg, err := LoadFile(home)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = cfg.Merge(pfile)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+		}
+	}
+}
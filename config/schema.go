@@ -0,0 +1,266 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// SchemaVersion is the version of the configuration format that this version
+// of Helm Classic reads and writes.
+//
+// Files with an older version are migrated when they are loaded. Files with a
+// newer version are refused.
+const SchemaVersion = 1
+
+// migrations upgrade a configuration document from one schema version to the
+// next: migrations[i] upgrades version i to version i+1. Files without a
+// version are version 0.
+var migrations = []func(doc map[interface{}]interface{}) error{
+	// Version 1 adds the version, and drops the workspace section, which
+	// never held any settings.
+	func(doc map[interface{}]interface{}) error {
+		delete(doc, "workspace")
+		return nil
+	},
+}
+
+// schemaKeys lists the keys that each section of a configuration may have.
+var schemaKeys = map[string][]string{
+	"":             {"version", "repos", "namespace", "kubeContext", "values", "exclude"},
+	"repos":        {"default", "tables"},
+	"repos.tables": {"name", "repo", "type", "ref", "verify"},
+}
+
+// validTableName matches the names that a table may have. Names are used in
+// chart names (table/chart), keys (repos.tables.NAME.repo), and directories.
+var validTableName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// hexCommit matches an abbreviated or full commit SHA.
+var hexCommit = regexp.MustCompile(`^[0-9a-fA-F]{4,40}$`)
+
+// scpURL matches the scp-like syntax for Git URLs, as in git@github.com:x/y.
+var scpURL = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:.+$`)
+
+// ValidationError lists the problems found in a configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+// migrate upgrades a configuration document to SchemaVersion, and returns the
+// version that it had.
+func migrate(doc map[interface{}]interface{}) (int, error) {
+	version := 0
+	if v, ok := doc["version"]; ok {
+		n, ok := v.(int)
+		if !ok || n < 0 {
+			return 0, fmt.Errorf("invalid version %v", v)
+		}
+		version = n
+	}
+	if version > SchemaVersion {
+		return version, fmt.Errorf("schema version %d is newer than this version of Helm Classic supports (%d)", version, SchemaVersion)
+	}
+	for v := version; v < SchemaVersion; v++ {
+		if err := migrations[v](doc); err != nil {
+			return version, fmt.Errorf("could not migrate from schema version %d: %s", v, err)
+		}
+	}
+	doc["version"] = SchemaVersion
+	return version, nil
+}
+
+// checkKeys returns the keys in a configuration document that are not in the
+// schema, sorted.
+func checkKeys(doc map[interface{}]interface{}) []string {
+	unknown := []string{}
+	var check func(section string, m map[interface{}]interface{}, prefix string)
+	check = func(section string, m map[interface{}]interface{}, prefix string) {
+		for k, v := range m {
+			key := fmt.Sprint(k)
+			if !contains(schemaKeys[section], key) {
+				unknown = append(unknown, prefix+key)
+				continue
+			}
+			sub := strings.TrimPrefix(section+"."+key, ".")
+			if _, ok := schemaKeys[sub]; !ok {
+				continue
+			}
+			switch v := v.(type) {
+			case map[interface{}]interface{}:
+				check(sub, v, prefix+key+".")
+			case []interface{}:
+				for i, item := range v {
+					if im, ok := item.(map[interface{}]interface{}); ok {
+						check(sub, im, fmt.Sprintf("%s%s[%d].", prefix, key, i))
+					}
+				}
+			}
+		}
+	}
+	check("", doc, "")
+	sort.Strings(unknown)
+	return unknown
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that a table has a valid name, type, URL, and ref.
+func (t *Table) Validate() error {
+	if !validTableName.MatchString(t.Name) {
+		return fmt.Errorf("invalid table name %q: use letters, digits, '-' and '_'", t.Name)
+	}
+	switch t.Type {
+	case "", TableGit:
+		if !validGitURL(t.Repo) {
+			return fmt.Errorf("table %s: %q is not a Git URL or an absolute path", t.Name, t.Repo)
+		}
+		if t.Ref != "" && !validRef(t.Ref) {
+			return fmt.Errorf("table %s: %q is not a branch, tag, or commit name", t.Name, t.Ref)
+		}
+	case TableHTTP:
+		if t.Ref != "" {
+			return fmt.Errorf("table %s: HTTP tables cannot be pinned to a ref", t.Name)
+		}
+		if _, err := indexURL(t.Repo); err != nil {
+			return fmt.Errorf("table %s: %s", t.Name, err)
+		}
+	default:
+		return fmt.Errorf("table %s: unknown table type %q. Use %q or %q", t.Name, t.Type, TableGit, TableHTTP)
+	}
+	return nil
+}
+
+// validRef reports whether ref can name a branch, tag, or commit. A ref that
+// starts with '-' is refused, since Git would read it as an option.
+func validRef(ref string) bool {
+	if strings.HasPrefix(ref, "-") {
+		return false
+	}
+	if hexCommit.MatchString(ref) {
+		return true
+	}
+	return exec.Command("git", "check-ref-format", "--allow-onelevel", ref).Run() == nil
+}
+
+func validGitURL(repo string) bool {
+	if filepath.IsAbs(repo) || scpURL.MatchString(repo) {
+		return true
+	}
+	u, err := url.Parse(repo)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https", "ssh", "git", "git+ssh":
+		return u.Host != ""
+	case "file":
+		return u.Path != ""
+	}
+	return false
+}
+
+// Validate checks the configuration, and returns a *ValidationError that lists
+// every problem found.
+//
+// Every key must be in the schema, every table must be valid and have a unique
+// name, and the default table must be one of them.
+func (c *Configfile) Validate() error {
+	problems := []string{}
+	for _, k := range c.unknown {
+		problems = append(problems, fmt.Sprintf("unknown key %s", k))
+	}
+	if c.Repos != nil {
+		seen := map[string]bool{}
+		for _, t := range c.Repos.Tables {
+			if err := t.Validate(); err != nil {
+				problems = append(problems, err.Error())
+			}
+			if seen[t.Name] {
+				problems = append(problems, fmt.Sprintf("table %s is defined more than once", t.Name))
+			}
+			seen[t.Name] = true
+		}
+		if d := c.Repos.Default; d != "" && !seen[d] {
+			problems = append(problems, fmt.Sprintf("default table %s is not defined", d))
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// Migrated reports whether the configuration was migrated from an older
+// schema version when it was loaded. It is not saved until Save is called.
+func (c *Configfile) Migrated() bool {
+	return c.fromVersion < SchemaVersion
+}
+
+// writeFile writes data to a file atomically, by writing it to a temporary
+// file in the same directory and renaming that over the file. The file is only
+// readable by its owner.
+func writeFile(filename string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename))
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0600)
+	}
+	if err == nil {
+		err = os.Rename(tmp, filename)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// decode migrates and decodes a configuration document into c. Keys that are
+// not in the schema are recorded for Validate, so that a file with a typo can
+// still be loaded, checked, and fixed.
+func (c *Configfile) decode(data []byte) error {
+	doc := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	v, err := migrate(doc)
+	if err != nil {
+		return err
+	}
+	c.fromVersion = v
+	c.unknown = checkKeys(doc)
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(out, c)
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSchema(t *testing.T) {
+	cfg, err := Parse([]byte("repos:\n  default: charts\nworkspace:\n  ignore: me\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Migrated() || cfg.Version != SchemaVersion {
+		t.Errorf("Expected an unversioned file to be migrated, got version %d", cfg.Version)
+	}
+
+	cfg, err = Parse([]byte("version: 1\nrepos:\n  default: charts\n"))
+	if err != nil || cfg.Migrated() {
+		t.Errorf("Expected a current file not to be migrated (%v)", err)
+	}
+
+	cfg, err = Parse([]byte("version: 1\nnamspace: dev\nrepos:\n  tables:\n    - name: charts\n      repo: /srv/charts\n      url: x\n"))
+	if err != nil {
+		t.Fatalf("Expected unknown keys not to stop parsing, got %s", err)
+	}
+	err = cfg.Validate()
+	if err == nil || err.Error() != "unknown key namspace; unknown key repos.tables[0].url" {
+		t.Errorf("Expected unknown keys to be reported, got %v", err)
+	}
+	if err := cfg.Save(""); err == nil || !strings.Contains(err.Error(), "unknown keys would be lost: namspace") {
+		t.Errorf("Expected unknown keys to stop Save, got %v", err)
+	}
+	if err := cfg.Unset("namspace"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Unset("repos.tables[0].url"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected unset to remove unknown keys, got %s", err)
+	}
+
+	if _, err := Parse([]byte("version: 99\n")); err == nil {
+		t.Error("Expected a newer schema version to be refused")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := &Configfile{Repos: &Repos{
+		Default: "nope",
+		Tables: []*Table{
+			{Name: "charts", Repo: "https://github.com/helm/charts"},
+			{Name: "ssh", Repo: "git@github.com:helm/charts.git"},
+			{Name: "local", Repo: "/srv/charts"},
+			{Name: "bad.name", Repo: "https://github.com/helm/charts"},
+			{Name: "bad-url", Repo: "github.com/helm/charts"},
+			{Name: "charts", Repo: "https://github.com/helm/charts"},
+			{Name: "option", Repo: "/srv/charts", Ref: "--upload-pack=evil"},
+			{Name: "dots", Repo: "/srv/charts", Ref: "v1..2"},
+		},
+	}}
+	err := cfg.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected a ValidationError, got %v", err)
+	}
+	expect := []string{"bad.name", "bad-url", "charts is defined more than once", "default table nope", "--upload-pack=evil", "v1..2"}
+	if len(verr.Problems) != len(expect) {
+		t.Errorf("Expected %d problems, got %q", len(expect), verr.Problems)
+	}
+	for _, e := range expect {
+		if !strings.Contains(err.Error(), e) {
+			t.Errorf("Expected %q in %s", e, err)
+		}
+	}
+
+	cfg.Repos.Default = "charts"
+	cfg.Repos.Tables = cfg.Repos.Tables[:3]
+	cfg.Repos.Tables[1].Ref = "release/v1.0"
+	cfg.Repos.Tables[2].Ref = "3f2a9c1"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected a valid configuration, got %s", err)
+	}
+}
+
+func TestSetGetUnset(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "helmc-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	filename := filepath.Join(tmp, "config.yaml")
+	ioutil.WriteFile(filename, []byte("repos:\n  default: charts\n  tables:\n    - name: charts\n      repo: https://github.com/helm/charts\n"), 0755)
+
+	cfg, err := LoadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sets := [][2]string{
+		{"namespace", "dev"},
+		{"exclude", "tpl, sed"},
+		{"repos.tables.stable.repo", "https://example.com/charts"},
+		{"repos.tables.stable.type", "http"},
+		{"repos.tables.charts.verify", "true"},
+	}
+	for _, s := range sets {
+		if err := cfg.Set(s[0], s[1]); err != nil {
+			t.Fatalf("%s: %s", s[0], err)
+		}
+	}
+	if err := cfg.Set("repos.tables.charts.verify", "maybe"); err == nil {
+		t.Error("Expected an error for a bad boolean")
+	}
+	if err := cfg.Set("nope", "x"); err == nil {
+		t.Error("Expected an error for an unknown key")
+	}
+	if err := cfg.Unset("repos.tables.charts.verify"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Save(""); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("Expected the configuration to be saved with mode 0600, got %s", fi.Mode())
+	}
+	if files, _ := filepath.Glob(filepath.Join(tmp, "*")); len(files) != 1 {
+		t.Errorf("Expected no temporary files to be left, got %v", files)
+	}
+
+	cfg, err = LoadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Migrated() {
+		t.Error("Expected the saved file to have the current schema version")
+	}
+	gets := map[string]string{
+		"namespace":                  "dev",
+		"exclude":                    "tpl,sed",
+		"repos.tables.stable.type":   "http",
+		"repos.tables.charts.verify": "false",
+	}
+	for k, v := range gets {
+		if got, err := cfg.Get(k); err != nil || got != v {
+			t.Errorf("Expected %s to be %q, got %q (%v)", k, v, got, err)
+		}
+	}
+
+	if err := cfg.Unset("repos.tables.stable"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.Get("repos.tables.stable.repo"); err == nil {
+		t.Error("Expected the stable table to be removed")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/helm/helm-classic/chart"
@@ -35,27 +36,8 @@ func Resolve(cf *chart.Chartfile, installdir string) ([]*chart.Dependency, error
 
 	res := []*chart.Dependency{}
 
-	// TODO: This could be made more efficient.
 	for _, check := range cf.Dependencies {
-		resolved := false
-		for n, chart := range cache {
-			log.Debug("Checking if %s (%s) %s meets %s %s", chart.Name, n, chart.Version, check.Name, check.Version)
-			if chart.From != nil {
-				log.Debug("✔︎")
-				if satisfies(chart.From, check) {
-					resolved = true
-					break
-				}
-			} else {
-				log.Info("Chart %s is pre-0.2.0. Legacy mode enabled.", chart.Name)
-				if chart.Name == check.Name && check.VersionOK(chart.Version) {
-					log.Debug("✔︎")
-					resolved = true
-					break
-				}
-			}
-		}
-		if !resolved {
+		if _, ok := findInstalled(cache, check); !ok {
 			log.Debug("No matches found for %s %s", check.Name, check.Version)
 			res = append(res, check)
 		}
@@ -63,6 +45,37 @@ func Resolve(cf *chart.Chartfile, installdir string) ([]*chart.Dependency, error
 	return res, nil
 }
 
+// findInstalled returns the directory name of a chart in the cache that
+// satisfies a dependency.
+//
+// Charts are checked in order of their directory names.
+func findInstalled(cache map[string]*chart.Chartfile, check *chart.Dependency) (string, bool) {
+	names := make([]string, 0, len(cache))
+	for n := range cache {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	// TODO: This could be made more efficient.
+	for _, n := range names {
+		chart := cache[n]
+		log.Debug("Checking if %s (%s) %s meets %s %s", chart.Name, n, chart.Version, check.Name, check.Version)
+		if chart.From != nil {
+			if satisfies(chart.From, check) {
+				log.Debug("✔︎")
+				return n, true
+			}
+		} else {
+			log.Info("Chart %s is pre-0.2.0. Legacy mode enabled.", chart.Name)
+			if chart.Name == check.Name && check.VersionOK(chart.Version) {
+				log.Debug("✔︎")
+				return n, true
+			}
+		}
+	}
+	return "", false
+}
+
 // satisfies checks that this satisfies the dependency spec in that.
 func satisfies(this, that *chart.Dependency) bool {
 	if this.Name != that.Name {
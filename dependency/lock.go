@@ -0,0 +1,116 @@
+package dependency
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/helm/helm-classic/chart"
+	"gopkg.in/yaml.v2"
+)
+
+// LockFile is the name of the file, next to Chart.yaml, that pins the charts
+// that satisfy a chart's dependencies.
+const LockFile = "Chart.lock"
+
+// Lock records the charts that satisfied a chart's dependencies, and their
+// dependencies in turn, when the lock was last updated.
+type Lock struct {
+	// Dependencies lists the pinned charts in the order they are installed.
+	Dependencies []*Locked `yaml:"dependencies"`
+}
+
+// Locked is a chart that is pinned by a lock.
+type Locked struct {
+	// Name is the name of the chart.
+	Name string `yaml:"name"`
+	// Version is the exact version of the chart.
+	Version string `yaml:"version"`
+	// Table is the local name of the repository table the chart came from.
+	Table string `yaml:"table"`
+	// Repo is the remote Git URL of the table.
+	Repo string `yaml:"repo"`
+	// Commit is the SHA of the table's commit that the chart was taken from.
+	Commit string `yaml:"commit"`
+}
+
+// NewLock pins the charts returned by Walk.
+//
+// commits maps the name of each source to the commit its directory is at.
+// Charts that did not come from a source are pinned to their version only.
+func NewLock(nodes []*Node, commits map[string]string) *Lock {
+	l := &Lock{Dependencies: []*Locked{}}
+	for _, n := range nodes {
+		d := &Locked{Name: n.Name, Version: n.Chartfile.Version}
+		if n.Source != nil {
+			d.Table = n.Source.Name
+			d.Repo = n.Source.Repo
+			d.Commit = commits[n.Source.Name]
+		}
+		l.Dependencies = append(l.Dependencies, d)
+	}
+	return l
+}
+
+// LoadLock loads a lock file.
+func LoadLock(filename string) (*Lock, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	l := &Lock{}
+	if err := yaml.Unmarshal(b, l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Save writes a lock file.
+func (l *Lock) Save(filename string) error {
+	b, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, b, 0644)
+}
+
+// Check verifies that every dependency of a chart is pinned at a version
+// that satisfies it.
+//
+// An error means that the chart's dependencies changed since the lock was
+// updated.
+func (l *Lock) Check(cf *chart.Chartfile) error {
+	for _, d := range cf.Dependencies {
+		found := false
+		for _, p := range l.Dependencies {
+			if satisfies(&chart.Dependency{Name: p.Name, Version: p.Version, Repo: p.Repo}, d) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s %s is not pinned", d.Name, d.Version)
+		}
+	}
+	return nil
+}
+
+// Stale lists the pinned charts that are in installdir at a different
+// version than the lock specifies.
+func (l *Lock) Stale(installdir string) []string {
+	res := []string{}
+	for _, p := range l.Dependencies {
+		cf, err := chart.LoadChartfile(filepath.Join(installdir, p.Name, "Chart.yaml"))
+		if err != nil {
+			continue
+		}
+		v := cf.Version
+		if cf.From != nil {
+			v = cf.From.Version
+		}
+		if v != p.Version {
+			res = append(res, fmt.Sprintf("%s is locked at %s, but the workspace has %s", p.Name, p.Version, v))
+		}
+	}
+	return res
+}
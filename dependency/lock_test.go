@@ -0,0 +1,60 @@
+package dependency
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/helm/helm-classic/chart"
+)
+
+func TestLock(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "helmc-lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	src := &Source{Name: "charts", Repo: "https://github.com/helm/charts.git"}
+	nodes := []*Node{
+		{Name: "db", Chartfile: &chart.Chartfile{Name: "db", Version: "2.3.0"}, Source: src},
+		{Name: "web", Chartfile: &chart.Chartfile{Name: "web", Version: "1.0.0"}, Source: src},
+	}
+	lock := NewLock(nodes, map[string]string{"charts": "abc123"})
+
+	lockfile := filepath.Join(tmp, LockFile)
+	if err := lock.Save(lockfile); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadLock(lockfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lock, loaded) {
+		t.Errorf("Expected %+v, got %+v", lock, loaded)
+	}
+	if d := loaded.Dependencies[0]; d.Table != "charts" || d.Commit != "abc123" || d.Repo != src.Repo {
+		t.Errorf("Unexpected pin %+v", d)
+	}
+
+	cf := &chart.Chartfile{Name: "app", Dependencies: []*chart.Dependency{
+		{Name: "web", Version: "~1.0"},
+		{Name: "db", Version: ">=2.0.0", Repo: "git@github.com:helm/charts.git"},
+	}}
+	if err := lock.Check(cf); err != nil {
+		t.Errorf("Expected the lock to satisfy the chart: %s", err)
+	}
+	cf.Dependencies = append(cf.Dependencies, &chart.Dependency{Name: "cache", Version: "1.0.0"})
+	if err := lock.Check(cf); err == nil || err.Error() != "cache 1.0.0 is not pinned" {
+		t.Errorf("Expected an error for an unpinned dependency, got %v", err)
+	}
+
+	writeChart(t, tmp, "db", "2.3.0")
+	writeChart(t, tmp, "web", "1.1.0")
+	expect := []string{"web is locked at 1.0.0, but the workspace has 1.1.0"}
+	if stale := lock.Stale(tmp); !reflect.DeepEqual(stale, expect) {
+		t.Errorf("Expected %v, got %v", expect, stale)
+	}
+}
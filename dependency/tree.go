@@ -0,0 +1,146 @@
+package dependency
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/log"
+)
+
+const (
+	// StatusSatisfied indicates that a chart in the workspace satisfies the dependency.
+	StatusSatisfied = "satisfied"
+	// StatusAvailable indicates that the dependency is not in the workspace, but
+	// can be fetched from a source.
+	StatusAvailable = "available"
+	// StatusMissing indicates that nothing satisfies the dependency.
+	StatusMissing = "missing"
+	// StatusCycle indicates that the dependency leads back to a chart that
+	// depends on it.
+	StatusCycle = "cycle"
+)
+
+// Tree is a dependency of a chart, together with the dependencies of the
+// chart that satisfies it.
+type Tree struct {
+	// Name is the name of the required chart.
+	Name string `json:"name"`
+	// Constraint is the version range that is required.
+	Constraint string `json:"constraint"`
+	// Repo is the repo that is required, if any.
+	Repo string `json:"repo,omitempty"`
+	// Status is one of the Status* constants.
+	Status string `json:"status"`
+	// Version is the version of the chart that satisfies the dependency.
+	Version string `json:"version,omitempty"`
+	// Workspace is the directory name of the satisfying chart in the workspace.
+	Workspace string `json:"workspace,omitempty"`
+	// Source is the name of the source that the chart can be fetched from.
+	Source string `json:"source,omitempty"`
+	// Dependencies are the dependencies of the satisfying chart.
+	Dependencies []*Tree `json:"dependencies,omitempty"`
+}
+
+// BuildTree resolves the dependencies of a chart, and their dependencies in
+// turn, against the charts in installdir.
+//
+// Dependencies that are not satisfied in installdir are looked up in the
+// sources, as Walk does. Unlike Walk, BuildTree does not stop at missing
+// dependencies or cycles; they are marked in the tree instead.
+func BuildTree(cf *chart.Chartfile, installdir string, sources []*Source) ([]*Tree, error) {
+	cache, err := dependencyCache(installdir)
+	if err != nil {
+		return nil, err
+	}
+	return buildTree(cf, cache, sources, []string{cf.Name}), nil
+}
+
+func buildTree(cf *chart.Chartfile, cache map[string]*chart.Chartfile, sources []*Source, path []string) []*Tree {
+	res := []*Tree{}
+	for _, d := range cf.Dependencies {
+		t := &Tree{Name: d.Name, Constraint: d.Version, Repo: d.Repo, Status: StatusMissing}
+		res = append(res, t)
+
+		var next *chart.Chartfile
+		if name, ok := findInstalled(cache, d); ok {
+			next = cache[name]
+			t.Status = StatusSatisfied
+			t.Workspace = name
+			t.Version = next.Version
+			if next.From != nil {
+				t.Version = next.From.Version
+			}
+		} else if src, scf, _ := findSource(sources, d); src != nil {
+			next = scf
+			t.Status = StatusAvailable
+			t.Source = src.Name
+			t.Version = scf.Version
+		} else {
+			continue
+		}
+
+		for _, p := range path {
+			if p == d.Name {
+				t.Status = StatusCycle
+			}
+		}
+		if t.Status != StatusCycle {
+			t.Dependencies = buildTree(next, cache, sources, append(path, d.Name))
+		}
+	}
+	return res
+}
+
+// Dependent is a chart that depends on another chart.
+type Dependent struct {
+	// Source is the name of the source that has the chart.
+	Source string `json:"source"`
+	// Name is the name of the chart.
+	Name string `json:"name"`
+	// Version is the version of the chart.
+	Version string `json:"version"`
+	// Constraint is the version range of the other chart that is required.
+	Constraint string `json:"constraint"`
+	// Repo is the repo of the other chart that is required, if any.
+	Repo string `json:"repo,omitempty"`
+}
+
+// Dependents lists the charts in the sources that declare a dependency on the
+// named chart, ordered by source and then by chart name. Sources whose
+// directory does not exist are skipped.
+func Dependents(name string, sources []*Source) ([]*Dependent, error) {
+	res := []*Dependent{}
+	for _, src := range sources {
+		fis, err := ioutil.ReadDir(src.Dir)
+		if os.IsNotExist(err) {
+			log.Debug("Skipping %s: %s", src.Name, err)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		names := []string{}
+		for _, fi := range fis {
+			if fi.IsDir() {
+				names = append(names, fi.Name())
+			}
+		}
+		sort.Strings(names)
+
+		for _, n := range names {
+			cf, err := chart.LoadChartfile(filepath.Join(src.Dir, n, "Chart.yaml"))
+			if err != nil {
+				continue
+			}
+			for _, d := range cf.Dependencies {
+				if d.Name != name {
+					continue
+				}
+				res = append(res, &Dependent{Source: src.Name, Name: cf.Name, Version: cf.Version, Constraint: d.Version, Repo: d.Repo})
+			}
+		}
+	}
+	return res, nil
+}
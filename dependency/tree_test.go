@@ -0,0 +1,74 @@
+package dependency
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/helm/helm-classic/chart"
+)
+
+func TestBuildTree(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "helmc-tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	workspace := filepath.Join(tmp, "workspace")
+	table := filepath.Join(tmp, "charts")
+	writeChart(t, workspace, "web", "2.0.0", dep("db", "^3.0"), dep("cache", "1.0.0"))
+	writeChart(t, table, "db", "3.1.0", dep("web", "*"))
+	writeChart(t, table, "cache", "2.0.0")
+	sources := []*Source{{Name: "charts", Dir: table}}
+
+	root := &chart.Chartfile{Name: "app", Dependencies: []*chart.Dependency{dep("web", "~2.0")}}
+	tree, err := BuildTree(root, workspace, sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []*Tree{{
+		Name: "web", Constraint: "~2.0", Status: StatusSatisfied, Version: "2.0.0", Workspace: "web",
+		Dependencies: []*Tree{
+			{Name: "db", Constraint: "^3.0", Status: StatusAvailable, Version: "3.1.0", Source: "charts", Dependencies: []*Tree{
+				{Name: "web", Constraint: "*", Status: StatusCycle, Version: "2.0.0", Workspace: "web"},
+			}},
+			{Name: "cache", Constraint: "1.0.0", Status: StatusMissing},
+		},
+	}}
+	if !reflect.DeepEqual(tree, expect) {
+		t.Errorf("Unexpected tree %+v", tree[0])
+	}
+}
+
+func TestDependents(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "helmc-tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	writeChart(t, filepath.Join(tmp, "a"), "web", "1.0.0", dep("db", "^3.0"))
+	writeChart(t, filepath.Join(tmp, "a"), "db", "3.0.0")
+	writeChart(t, filepath.Join(tmp, "b"), "api", "0.1.0", dep("cache", "*"), dep("db", ">=2.0.0"))
+	sources := []*Source{
+		{Name: "a", Dir: filepath.Join(tmp, "a")},
+		{Name: "b", Dir: filepath.Join(tmp, "b")},
+		{Name: "c", Dir: filepath.Join(tmp, "c")},
+	}
+
+	deps, err := Dependents("db", sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []*Dependent{
+		{Source: "a", Name: "web", Version: "1.0.0", Constraint: "^3.0"},
+		{Source: "b", Name: "api", Version: "0.1.0", Constraint: ">=2.0.0"},
+	}
+	if !reflect.DeepEqual(deps, expect) {
+		t.Errorf("Expected %+v, got %+v", expect, deps)
+	}
+}
@@ -0,0 +1,169 @@
+package dependency
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/log"
+)
+
+// Source is a directory of charts that dependencies can be fetched from, such
+// as the local copy of a repository table.
+type Source struct {
+	// Name is the name of the table.
+	Name string
+	// Repo is the remote Git URL of the table.
+	Repo string
+	// Dir is the directory that contains the table's charts.
+	Dir string
+}
+
+// Node is a chart that is needed to satisfy a dependency.
+type Node struct {
+	// Name is the name of the chart in the workspace.
+	Name string
+	// Chartfile is the chart's Chart.yaml.
+	Chartfile *chart.Chartfile
+	// Source is the table that the chart must be fetched from, or nil if the
+	// chart is already in the workspace.
+	Source *Source
+}
+
+// Walk finds the charts that satisfy the dependencies of a chart, and their
+// dependencies in turn.
+//
+// A dependency is satisfied by a chart in installdir if there is one, and
+// otherwise by the chart of the same name in the first source that has a
+// matching version. If the dependency names a repo, only sources for that repo
+// are searched.
+//
+// The charts are returned in the order they must be installed: every chart comes
+// after the charts it depends on. The chart itself is not included.
+//
+// If installdir is empty, every dependency is taken from the sources.
+//
+// An error is returned if a dependency cannot be satisfied, or if the
+// dependencies form a cycle.
+func Walk(cf *chart.Chartfile, installdir string, sources []*Source) ([]*Node, error) {
+	cache := map[string]*chart.Chartfile{}
+	if installdir != "" {
+		var err error
+		if cache, err = dependencyCache(installdir); err != nil {
+			return nil, err
+		}
+	}
+	w := &walker{
+		cache:   cache,
+		sources: sources,
+		nodes:   map[string]*Node{},
+		done:    map[string]bool{},
+	}
+	if err := w.walk(cf, []string{cf.Name}); err != nil {
+		return nil, err
+	}
+	return w.order, nil
+}
+
+type walker struct {
+	cache   map[string]*chart.Chartfile
+	sources []*Source
+	// nodes are the charts found so far, by name.
+	nodes map[string]*Node
+	// done marks the charts whose dependencies have all been walked.
+	done  map[string]bool
+	order []*Node
+}
+
+// walk resolves the dependencies of cf. path lists the charts that led to cf.
+func (w *walker) walk(cf *chart.Chartfile, path []string) error {
+	for _, d := range cf.Dependencies {
+		n, err := w.resolve(d, path[len(path)-1])
+		if err != nil {
+			return err
+		}
+		for i, p := range path {
+			if p == n.Name {
+				cycle := append(append([]string{}, path[i:]...), n.Name)
+				return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+			}
+		}
+		if w.done[n.Name] {
+			continue
+		}
+		if err := w.walk(n.Chartfile, append(path, n.Name)); err != nil {
+			return err
+		}
+		w.done[n.Name] = true
+		w.order = append(w.order, n)
+	}
+	return nil
+}
+
+// resolve finds the chart that satisfies a dependency of the chart named parent.
+func (w *walker) resolve(d *chart.Dependency, parent string) (*Node, error) {
+	for _, n := range w.nodes {
+		if n.Chartfile.Name == d.Name && d.VersionOK(n.Chartfile.Version) && n.Source != nil {
+			return n, nil
+		}
+	}
+	if n, ok := w.nodes[d.Name]; ok && n.Source != nil {
+		return nil, fmt.Errorf("%s requires %s %s, but another chart requires %s %s", parent, d.Name, d.Version, d.Name, n.Chartfile.Version)
+	}
+	if name, ok := findInstalled(w.cache, d); ok {
+		return w.node(name, w.cache[name], nil), nil
+	}
+
+	src, cf, found := findSource(w.sources, d)
+	if src != nil {
+		if other, ok := w.cache[d.Name]; ok {
+			return nil, fmt.Errorf("%s %s is required by %s, but your workspace has a chart named %s at version %s. Rename or remove it", d.Name, d.Version, parent, d.Name, other.Version)
+		}
+		return w.node(d.Name, cf, src), nil
+	}
+
+	msg := fmt.Sprintf("no chart satisfies %s %s, which is required by %s", d.Name, d.Version, parent)
+	if d.Repo != "" {
+		msg += fmt.Sprintf(" from %s", d.Repo)
+	}
+	if len(found) > 0 {
+		msg += fmt.Sprintf(" (found versions %s)", strings.Join(found, ", "))
+	}
+	return nil, fmt.Errorf("%s", msg)
+}
+
+// findSource returns the first source with a chart that satisfies a
+// dependency, and that chart.
+//
+// If none does, it returns the versions that were found instead, each with
+// the name of its source.
+func findSource(sources []*Source, d *chart.Dependency) (*Source, *chart.Chartfile, []string) {
+	found := []string{}
+	for _, src := range sources {
+		if d.Repo != "" && !optRepoMatch(&chart.Dependency{Repo: src.Repo}, d) {
+			continue
+		}
+		cf, err := chart.LoadChartfile(filepath.Join(src.Dir, d.Name, "Chart.yaml"))
+		if err != nil {
+			log.Debug("No chart %s in %s: %s", d.Name, src.Name, err)
+			continue
+		}
+		if cf.Name != d.Name || !d.VersionOK(cf.Version) {
+			found = append(found, fmt.Sprintf("%s in %s", cf.Version, src.Name))
+			continue
+		}
+		return src, cf, nil
+	}
+	return nil, nil, found
+}
+
+// node returns the node for a chart, creating it if it has not been seen.
+func (w *walker) node(name string, cf *chart.Chartfile, src *Source) *Node {
+	if n, ok := w.nodes[name]; ok {
+		return n
+	}
+	n := &Node{Name: name, Chartfile: cf, Source: src}
+	w.nodes[name] = n
+	return n
+}
@@ -0,0 +1,96 @@
+package dependency
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/helm/helm-classic/chart"
+)
+
+// writeChart writes a Chart.yaml with the given dependencies into dir/name.
+func writeChart(t *testing.T, dir, name, version string, deps ...*chart.Dependency) {
+	if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cf := &chart.Chartfile{Name: name, Version: version, Dependencies: deps}
+	if err := cf.Save(filepath.Join(dir, name, "Chart.yaml")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func dep(name, version string) *chart.Dependency {
+	return &chart.Dependency{Name: name, Version: version}
+}
+
+func TestWalk(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "helmc-walk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	workspace := filepath.Join(tmp, "workspace")
+	table := filepath.Join(tmp, "charts")
+	other := filepath.Join(tmp, "other")
+	os.MkdirAll(workspace, 0755)
+
+	writeChart(t, table, "web", "1.0.0", dep("cache", "~1.2"), dep("db", ">=2.0.0"))
+	writeChart(t, table, "cache", "1.2.5", dep("db", "^2.1"))
+	writeChart(t, table, "db", "1.0.0")
+	writeChart(t, other, "db", "2.3.0")
+	writeChart(t, workspace, "cache", "1.1.0")
+
+	sources := []*Source{
+		{Name: "charts", Repo: "https://github.com/helm/charts.git", Dir: table},
+		{Name: "other", Repo: "https://example.com/other.git", Dir: other},
+	}
+	root := &chart.Chartfile{Name: "app", Dependencies: []*chart.Dependency{dep("web", "1.0.0")}}
+
+	// The workspace has a cache chart with the wrong version.
+	if _, err := Walk(root, workspace, sources); err == nil || !strings.Contains(err.Error(), "Rename or remove it") {
+		t.Errorf("Expected a conflict with the workspace, got %v", err)
+	}
+	os.RemoveAll(filepath.Join(workspace, "cache"))
+
+	nodes, err := Walk(root, workspace, sources)
+	if err != nil {
+		t.Fatalf("Walk failed: %s", err)
+	}
+	got := []string{}
+	for _, n := range nodes {
+		got = append(got, n.Name+"@"+n.Chartfile.Version+"<"+n.Source.Name)
+	}
+	expect := "db@2.3.0<other cache@1.2.5<charts web@1.0.0<charts"
+	if strings.Join(got, " ") != expect {
+		t.Errorf("Expected %q, got %q", expect, strings.Join(got, " "))
+	}
+
+	// Charts that are in the workspace are not fetched.
+	writeChart(t, workspace, "db", "2.4.0")
+	nodes, err = Walk(root, workspace, sources)
+	if err != nil {
+		t.Fatalf("Walk failed: %s", err)
+	}
+	if nodes[0].Name != "db" || nodes[0].Source != nil {
+		t.Errorf("Expected db to come from the workspace, got %+v", nodes[0])
+	}
+
+	// Repos restrict the sources.
+	root.Dependencies = []*chart.Dependency{{Name: "db", Version: ">=2.0.0", Repo: "git@github.com:helm/charts.git"}}
+	os.RemoveAll(filepath.Join(workspace, "db"))
+	if _, err := Walk(root, workspace, sources); err == nil || !strings.Contains(err.Error(), "found versions 1.0.0 in charts") {
+		t.Errorf("Expected an unsatisfiable dependency, got %v", err)
+	}
+
+	// Cycles are reported.
+	writeChart(t, table, "db", "1.0.0", dep("web", "*"))
+	root.Dependencies = []*chart.Dependency{dep("web", "*")}
+	sources = sources[:1]
+	writeChart(t, table, "web", "1.0.0", dep("db", "1.0.0"))
+	if _, err := Walk(root, workspace, sources); err == nil || !strings.Contains(err.Error(), "dependency cycle: web -> db -> web") {
+		t.Errorf("Expected a cycle, got %v", err)
+	}
+}
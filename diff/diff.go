@@ -0,0 +1,146 @@
+// Package diff computes line-oriented differences between two texts.
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// op is a single line of an edit script.
+type op struct {
+	kind byte // ' ' for unchanged, '-' for removed, '+' for added
+	text string
+	// a and b are the zero-based line numbers in the old and new text.
+	a, b int
+}
+
+// Unified returns the differences between a and b in unified diff format.
+//
+// The from and to names label the old and new texts. Context is the number of
+// unchanged lines shown around each change. If a and b are equal, an empty
+// string is returned.
+func Unified(a, b, from, to string, context int) string {
+	ops := script(lines(a), lines(b))
+
+	changed := false
+	for _, o := range ops {
+		if o.kind != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", from, to)
+	for _, h := range hunks(ops, context) {
+		writeHunk(&buf, ops[h[0]:h[1]])
+	}
+	return buf.String()
+}
+
+// lines splits text into lines, ignoring a trailing newline.
+func lines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// script computes an edit script from a to b using a longest common subsequence.
+func script(a, b []string) []op {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := []op{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{' ', a[i], i, j})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, op{'+', b[j], i, j})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, op{'-', a[i], i, j})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, op{'+', b[j], i, j})
+	}
+	return ops
+}
+
+// hunks groups changes with their context, returning [start, end) ranges of ops.
+func hunks(ops []op, context int) [][2]int {
+	res := [][2]int{}
+	for i, o := range ops {
+		if o.kind == ' ' {
+			continue
+		}
+		start, end := i-context, i+context+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(ops) {
+			end = len(ops)
+		}
+		if n := len(res); n > 0 && start <= res[n-1][1] {
+			res[n-1][1] = end
+			continue
+		}
+		res = append(res, [2]int{start, end})
+	}
+	return res
+}
+
+func writeHunk(buf *bytes.Buffer, ops []op) {
+	alen, blen := 0, 0
+	for _, o := range ops {
+		if o.kind != '+' {
+			alen++
+		}
+		if o.kind != '-' {
+			blen++
+		}
+	}
+	fmt.Fprintf(buf, "@@ -%s +%s @@\n", rng(ops[0].a, alen), rng(ops[0].b, blen))
+	for _, o := range ops {
+		fmt.Fprintf(buf, "%c%s\n", o.kind, o.text)
+	}
+}
+
+// rng formats a hunk range. Line numbers are one-based, and an empty range
+// refers to the line before it.
+func rng(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
@@ -0,0 +1,38 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		a, b, expected string
+	}{
+		{"one\ntwo\n", "one\ntwo\n", ""},
+		{
+			"one\ntwo\nthree\n",
+			"one\n2\nthree\n",
+			"--- old\n+++ new\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		},
+		{
+			"",
+			"one\n",
+			"--- old\n+++ new\n@@ -0,0 +1 @@\n+one\n",
+		},
+		{
+			"one\n",
+			"",
+			"--- old\n+++ new\n@@ -1 +0,0 @@\n-one\n",
+		},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			"1\n2\nthree\n4\n5\n6\n7\n8\nnine\n",
+			"--- old\n+++ new\n@@ -2,3 +2,3 @@\n 2\n-3\n+three\n 4\n@@ -8,2 +8,2 @@\n 8\n-9\n+nine\n",
+		},
+	}
+
+	for _, tt := range tests {
+		actual := Unified(tt.a, tt.b, "old", "new", 1)
+		if actual != tt.expected {
+			t.Errorf("Diff of %q and %q:\n[Expected]\n%s\n[Got]\n%s", tt.a, tt.b, tt.expected, actual)
+		}
+	}
+}
@@ -99,9 +99,22 @@ func Walk(dir string, exclude []string, force bool) (int, error) {
 }
 
 func execute(command string, force bool) error {
+	cmd, err := Command(command, force)
+	if err != nil {
+		return err
+	}
+	return cmd.Run()
+}
+
+// Command builds the command for a generator line, without running it.
+//
+// The command is connected to the standard input and output of helmc. A
+// leading "helm" is replaced with "helmc", and template commands get the -f flag
+// if force is set.
+func Command(command string, force bool) (*exec.Cmd, error) {
 	args := strings.Fields(command)
 	if len(args) == 0 {
-		return errors.New("empty command")
+		return nil, errors.New("empty command")
 	}
 	name := args[0]
 	if args[0] == "helm" && (args[1] == "template" || args[1] == "tpl") && force {
@@ -124,11 +137,7 @@ func execute(command string, force bool) error {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
-
-	if err := cmd.Run(); err != nil {
-		return err
-	}
-	return nil
+	return cmd, nil
 }
 
 // skip indicates whether the directory's contents should be skipped.
@@ -0,0 +1,476 @@
+package kubectl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/helm/helm-classic/codec"
+)
+
+// Reasons reported by the API server for common failures.
+const (
+	ReasonNotFound      = "NotFound"
+	ReasonAlreadyExists = "AlreadyExists"
+	ReasonForbidden     = "Forbidden"
+	ReasonUnauthorized  = "Unauthorized"
+	ReasonConflict      = "Conflict"
+)
+
+// APIRunner implements Runner by sending requests directly to the Kubernetes
+// API server, instead of running kubectl.
+//
+// Errors reported by the API server are returned as *APIError values, which
+// can be inspected with IsNotFound, IsAlreadyExists, and IsForbidden.
+type APIRunner struct {
+	// Server is the base URL of the API server.
+	Server string
+	// Namespace is used when neither the caller nor the object names a namespace.
+	Namespace string
+	// Token, if set, is sent as a bearer token.
+	Token string
+	// Username and Password, if set, are sent using basic authentication.
+	Username string
+	Password string
+	// HTTP is the client that is used to make requests.
+	HTTP *http.Client
+}
+
+// APIError is an error returned by the Kubernetes API server.
+type APIError struct {
+	// Code is the HTTP status code of the response.
+	Code int
+	// Reason is a machine-readable description of the error, such as "NotFound".
+	Reason string
+	// Message is a human-readable description of the error.
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// IsNotFound returns true if err is an API error reporting a missing object.
+func IsNotFound(err error) bool {
+	return hasReason(err, ReasonNotFound)
+}
+
+// IsAlreadyExists returns true if err is an API error reporting that an object
+// already exists.
+func IsAlreadyExists(err error) bool {
+	return hasReason(err, ReasonAlreadyExists)
+}
+
+// IsForbidden returns true if err is an API error reporting that a request was
+// not allowed.
+func IsForbidden(err error) bool {
+	return hasReason(err, ReasonForbidden)
+}
+
+func hasReason(err error, reason string) bool {
+	e, ok := err.(*APIError)
+	return ok && e.Reason == reason
+}
+
+// NewAPIRunner creates an APIRunner from a kubectl configuration file.
+//
+// If opts.Kubeconfig is empty, the file is found with KubeconfigPath. If
+// opts.Context is empty, the current context of the file is used.
+func NewAPIRunner(opts Options) (*APIRunner, error) {
+	path := opts.Kubeconfig
+	if path == "" {
+		path = KubeconfigPath()
+	}
+	kc, err := LoadKubeconfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return kc.Runner(opts.Context)
+}
+
+// defaultAPIVersions are the API versions used for kinds outside of the core
+// API group when an object does not declare one.
+var defaultAPIVersions = map[string]string{
+	"DaemonSet":               "extensions/v1beta1",
+	"Deployment":              "extensions/v1beta1",
+	"HorizontalPodAutoscaler": "extensions/v1beta1",
+	"Ingress":                 "extensions/v1beta1",
+	"Job":                     "extensions/v1beta1",
+	"ReplicaSet":              "extensions/v1beta1",
+	"ThirdPartyResource":      "extensions/v1beta1",
+}
+
+// coreKinds are the kinds in the core API group, v1.
+var coreKinds = map[string]bool{
+	"ComponentStatus":       true,
+	"ConfigMap":             true,
+	"Endpoints":             true,
+	"Event":                 true,
+	"LimitRange":            true,
+	"Namespace":             true,
+	"Node":                  true,
+	"PersistentVolume":      true,
+	"PersistentVolumeClaim": true,
+	"Pod":                   true,
+	"PodTemplate":           true,
+	"ReplicationController": true,
+	"ResourceQuota":         true,
+	"Secret":                true,
+	"Service":               true,
+	"ServiceAccount":        true,
+}
+
+// QualifiedKind returns the type of an object in the form that Delete and List
+// take: the kind, prefixed by the apiVersion and a slash if that is not the
+// default for the kind, such as "apps/v1beta1/StatefulSet".
+func QualifiedKind(apiVersion, kind string) string {
+	if apiVersion == "" || apiVersion == defaultAPIVersion(kind) {
+		return kind
+	}
+	return apiVersion + "/" + kind
+}
+
+// SplitKind splits a type that was passed to Delete or List into its
+// apiVersion and kind. The apiVersion is empty if the type is a plain kind.
+func SplitKind(t string) (apiVersion, kind string) {
+	i := strings.LastIndex(t, "/")
+	if i < 0 {
+		return "", t
+	}
+	return t[:i], t[i+1:]
+}
+
+// defaultAPIVersion returns the API version of a kind, or "" if it is unknown.
+func defaultAPIVersion(kind string) string {
+	if v, ok := defaultAPIVersions[kind]; ok {
+		return v
+	}
+	if coreKinds[kind] {
+		return "v1"
+	}
+	return ""
+}
+
+// typePath splits a type passed to Delete or List, and finds the API version
+// of a plain kind.
+func typePath(t string) (apiVersion, kind string, err error) {
+	apiVersion, kind = SplitKind(t)
+	if apiVersion == "" {
+		apiVersion = defaultAPIVersion(kind)
+	}
+	if apiVersion == "" {
+		return "", kind, fmt.Errorf("unknown kind %q: pass it with its apiVersion, as in \"apps/v1beta1/%s\"", kind, kind)
+	}
+	return apiVersion, kind, nil
+}
+
+// clusterScoped are the kinds that do not belong to a namespace.
+var clusterScoped = map[string]bool{
+	"Namespace":          true,
+	"Node":               true,
+	"PersistentVolume":   true,
+	"ThirdPartyResource": true,
+	"ClusterRole":        true,
+	"ClusterRoleBinding": true,
+	"StorageClass":       true,
+}
+
+// IsClusterScoped returns true if objects of a kind do not belong to a namespace.
+func IsClusterScoped(kind string) bool {
+	return clusterScoped[kind]
+}
+
+// apiObject is an object decoded from Runner input.
+type apiObject struct {
+	apiVersion, kind, name, namespace string
+	body                              []byte
+}
+
+// ClusterInfo returns the address and version of the API server.
+func (r *APIRunner) ClusterInfo() ([]byte, error) {
+	b, err := r.do("GET", "/version", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	v := struct {
+		GitVersion string `json:"gitVersion"`
+	}{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("Kubernetes master is running at %s\nServer version: %s\n", r.Server, v.GitVersion)), nil
+}
+
+// Create uploads an object to Kubernetes.
+//
+// If the object already exists, the error satisfies IsAlreadyExists.
+func (r *APIRunner) Create(stdin []byte, ns string) ([]byte, error) {
+	o, err := r.decode(stdin, ns)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.do("POST", r.path(o.apiVersion, o.kind, o.namespace, ""), "application/json", o.body); err != nil {
+		return nil, err
+	}
+	return status(o.kind, o.name, "created"), nil
+}
+
+// Apply creates an object, or updates it if it already exists.
+//
+// Updates are sent as a JSON merge patch, so fields that are set by the server
+// are preserved.
+func (r *APIRunner) Apply(stdin []byte, ns string) ([]byte, error) {
+	o, err := r.decode(stdin, ns)
+	if err != nil {
+		return nil, err
+	}
+	p := r.path(o.apiVersion, o.kind, o.namespace, o.name)
+	if _, err := r.do("GET", p, "", nil); IsNotFound(err) {
+		if _, err := r.do("POST", r.path(o.apiVersion, o.kind, o.namespace, ""), "application/json", o.body); err != nil {
+			return nil, err
+		}
+		return status(o.kind, o.name, "created"), nil
+	} else if err != nil {
+		return nil, err
+	}
+	if _, err := r.do("PATCH", p, "application/merge-patch+json", o.body); err != nil {
+		return nil, err
+	}
+	return status(o.kind, o.name, "configured"), nil
+}
+
+// Delete removes an object from Kubernetes.
+//
+// Objects owned by the deleted object, such as the pods of a
+// ReplicationController, are deleted by the server's garbage collector if it
+// is enabled.
+//
+// The type is a kind, qualified by its apiVersion if that is not the default.
+// See QualifiedKind. Unknown plain kinds are an error.
+func (r *APIRunner) Delete(name, ktype, ns string) ([]byte, error) {
+	apiVersion, kind, err := typePath(ktype)
+	if err != nil {
+		return nil, err
+	}
+	if ns == "" {
+		ns = r.defaultNamespace()
+	}
+	body := []byte(`{"kind": "DeleteOptions", "apiVersion": "v1", "orphanDependents": false}`)
+	if _, err := r.do("DELETE", r.path(apiVersion, kind, ns, name), "application/json", body); err != nil {
+		return nil, err
+	}
+	return status(kind, name, "deleted"), nil
+}
+
+// Get returns the live state of an object as JSON.
+//
+// If the object does not exist, the error satisfies IsNotFound.
+func (r *APIRunner) Get(stdin []byte, ns string) ([]byte, error) {
+	o, err := r.decode(stdin, ns)
+	if err != nil {
+		return nil, err
+	}
+	return r.do("GET", r.path(o.apiVersion, o.kind, o.namespace, o.name), "", nil)
+}
+
+// List returns every object of the given kinds as a JSON List.
+//
+// If ns is empty, objects in every namespace are listed. The kinds are
+// qualified by their apiVersion if that is not the default. See QualifiedKind.
+// Unknown plain kinds are an error. Kinds whose API the server does not serve
+// have no objects, and are skipped.
+func (r *APIRunner) List(kinds []string, ns string) ([]byte, error) {
+	items := []interface{}{}
+	for _, t := range kinds {
+		apiVersion, kind, err := typePath(t)
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.do("GET", r.path(apiVersion, kind, ns, ""), "", nil)
+		if IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		list := struct {
+			Items []map[string]interface{} `json:"items"`
+		}{}
+		if err := json.Unmarshal(b, &list); err != nil {
+			return nil, err
+		}
+		// The items of a list do not declare their own kind.
+		for _, item := range list.Items {
+			item["kind"] = kind
+			item["apiVersion"] = apiVersion
+			items = append(items, item)
+		}
+	}
+	return json.MarshalIndent(map[string]interface{}{
+		"kind":       "List",
+		"apiVersion": "v1",
+		"items":      items,
+	}, "", "    ")
+}
+
+// decode reads the type and name of an object, and re-encodes it as JSON.
+//
+// The namespace is ns if it is set, and otherwise the object's own namespace or
+// the runner's default namespace.
+func (r *APIRunner) decode(stdin []byte, ns string) (*apiObject, error) {
+	obj, err := codec.YAML.Decode(stdin).One()
+	if err != nil {
+		return nil, err
+	}
+	meta, err := obj.Meta()
+	if err != nil {
+		return nil, err
+	}
+	if meta.Kind == "" || meta.Name == "" {
+		return nil, fmt.Errorf("object must have a kind and a name")
+	}
+	body, err := obj.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	o := &apiObject{
+		apiVersion: meta.APIVersion,
+		kind:       meta.Kind,
+		name:       meta.Name,
+		namespace:  ns,
+		body:       body,
+	}
+	if o.apiVersion == "" {
+		o.apiVersion = defaultAPIVersions[o.kind]
+	}
+	if o.namespace == "" {
+		o.namespace = meta.Namespace
+	}
+	if o.namespace == "" {
+		o.namespace = r.defaultNamespace()
+	}
+	return o, nil
+}
+
+func (r *APIRunner) defaultNamespace() string {
+	if r.Namespace != "" {
+		return r.Namespace
+	}
+	return "default"
+}
+
+// path returns the API path of an object, or of its collection if name is empty.
+//
+// If ns is empty, the path of a collection covers every namespace.
+func (r *APIRunner) path(apiVersion, kind, ns, name string) string {
+	if apiVersion == "" {
+		apiVersion = "v1"
+	}
+	p := "/apis/" + apiVersion
+	if !strings.Contains(apiVersion, "/") {
+		p = "/api/" + apiVersion
+	}
+	if !clusterScoped[kind] && ns != "" {
+		p += "/namespaces/" + ns
+	}
+	p += "/" + resource(kind)
+	if name != "" {
+		p += "/" + name
+	}
+	return p
+}
+
+// resource returns the plural resource name of a kind.
+func resource(kind string) string {
+	r := strings.ToLower(kind)
+	switch {
+	case r == "endpoints":
+		return r
+	case strings.HasSuffix(r, "s"):
+		return r + "es"
+	case strings.HasSuffix(r, "y"):
+		return strings.TrimSuffix(r, "y") + "ies"
+	}
+	return r + "s"
+}
+
+// status formats a message about an object the way kubectl does.
+func status(kind, name, verb string) []byte {
+	return []byte(fmt.Sprintf("%s %q %s\n", strings.ToLower(kind), name, verb))
+}
+
+// do sends a request to the API server, and returns the response body.
+//
+// Responses with an error status are returned as an *APIError.
+func (r *APIRunner) do(method, path, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, r.Server+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	} else if r.Username != "" {
+		req.SetBasicAuth(r.Username, r.Password)
+	}
+
+	client := r.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, apiError(res.StatusCode, b)
+	}
+	return b, nil
+}
+
+// apiError builds an APIError from an error response.
+//
+// The API server describes errors with a Status object. If the response is not
+// a Status, the reason is guessed from the HTTP status code.
+func apiError(code int, body []byte) *APIError {
+	e := &APIError{Code: code}
+	s := struct {
+		Kind    string `json:"kind"`
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	}{}
+	if err := json.Unmarshal(body, &s); err == nil && s.Kind == "Status" {
+		e.Reason = s.Reason
+		e.Message = s.Message
+	}
+
+	if e.Reason == "" {
+		switch code {
+		case http.StatusUnauthorized:
+			e.Reason = ReasonUnauthorized
+		case http.StatusForbidden:
+			e.Reason = ReasonForbidden
+		case http.StatusNotFound:
+			e.Reason = ReasonNotFound
+		case http.StatusConflict:
+			e.Reason = ReasonConflict
+		}
+	}
+	if e.Message == "" {
+		e.Message = fmt.Sprintf("%s: %s", http.StatusText(code), strings.TrimSpace(string(body)))
+	}
+	return e
+}
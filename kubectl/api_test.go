@@ -0,0 +1,324 @@
+package kubectl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeAPI is a minimal Kubernetes API server that stores objects by path.
+type fakeAPI struct {
+	sync.Mutex
+	objects  map[string][]byte
+	requests []string
+	auth     string
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{objects: map[string][]byte{}}
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	f.auth = r.Header.Get("Authorization")
+
+	if r.URL.Path == "/version" {
+		fmt.Fprint(w, `{"gitVersion": "v1.2.4"}`)
+		return
+	}
+	if strings.Contains(r.URL.Path, "/namespaces/forbidden/") {
+		writeStatus(w, http.StatusForbidden, "Forbidden", "access denied")
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	switch r.Method {
+	case "POST":
+		var name struct {
+			Metadata struct{ Name string } `json:"metadata"`
+		}
+		if err := json.Unmarshal(body, &name); err != nil {
+			writeStatus(w, http.StatusBadRequest, "BadRequest", err.Error())
+			return
+		}
+		p := r.URL.Path + "/" + name.Metadata.Name
+		if _, ok := f.objects[p]; ok {
+			writeStatus(w, http.StatusConflict, "AlreadyExists", name.Metadata.Name+" already exists")
+			return
+		}
+		f.objects[p] = body
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	case "GET", "PATCH", "DELETE":
+		obj, ok := f.objects[r.URL.Path]
+		if !ok && r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/pods") {
+			f.list(w, r.URL.Path)
+			return
+		}
+		if !ok {
+			writeStatus(w, http.StatusNotFound, "NotFound", r.URL.Path+" not found")
+			return
+		}
+		if r.Method == "PATCH" {
+			if ct := r.Header.Get("Content-Type"); ct != "application/merge-patch+json" {
+				writeStatus(w, http.StatusUnsupportedMediaType, "", ct)
+				return
+			}
+			obj = body
+			f.objects[r.URL.Path] = obj
+		}
+		if r.Method == "DELETE" {
+			delete(f.objects, r.URL.Path)
+		}
+		w.Write(obj)
+	}
+}
+
+// list writes the pods in a collection, which may cover every namespace.
+func (f *fakeAPI) list(w http.ResponseWriter, collection string) {
+	items := []string{}
+	for p, obj := range f.objects {
+		dir := path.Dir(p)
+		all := regexp.MustCompile(`/namespaces/[^/]+`).ReplaceAllString(dir, "")
+		if dir == collection || all == collection {
+			items = append(items, string(obj))
+		}
+	}
+	fmt.Fprintf(w, `{"kind": "PodList", "items": [%s]}`, strings.Join(items, ","))
+}
+
+func writeStatus(w http.ResponseWriter, code int, reason, msg string) {
+	w.WriteHeader(code)
+	fmt.Fprintf(w, `{"kind": "Status", "status": "Failure", "reason": %q, "message": %q, "code": %d}`, reason, msg, code)
+}
+
+const apiPod = `apiVersion: v1
+kind: Pod
+metadata:
+  name: redis
+spec:
+  containers:
+  - name: redis
+    image: redis
+`
+
+func TestAPIRunner(t *testing.T) {
+	api := newFakeAPI()
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	var client Runner = &APIRunner{Server: srv.URL, Token: "secret"}
+
+	out, err := client.Create([]byte(apiPod), "")
+	if err != nil {
+		t.Fatalf("Create failed: %s", err)
+	}
+	expectString(t, string(out), "pod \"redis\" created\n")
+	expectString(t, api.auth, "Bearer secret")
+	if _, ok := api.objects["/api/v1/namespaces/default/pods/redis"]; !ok {
+		t.Errorf("Expected pod to be created in the default namespace, got %v", api.requests)
+	}
+
+	if _, err := client.Create([]byte(apiPod), ""); !IsAlreadyExists(err) {
+		t.Errorf("Expected AlreadyExists, got %v", err)
+	}
+
+	out, err = client.Get([]byte(apiPod), "default")
+	if err != nil {
+		t.Fatalf("Get failed: %s", err)
+	}
+	if !strings.Contains(string(out), `"image": "redis"`) {
+		t.Errorf("Expected the pod as JSON, got %s", out)
+	}
+
+	out, err = client.Apply([]byte(strings.Replace(apiPod, "image: redis", "image: redis:3", 1)), "")
+	if err != nil {
+		t.Fatalf("Apply failed: %s", err)
+	}
+	expectString(t, string(out), "pod \"redis\" configured\n")
+
+	out, err = client.Delete("redis", "Pod", "")
+	if err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+	expectString(t, string(out), "pod \"redis\" deleted\n")
+
+	if _, err := client.Get([]byte(apiPod), ""); !IsNotFound(err) {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+	if _, err := client.Delete("redis", "Pod", ""); !IsNotFound(err) {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+
+	out, err = client.Apply([]byte(apiPod), "")
+	if err != nil {
+		t.Fatalf("Apply failed: %s", err)
+	}
+	expectString(t, string(out), "pod \"redis\" created\n")
+
+	for _, ns := range []string{"", "default"} {
+		out, err = client.List([]string{"Pod", "Service"}, ns)
+		if err != nil {
+			t.Fatalf("List failed: %s", err)
+		}
+		var list struct {
+			Items []struct {
+				Kind     string
+				Metadata struct{ Name string }
+			}
+		}
+		if err := json.Unmarshal(out, &list); err != nil {
+			t.Fatalf("List did not return JSON: %s", err)
+		}
+		if len(list.Items) != 1 || list.Items[0].Kind != "Pod" || list.Items[0].Metadata.Name != "redis" {
+			t.Errorf("Expected a list with the pod, got %s", out)
+		}
+	}
+	expectString(t, api.requests[len(api.requests)-4], "GET /api/v1/pods")
+	expectString(t, api.requests[len(api.requests)-2], "GET /api/v1/namespaces/default/pods")
+
+	_, err = client.Create([]byte(apiPod), "forbidden")
+	if !IsForbidden(err) {
+		t.Errorf("Expected Forbidden, got %v", err)
+	}
+	if e, ok := err.(*APIError); !ok || e.Code != http.StatusForbidden || e.Message != "access denied" {
+		t.Errorf("Unexpected error %#v", err)
+	}
+
+	out, err = client.ClusterInfo()
+	if err != nil {
+		t.Fatalf("ClusterInfo failed: %s", err)
+	}
+	if !strings.Contains(string(out), "v1.2.4") {
+		t.Errorf("Expected server version, got %s", out)
+	}
+}
+
+func TestAPIPath(t *testing.T) {
+	r := &APIRunner{}
+	tests := []struct {
+		apiVersion, kind, ns, name, expected string
+	}{
+		{"v1", "Pod", "default", "redis", "/api/v1/namespaces/default/pods/redis"},
+		{"v1", "Namespace", "default", "kube-system", "/api/v1/namespaces/kube-system"},
+		{"extensions/v1beta1", "Ingress", "web", "", "/apis/extensions/v1beta1/namespaces/web/ingresses"},
+		{"", "Endpoints", "web", "api", "/api/v1/namespaces/web/endpoints/api"},
+		{"extensions/v1beta1", "NetworkPolicy", "web", "deny", "/apis/extensions/v1beta1/namespaces/web/networkpolicies/deny"},
+		{"v1", "Pod", "", "", "/api/v1/pods"},
+	}
+	for _, tt := range tests {
+		expectString(t, r.path(tt.apiVersion, tt.kind, tt.ns, tt.name), tt.expected)
+	}
+}
+
+const apiStatefulSet = `apiVersion: apps/v1beta1
+kind: StatefulSet
+metadata:
+  name: db
+`
+
+func TestAPIQualifiedKinds(t *testing.T) {
+	api := newFakeAPI()
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	var client Runner = &APIRunner{Server: srv.URL}
+	if _, err := client.Create([]byte(apiStatefulSet), "default"); err != nil {
+		t.Fatalf("Create failed: %s", err)
+	}
+
+	if _, err := client.Delete("db", "StatefulSet", "default"); err == nil || !strings.Contains(err.Error(), "unknown kind \"StatefulSet\"") {
+		t.Errorf("Expected an unknown kind error, got %v", err)
+	}
+	if _, err := client.List([]string{"StatefulSet"}, "default"); err == nil {
+		t.Error("Expected an unknown kind error from List")
+	}
+
+	kind := QualifiedKind("apps/v1beta1", "StatefulSet")
+	expectString(t, kind, "apps/v1beta1/StatefulSet")
+	if _, err := client.Delete("db", kind, "default"); err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+	expectString(t, api.requests[len(api.requests)-1], "DELETE /apis/apps/v1beta1/namespaces/default/statefulsets/db")
+
+	expectString(t, QualifiedKind("v1", "Pod"), "Pod")
+	expectString(t, QualifiedKind("extensions/v1beta1", "Deployment"), "Deployment")
+	if v, k := SplitKind("Pod"); v != "" || k != "Pod" {
+		t.Errorf("Unexpected split of Pod: %q %q", v, k)
+	}
+}
+
+func TestNewAPIRunner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "helmc-kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kc := `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: local
+  cluster:
+    server: https://127.0.0.1:6443/
+    insecure-skip-tls-verify: true
+contexts:
+- name: dev
+  context:
+    cluster: local
+    user: admin
+    namespace: dev
+- name: prod
+  context:
+    cluster: local
+    user: admin
+users:
+- name: admin
+  user:
+    token: abc123
+`
+	path := filepath.Join(dir, "config")
+	if err := ioutil.WriteFile(path, []byte(kc), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewAPIRunner(Options{Kubeconfig: path})
+	if err != nil {
+		t.Fatalf("Failed to load kubeconfig: %s", err)
+	}
+	expectString(t, r.Server, "https://127.0.0.1:6443")
+	expectString(t, r.Namespace, "dev")
+	expectString(t, r.Token, "abc123")
+
+	r, err = NewAPIRunner(Options{Kubeconfig: path, Context: "prod"})
+	if err != nil {
+		t.Fatalf("Failed to load context prod: %s", err)
+	}
+	expectString(t, r.defaultNamespace(), "default")
+
+	if _, err := NewAPIRunner(Options{Kubeconfig: path, Context: "nope"}); err == nil {
+		t.Error("Expected an error for a missing context")
+	}
+
+	expectString(t, ContextNamespace(Options{Kubeconfig: path}), "dev")
+	expectString(t, ContextNamespace(Options{Kubeconfig: path, Context: "prod"}), "default")
+	expectString(t, ContextNamespace(Options{Kubeconfig: filepath.Join(dir, "nope")}), "default")
+}
+
+func expectString(t *testing.T, actual, expected string) {
+	if actual != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
@@ -8,7 +8,7 @@ func (r RealRunner) Apply(stdin []byte, ns string) ([]byte, error) {
 		args = append([]string{"--namespace=" + ns}, args...)
 	}
 
-	cmd := command(args...)
+	cmd := r.command(args...)
 	assignStdin(cmd, stdin)
 
 	return cmd.CombinedOutput()
@@ -22,7 +22,7 @@ func (r PrintRunner) Apply(stdin []byte, ns string) ([]byte, error) {
 		args = append([]string{"--namespace=" + ns}, args...)
 	}
 
-	cmd := command(args...)
+	cmd := r.command(args...)
 	assignStdin(cmd, stdin)
 
 	return []byte(cmd.String()), nil
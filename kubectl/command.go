@@ -12,8 +12,24 @@ type cmd struct {
 	*exec.Cmd
 }
 
-func command(args ...string) *cmd {
-	return &cmd{exec.Command(Path, args...)}
+// command builds a kubectl command, adding the global flags that select a cluster.
+func (o Options) command(args ...string) *cmd {
+	global := []string{}
+	if o.Kubeconfig != "" {
+		global = append(global, "--kubeconfig="+o.Kubeconfig)
+	}
+	if o.Context != "" {
+		global = append(global, "--context="+o.Context)
+	}
+	return &cmd{exec.Command(o.Binary(), append(global, args...)...)}
+}
+
+// Binary returns the path of the kubectl binary.
+func (o Options) Binary() string {
+	if o.Path != "" {
+		return o.Path
+	}
+	return Path
 }
 
 func assignStdin(cmd *cmd, in []byte) {
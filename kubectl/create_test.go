@@ -20,3 +20,24 @@ func TestPrintCreate(t *testing.T) {
 		t.Fatalf("actual %s != expected %s", actual, expected)
 	}
 }
+
+func TestPrintCreateWithOptions(t *testing.T) {
+	var client Runner = PrintRunner{Options{
+		Path:       "/opt/bin/kubectl",
+		Kubeconfig: "/etc/kube/config",
+		Context:    "staging",
+	}}
+
+	expected := `[CMD] /opt/bin/kubectl --kubeconfig=/etc/kube/config --context=staging --namespace=default-namespace create -f - < some stdin data`
+
+	out, err := client.Create([]byte("some stdin data"), "default-namespace")
+	if err != nil {
+		t.Error(err)
+	}
+
+	actual := string(out)
+
+	if expected != actual {
+		t.Fatalf("actual %s != expected %s", actual, expected)
+	}
+}
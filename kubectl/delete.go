@@ -1,25 +1,27 @@
 package kubectl
 
 // Delete removes a chart from Kubernetes.
+//
+// The apiVersion of a qualified type is dropped, since kubectl finds it.
 func (r RealRunner) Delete(name, ktype, ns string) ([]byte, error) {
-
-	args := []string{"delete", ktype, name}
+	_, kind := SplitKind(ktype)
+	args := []string{"delete", kind, name}
 
 	if ns != "" {
 		args = append([]string{"--namespace=" + ns}, args...)
 	}
-	return command(args...).CombinedOutput()
+	return r.command(args...).CombinedOutput()
 }
 
 // Delete returns the commands to kubectl
 func (r PrintRunner) Delete(name, ktype, ns string) ([]byte, error) {
-
-	args := []string{"delete", ktype, name}
+	_, kind := SplitKind(ktype)
+	args := []string{"delete", kind, name}
 
 	if ns != "" {
 		args = append([]string{"--namespace=" + ns}, args...)
 	}
 
-	cmd := command(args...)
+	cmd := r.command(args...)
 	return []byte(cmd.String()), nil
 }
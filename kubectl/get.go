@@ -1,13 +1,13 @@
 package kubectl
 
-// Get returns Kubernetes resources
+// Get returns Kubernetes resources as JSON
 func (r RealRunner) Get(stdin []byte, ns string) ([]byte, error) {
-	args := []string{"get", "-f", "-"}
+	args := []string{"get", "-f", "-", "-o", "json"}
 
 	if ns != "" {
 		args = append([]string{"--namespace=" + ns}, args...)
 	}
-	cmd := command(args...)
+	cmd := r.command(args...)
 	assignStdin(cmd, stdin)
 
 	return cmd.CombinedOutput()
@@ -15,12 +15,12 @@ func (r RealRunner) Get(stdin []byte, ns string) ([]byte, error) {
 
 // Get returns the commands to kubectl
 func (r PrintRunner) Get(stdin []byte, ns string) ([]byte, error) {
-	args := []string{"get", "-f", "-"}
+	args := []string{"get", "-f", "-", "-o", "json"}
 
 	if ns != "" {
 		args = append([]string{"--namespace=" + ns}, args...)
 	}
-	cmd := command(args...)
+	cmd := r.command(args...)
 	assignStdin(cmd, stdin)
 
 	return []byte(cmd.String()), nil
@@ -15,3 +15,20 @@ func TestGet(t *testing.T) {
 		t.Errorf("%s != %s", string(out), expects)
 	}
 }
+
+func TestPrintGet(t *testing.T) {
+	var client Runner = PrintRunner{}
+
+	expected := `[CMD] kubectl --namespace=default-namespace get -f - -o json < some stdin data`
+
+	out, err := client.Get([]byte("some stdin data"), "default-namespace")
+	if err != nil {
+		t.Error(err)
+	}
+
+	actual := string(out)
+
+	if expected != actual {
+		t.Fatalf("actual %s != expected %s", actual, expected)
+	}
+}
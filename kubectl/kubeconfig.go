@@ -0,0 +1,212 @@
+package kubectl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// Kubeconfig is the subset of a kubectl configuration file that is needed to
+// connect to a cluster.
+type Kubeconfig struct {
+	CurrentContext string         `json:"current-context"`
+	Clusters       []namedCluster `json:"clusters"`
+	Contexts       []namedContext `json:"contexts"`
+	Users          []namedUser    `json:"users"`
+
+	// dir is the directory of the file, which relative paths are resolved against.
+	dir string
+}
+
+type namedCluster struct {
+	Name    string  `json:"name"`
+	Cluster cluster `json:"cluster"`
+}
+
+type cluster struct {
+	Server                   string `json:"server"`
+	CertificateAuthority     string `json:"certificate-authority"`
+	CertificateAuthorityData string `json:"certificate-authority-data"`
+	InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify"`
+}
+
+type namedContext struct {
+	Name    string      `json:"name"`
+	Context contextInfo `json:"context"`
+}
+
+type contextInfo struct {
+	Cluster   string `json:"cluster"`
+	User      string `json:"user"`
+	Namespace string `json:"namespace"`
+}
+
+type namedUser struct {
+	Name string `json:"name"`
+	User user   `json:"user"`
+}
+
+type user struct {
+	Token                 string `json:"token"`
+	ClientCertificate     string `json:"client-certificate"`
+	ClientCertificateData string `json:"client-certificate-data"`
+	ClientKey             string `json:"client-key"`
+	ClientKeyData         string `json:"client-key-data"`
+	Username              string `json:"username"`
+	Password              string `json:"password"`
+}
+
+// KubeconfigPath returns the path of the kubectl configuration file.
+//
+// This is the first entry in $KUBECONFIG, or ~/.kube/config.
+func KubeconfigPath() string {
+	if kc := os.Getenv("KUBECONFIG"); kc != "" {
+		return filepath.SplitList(kc)[0]
+	}
+	return filepath.Join(os.Getenv("HOME"), ".kube", "config")
+}
+
+// LoadKubeconfig reads a kubectl configuration file.
+func LoadKubeconfig(path string) (*Kubeconfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	kc := &Kubeconfig{dir: filepath.Dir(path)}
+	if err := yaml.Unmarshal(b, kc); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %s", path, err)
+	}
+	return kc, nil
+}
+
+// ContextNamespace returns the namespace that kubectl uses when none is given:
+// the namespace of the kubeconfig context selected by opts, or "default" if
+// the context does not set one or the file cannot be read.
+func ContextNamespace(opts Options) string {
+	path := opts.Kubeconfig
+	if path == "" {
+		path = KubeconfigPath()
+	}
+	kc, err := LoadKubeconfig(path)
+	if err != nil {
+		return "default"
+	}
+	name := opts.Context
+	if name == "" {
+		name = kc.CurrentContext
+	}
+	for _, c := range kc.Contexts {
+		if c.Name == name && c.Context.Namespace != "" {
+			return c.Context.Namespace
+		}
+	}
+	return "default"
+}
+
+// Runner returns an APIRunner for the named context.
+//
+// If contextName is empty, the current context is used.
+func (kc *Kubeconfig) Runner(contextName string) (*APIRunner, error) {
+	if contextName == "" {
+		contextName = kc.CurrentContext
+	}
+	if contextName == "" {
+		return nil, fmt.Errorf("no current context is set")
+	}
+
+	var ctx *contextInfo
+	for i := range kc.Contexts {
+		if kc.Contexts[i].Name == contextName {
+			ctx = &kc.Contexts[i].Context
+		}
+	}
+	if ctx == nil {
+		return nil, fmt.Errorf("no context named %q", contextName)
+	}
+
+	var cl *cluster
+	for i := range kc.Clusters {
+		if kc.Clusters[i].Name == ctx.Cluster {
+			cl = &kc.Clusters[i].Cluster
+		}
+	}
+	if cl == nil || cl.Server == "" {
+		return nil, fmt.Errorf("context %q has no cluster server", contextName)
+	}
+
+	u := &user{}
+	for i := range kc.Users {
+		if kc.Users[i].Name == ctx.User {
+			u = &kc.Users[i].User
+		}
+	}
+
+	tc, err := kc.tlsConfig(cl, u)
+	if err != nil {
+		return nil, err
+	}
+
+	return &APIRunner{
+		Server:    strings.TrimSuffix(cl.Server, "/"),
+		Namespace: ctx.Namespace,
+		Token:     u.Token,
+		Username:  u.Username,
+		Password:  u.Password,
+		HTTP:      &http.Client{Transport: &http.Transport{TLSClientConfig: tc, Proxy: http.ProxyFromEnvironment}},
+	}, nil
+}
+
+// tlsConfig builds the TLS configuration for a cluster and user.
+func (kc *Kubeconfig) tlsConfig(cl *cluster, u *user) (*tls.Config, error) {
+	tc := &tls.Config{InsecureSkipVerify: cl.InsecureSkipTLSVerify}
+
+	ca, err := kc.data(cl.CertificateAuthorityData, cl.CertificateAuthority)
+	if err != nil {
+		return nil, fmt.Errorf("could not read certificate authority: %s", err)
+	}
+	if len(ca) > 0 {
+		tc.RootCAs = x509.NewCertPool()
+		if !tc.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in certificate authority")
+		}
+	}
+
+	cert, err := kc.data(u.ClientCertificateData, u.ClientCertificate)
+	if err != nil {
+		return nil, fmt.Errorf("could not read client certificate: %s", err)
+	}
+	key, err := kc.data(u.ClientKeyData, u.ClientKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not read client key: %s", err)
+	}
+	if len(cert) > 0 {
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %s", err)
+		}
+		tc.Certificates = []tls.Certificate{pair}
+	}
+	return tc, nil
+}
+
+// data returns inline base64 data if it is set, and otherwise reads the file.
+func (kc *Kubeconfig) data(inline, file string) ([]byte, error) {
+	if inline != "" {
+		return base64.StdEncoding.DecodeString(inline)
+	}
+	if file == "" {
+		return nil, nil
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(kc.dir, file)
+	}
+	return ioutil.ReadFile(file)
+}
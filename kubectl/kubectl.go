@@ -1,8 +1,18 @@
 package kubectl
 
-// Path is the path of the kubectl binary
+// Path is the path of the kubectl binary, unless Options.Path is set
 var Path = "kubectl"
 
+// Options select the kubectl binary and the cluster that it talks to.
+type Options struct {
+	// Path is the path of the kubectl binary.
+	Path string
+	// Kubeconfig is the path of the kubectl configuration file.
+	Kubeconfig string
+	// Context is the name of the kubeconfig context to use.
+	Context string
+}
+
 // Runner is an interface to wrap kubectl convenience methods
 type Runner interface {
 	// ClusterInfo returns Kubernetes cluster info
@@ -11,17 +21,26 @@ type Runner interface {
 	Apply([]byte, string) ([]byte, error)
 	// Create uploads a chart to Kubernetes
 	Create([]byte, string) ([]byte, error)
-	// Delete removes a chart from Kubernetes.
+	// Delete removes a chart from Kubernetes. The kind is qualified by its
+	// apiVersion if that is not the default. See QualifiedKind.
 	Delete(string, string, string) ([]byte, error)
-	// Get returns Kubernetes resources
+	// Get returns Kubernetes resources as JSON
 	Get([]byte, string) ([]byte, error)
+	// List returns every object of the given kinds in a namespace as a JSON
+	// List. If the namespace is empty, every namespace is searched. The kinds
+	// are qualified like those passed to Delete.
+	List([]string, string) ([]byte, error)
 }
 
 // RealRunner implements Runner to execute kubectl commands
-type RealRunner struct{}
+type RealRunner struct {
+	Options
+}
 
 // PrintRunner implements Runner to return a []byte of the command to be executed
-type PrintRunner struct{}
+type PrintRunner struct {
+	Options
+}
 
 // Client stores the instance of Runner
 var Client Runner = RealRunner{}
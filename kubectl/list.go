@@ -0,0 +1,41 @@
+package kubectl
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// List returns the objects of the given kinds as a JSON List.
+//
+// If ns is empty, objects in every namespace are listed.
+func (r RealRunner) List(kinds []string, ns string) ([]byte, error) {
+	out, err := r.command(listArgs(kinds, ns)...).Output()
+	if e, ok := err.(*exec.ExitError); ok && len(e.Stderr) > 0 {
+		return out, fmt.Errorf("%s: %s", err, strings.TrimSpace(string(e.Stderr)))
+	}
+	return out, err
+}
+
+// List returns the commands to kubectl
+func (r PrintRunner) List(kinds []string, ns string) ([]byte, error) {
+	cmd := r.command(listArgs(kinds, ns)...)
+	return []byte(cmd.String()), nil
+}
+
+// listArgs drops the apiVersion of qualified types, since kubectl finds it.
+func listArgs(kinds []string, ns string) []string {
+	plain := []string{}
+	seen := map[string]bool{}
+	for _, t := range kinds {
+		if _, kind := SplitKind(t); !seen[kind] {
+			seen[kind] = true
+			plain = append(plain, kind)
+		}
+	}
+	args := []string{"get", strings.ToLower(strings.Join(plain, ",")), "-o", "json"}
+	if ns == "" {
+		return append(args, "--all-namespaces")
+	}
+	return append([]string{"--namespace=" + ns}, args...)
+}
@@ -0,0 +1,24 @@
+package kubectl
+
+import (
+	"testing"
+)
+
+func TestPrintList(t *testing.T) {
+	var client Runner = PrintRunner{}
+
+	expected := `[CMD] kubectl --namespace=default get pod,service -o json `
+	out, err := client.List([]string{"Pod", "Service", "v2/Service"}, "default")
+	if err != nil {
+		t.Error(err)
+	}
+	if actual := string(out); expected != actual {
+		t.Fatalf("actual %s != expected %s", actual, expected)
+	}
+
+	expected = `[CMD] kubectl get pod -o json --all-namespaces `
+	out, _ = client.List([]string{"Pod"}, "")
+	if actual := string(out); expected != actual {
+		t.Fatalf("actual %s != expected %s", actual, expected)
+	}
+}
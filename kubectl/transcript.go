@@ -0,0 +1,292 @@
+package kubectl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/helm/helm-classic/codec"
+)
+
+// Call is one entry in a transcript of Runner calls.
+type Call struct {
+	// Method is the name of the Runner method, such as "Create".
+	Method string `json:"method"`
+	// Namespace is the namespace that was passed to the method.
+	Namespace string `json:"namespace,omitempty"`
+	// Kind and Name identify the object that was deleted, for Delete calls.
+	// For List calls, Kind is a comma-separated list of the kinds.
+	Kind string `json:"kind,omitempty"`
+	Name string `json:"name,omitempty"`
+	// Stdin is the payload that was passed to the method.
+	Stdin string `json:"stdin,omitempty"`
+	// Output is the output of the method.
+	Output string `json:"output"`
+	// Error is the message of the error that was returned, if any.
+	Error string `json:"error,omitempty"`
+	// Reason is the reason of an *APIError, if one was returned.
+	Reason string `json:"reason,omitempty"`
+	// Time is when the call was made, formatted as RFC 3339.
+	Time string `json:"time,omitempty"`
+	// Duration is how long the call took.
+	Duration string `json:"duration,omitempty"`
+}
+
+// Recorder is a Runner that passes every call on to another Runner, and writes
+// each call to a transcript as a line of JSON.
+//
+// The values in the data and stringData of Secrets are replaced by
+// RedactedValue, both in payloads and in output. Other objects are recorded
+// verbatim, so a transcript may still contain sensitive content.
+type Recorder struct {
+	Runner
+
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder creates a Recorder that wraps r, and writes its transcript to w.
+func NewRecorder(r Runner, w io.Writer) *Recorder {
+	return &Recorder{Runner: r, enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error that occurred while writing the transcript.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// ClusterInfo records a call to ClusterInfo.
+func (r *Recorder) ClusterInfo() ([]byte, error) {
+	c := &Call{Method: "ClusterInfo"}
+	return r.record(c, r.Runner.ClusterInfo)
+}
+
+// Apply records a call to Apply.
+func (r *Recorder) Apply(stdin []byte, ns string) ([]byte, error) {
+	c := &Call{Method: "Apply", Namespace: ns, Stdin: redactSecrets(stdin)}
+	return r.record(c, func() ([]byte, error) { return r.Runner.Apply(stdin, ns) })
+}
+
+// Create records a call to Create.
+func (r *Recorder) Create(stdin []byte, ns string) ([]byte, error) {
+	c := &Call{Method: "Create", Namespace: ns, Stdin: redactSecrets(stdin)}
+	return r.record(c, func() ([]byte, error) { return r.Runner.Create(stdin, ns) })
+}
+
+// Delete records a call to Delete.
+func (r *Recorder) Delete(name, ktype, ns string) ([]byte, error) {
+	c := &Call{Method: "Delete", Namespace: ns, Kind: ktype, Name: name}
+	return r.record(c, func() ([]byte, error) { return r.Runner.Delete(name, ktype, ns) })
+}
+
+// Get records a call to Get.
+func (r *Recorder) Get(stdin []byte, ns string) ([]byte, error) {
+	c := &Call{Method: "Get", Namespace: ns, Stdin: redactSecrets(stdin)}
+	return r.record(c, func() ([]byte, error) { return r.Runner.Get(stdin, ns) })
+}
+
+// List records a call to List.
+func (r *Recorder) List(kinds []string, ns string) ([]byte, error) {
+	c := &Call{Method: "List", Namespace: ns, Kind: strings.Join(kinds, ",")}
+	return r.record(c, func() ([]byte, error) { return r.Runner.List(kinds, ns) })
+}
+
+// record runs fn, and writes the call and its result to the transcript.
+func (r *Recorder) record(c *Call, fn func() ([]byte, error)) ([]byte, error) {
+	start := time.Now()
+	out, err := fn()
+	c.Time = start.UTC().Format(time.RFC3339)
+	c.Duration = time.Since(start).String()
+	c.Output = redactSecrets(out)
+	if err != nil {
+		c.Error = err.Error()
+		if e, ok := err.(*APIError); ok {
+			c.Reason = e.Reason
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if werr := r.enc.Encode(c); werr != nil && r.err == nil {
+		r.err = werr
+	}
+	return out, err
+}
+
+// RedactedValue replaces the values of Secrets in a transcript.
+const RedactedValue = "REDACTED"
+
+// redactSecrets returns data as a string, with the values of a Secret, or of
+// the Secrets in a List, replaced by RedactedValue.
+//
+// Anything that is not a Secret is returned verbatim. A Secret is re-encoded
+// as JSON, which keeps its kind and name for matching during replay.
+func redactSecrets(data []byte) string {
+	if !strings.Contains(string(data), "Secret") {
+		return string(data)
+	}
+	obj, err := codec.YAML.Decode(data).One()
+	if err != nil {
+		return string(data)
+	}
+	b, err := obj.JSON()
+	if err != nil {
+		return string(data)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return string(data)
+	}
+
+	redacted := redactSecret(m)
+	if items, ok := m["items"].([]interface{}); ok {
+		for _, item := range items {
+			if o, ok := item.(map[string]interface{}); ok && redactSecret(o) {
+				redacted = true
+			}
+		}
+	}
+	if !redacted {
+		return string(data)
+	}
+	b, err = json.MarshalIndent(m, "", "    ")
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// redactSecret replaces the values in the data and stringData of a Secret. It
+// returns true if obj is a Secret.
+func redactSecret(obj map[string]interface{}) bool {
+	if obj["kind"] != "Secret" {
+		return false
+	}
+	for _, field := range []string{"data", "stringData"} {
+		values, _ := obj[field].(map[string]interface{})
+		for k := range values {
+			values[k] = RedactedValue
+		}
+	}
+	return true
+}
+
+// Replayer is a Runner that serves responses from a transcript written by a
+// Recorder.
+//
+// Each call is answered by the first unused entry in the transcript with the
+// same method and namespace, for the same object. Objects are matched by kind
+// and name rather than by their full payload, so that a replay does not depend
+// on details such as file paths in annotations. A call that has no matching
+// entry returns an error.
+type Replayer struct {
+	mu    sync.Mutex
+	calls []*Call
+	used  []bool
+}
+
+// NewReplayer reads a transcript and creates a Replayer for it.
+func NewReplayer(transcript io.Reader) (*Replayer, error) {
+	r := &Replayer{}
+	s := bufio.NewScanner(transcript)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; s.Scan(); line++ {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		c := &Call{}
+		if err := json.Unmarshal(s.Bytes(), c); err != nil {
+			return nil, fmt.Errorf("transcript line %d: %s", line, err)
+		}
+		r.calls = append(r.calls, c)
+	}
+	r.used = make([]bool, len(r.calls))
+	return r, s.Err()
+}
+
+// Remaining returns the transcript entries that have not been replayed.
+func (r *Replayer) Remaining() []*Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := []*Call{}
+	for i, c := range r.calls {
+		if !r.used[i] {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// ClusterInfo replays a call to ClusterInfo.
+func (r *Replayer) ClusterInfo() ([]byte, error) {
+	return r.replay(&Call{Method: "ClusterInfo"})
+}
+
+// Apply replays a call to Apply.
+func (r *Replayer) Apply(stdin []byte, ns string) ([]byte, error) {
+	return r.replay(&Call{Method: "Apply", Namespace: ns, Stdin: string(stdin)})
+}
+
+// Create replays a call to Create.
+func (r *Replayer) Create(stdin []byte, ns string) ([]byte, error) {
+	return r.replay(&Call{Method: "Create", Namespace: ns, Stdin: string(stdin)})
+}
+
+// Delete replays a call to Delete.
+func (r *Replayer) Delete(name, ktype, ns string) ([]byte, error) {
+	return r.replay(&Call{Method: "Delete", Namespace: ns, Kind: ktype, Name: name})
+}
+
+// Get replays a call to Get.
+func (r *Replayer) Get(stdin []byte, ns string) ([]byte, error) {
+	return r.replay(&Call{Method: "Get", Namespace: ns, Stdin: string(stdin)})
+}
+
+// List replays a call to List.
+func (r *Replayer) List(kinds []string, ns string) ([]byte, error) {
+	return r.replay(&Call{Method: "List", Namespace: ns, Kind: strings.Join(kinds, ",")})
+}
+
+// replay finds the first unused entry that matches c, and returns its result.
+func (r *Replayer) replay(c *Call) ([]byte, error) {
+	want := c.key()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, rc := range r.calls {
+		if r.used[i] || rc.key() != want {
+			continue
+		}
+		r.used[i] = true
+		if rc.Error == "" {
+			return []byte(rc.Output), nil
+		}
+		if rc.Reason != "" {
+			return []byte(rc.Output), &APIError{Reason: rc.Reason, Message: rc.Error}
+		}
+		return []byte(rc.Output), errors.New(rc.Error)
+	}
+	return nil, fmt.Errorf("no recorded call matches %s", want)
+}
+
+// key identifies the object and operation of a call.
+func (c *Call) key() string {
+	kind, name := c.Kind, c.Name
+	if c.Stdin != "" {
+		kind, name = "", c.Stdin
+		if obj, err := codec.YAML.Decode([]byte(c.Stdin)).One(); err == nil {
+			if m, err := obj.Meta(); err == nil && m.Name != "" {
+				kind, name = m.Kind, m.Name
+			}
+		}
+	}
+	return fmt.Sprintf("%s %s/%s in namespace %q", c.Method, kind, name, c.Namespace)
+}
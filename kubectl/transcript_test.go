@@ -0,0 +1,131 @@
+package kubectl
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// fakeRunner returns fixed output for every call.
+type fakeRunner struct {
+	out []byte
+	err error
+}
+
+func (r fakeRunner) ClusterInfo() ([]byte, error)                   { return r.out, r.err }
+func (r fakeRunner) Apply(stdin []byte, ns string) ([]byte, error)  { return r.out, r.err }
+func (r fakeRunner) Create(stdin []byte, ns string) ([]byte, error) { return r.out, r.err }
+func (r fakeRunner) Delete(name, ktype, ns string) ([]byte, error)  { return r.out, r.err }
+func (r fakeRunner) Get(stdin []byte, ns string) ([]byte, error)    { return r.out, r.err }
+func (r fakeRunner) List(kinds []string, ns string) ([]byte, error) { return r.out, r.err }
+
+const transcriptPod = `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "redis"}}`
+
+func TestRecorder(t *testing.T) {
+	var buf bytes.Buffer
+
+	rec := NewRecorder(fakeRunner{out: []byte(`pod "redis" created`)}, &buf)
+	if _, err := rec.Create([]byte(transcriptPod), "default"); err != nil {
+		t.Fatal(err)
+	}
+	rec.Runner = fakeRunner{err: &APIError{Reason: ReasonNotFound, Message: `pods "redis" not found`}}
+	if _, err := rec.Delete("redis", "Pod", "default"); !IsNotFound(err) {
+		t.Fatalf("Expected the error to be passed through, got %v", err)
+	}
+	if err := rec.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", buf.String())
+	}
+	c := &Call{}
+	if err := json.Unmarshal([]byte(lines[0]), c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Method != "Create" || c.Namespace != "default" || c.Stdin != transcriptPod || c.Output != `pod "redis" created` || c.Duration == "" {
+		t.Errorf("Unexpected call %+v", c)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Method != "Delete" || c.Kind != "Pod" || c.Reason != ReasonNotFound {
+		t.Errorf("Unexpected call %+v", c)
+	}
+
+	// Replaying the transcript gives the same results.
+	rep, err := NewReplayer(&buf)
+	if err != nil {
+		t.Fatalf("Failed to read transcript: %s", err)
+	}
+	if _, err := rep.Delete("redis", "Pod", "other"); err == nil {
+		t.Error("Expected a call in another namespace not to match")
+	}
+	// The payload may differ, as long as it describes the same object.
+	out, err := rep.Create([]byte(strings.Replace(transcriptPod, "{", `{"spec": {},`, 1)), "default")
+	if err != nil || string(out) != `pod "redis" created` {
+		t.Errorf("Unexpected replay of Create: %q, %v", out, err)
+	}
+	if _, err := rep.Delete("redis", "Pod", "default"); !IsNotFound(err) {
+		t.Errorf("Expected a NotFound error, got %v", err)
+	}
+	if _, err := rep.Delete("redis", "Pod", "default"); err == nil {
+		t.Error("Expected each call to be replayed once")
+	}
+	if n := len(rep.Remaining()); n != 0 {
+		t.Errorf("Expected every call to be replayed, %d remain", n)
+	}
+}
+
+func TestReplayerPlainError(t *testing.T) {
+	rep, err := NewReplayer(strings.NewReader(`{"method": "ClusterInfo", "output": "nope", "error": "exit status 1"}` + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := rep.ClusterInfo()
+	if err == nil || err.Error() != "exit status 1" || string(out) != "nope" {
+		t.Errorf("Unexpected replay: %q, %v", out, err)
+	}
+	if _, ok := err.(*APIError); ok {
+		t.Error("Expected a plain error")
+	}
+}
+
+const transcriptSecret = `apiVersion: v1
+kind: Secret
+metadata:
+  name: creds
+data:
+  password: aHVudGVyMg==
+stringData:
+  token: s3cr3t
+`
+
+func TestRecorderRedactsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+
+	list := `{"kind": "List", "items": [{"kind": "Secret", "metadata": {"name": "creds"}, "data": {"password": "aHVudGVyMg=="}}]}`
+	rec := NewRecorder(fakeRunner{out: []byte(list)}, &buf)
+	if _, err := rec.Create([]byte(transcriptSecret), "default"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rec.List([]string{"Secret"}, "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"aHVudGVyMg==", "s3cr3t"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("Expected %q to be redacted from %s", secret, buf.String())
+		}
+	}
+
+	rep, err := NewReplayer(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rep.Create([]byte(transcriptSecret), "default"); err != nil {
+		t.Errorf("Expected the redacted Secret to be replayed, got %s", err)
+	}
+}
@@ -0,0 +1,295 @@
+// Package provenance signs charts and verifies their signatures.
+package provenance
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/helm/helm-classic/chart"
+	"golang.org/x/crypto/ed25519"
+	"gopkg.in/yaml.v2"
+)
+
+// SignatureFile is the name of the file, next to Chart.yaml, that holds a
+// chart's signature.
+const SignatureFile = "Chart.sig"
+
+// Algorithm is the signature algorithm.
+const Algorithm = "ed25519"
+
+// PublicKeyExt is the extension of the public key files in a keyring.
+const PublicKeyExt = ".pub"
+
+const (
+	privatePEM = "ED25519 PRIVATE KEY"
+	publicPEM  = "ED25519 PUBLIC KEY"
+)
+
+// ErrUnsigned indicates that a chart has no signature.
+var ErrUnsigned = errors.New("chart is not signed")
+
+// Signature is the signature of a chart.
+type Signature struct {
+	// Algorithm is the signature algorithm. Only Algorithm is supported.
+	Algorithm string `yaml:"algorithm"`
+	// Key is the name of the key that made the signature.
+	Key string `yaml:"key"`
+	// Digest is the digest of the chart that was signed. See Digest.
+	Digest string `yaml:"digest"`
+	// Signature is the base64-encoded signature of Digest.
+	Signature string `yaml:"signature"`
+}
+
+// Key is a named signing key. Keys loaded from a keyring only have the public
+// half.
+type Key struct {
+	Name    string
+	Public  ed25519.PublicKey
+	Private ed25519.PrivateKey
+}
+
+// Keyring is a set of trusted public keys.
+type Keyring []*Key
+
+// Digest computes the digest of a chart in dir, as "sha256:HEX".
+//
+// The digest covers every file in the chart except the signature itself, by
+// path and content, in sorted order. A symbolic link is covered by the path
+// that it points to.
+//
+// Chart.yaml is digested in a canonical form, as the chart was before it was
+// fetched: the local name and the origin that fetching records are undone.
+// This way, a chart can be verified both in a table and in the workspace.
+func Digest(dir string) (string, error) {
+	files := []string{}
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel = filepath.ToSlash(rel); rel != SignatureFile {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, f := range files {
+		var sum string
+		if f == "Chart.yaml" {
+			sum, err = chartfileDigest(filepath.Join(dir, f))
+		} else {
+			sum, err = fileDigest(filepath.Join(dir, filepath.FromSlash(f)))
+		}
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%s\n", f, sum)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// chartfileDigest digests the canonical form of a Chart.yaml.
+func chartfileDigest(filename string) (string, error) {
+	cf, err := chart.LoadChartfile(filename)
+	if err != nil {
+		return "", err
+	}
+	if cf.From != nil {
+		cf.Name = cf.From.Name
+		cf.From = nil
+	}
+	data, err := yaml.Marshal(cf)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// fileDigest digests the content of a file, or the target of a symbolic link.
+func fileDigest(filename string) (string, error) {
+	if fi, err := os.Lstat(filename); err != nil {
+		return "", err
+	} else if fi.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(filename)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256([]byte("symlink:" + target))
+		return hex.EncodeToString(sum[:]), nil
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Sign signs the chart in dir with a private key, and writes the signature
+// to the chart's SignatureFile.
+func Sign(dir string, k *Key) (*Signature, error) {
+	if k.Private == nil {
+		return nil, fmt.Errorf("key %s has no private key", k.Name)
+	}
+	d, err := Digest(dir)
+	if err != nil {
+		return nil, err
+	}
+	sig := &Signature{
+		Algorithm: Algorithm,
+		Key:       k.Name,
+		Digest:    d,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(k.Private, []byte(d))),
+	}
+	data, err := yaml.Marshal(sig)
+	if err != nil {
+		return nil, err
+	}
+	return sig, ioutil.WriteFile(filepath.Join(dir, SignatureFile), data, 0644)
+}
+
+// Verify checks that the chart in dir is signed by a key in the keyring, and
+// that it has not changed since it was signed.
+//
+// If the chart has no signature, ErrUnsigned is returned.
+func Verify(dir string, ring Keyring) (*Signature, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, SignatureFile))
+	if os.IsNotExist(err) {
+		return nil, ErrUnsigned
+	} else if err != nil {
+		return nil, err
+	}
+	sig := &Signature{}
+	if err := yaml.Unmarshal(data, sig); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %s", SignatureFile, err)
+	}
+	if sig.Algorithm != Algorithm {
+		return sig, fmt.Errorf("unsupported signature algorithm %q", sig.Algorithm)
+	}
+
+	var key *Key
+	for _, k := range ring {
+		if k.Name == sig.Key {
+			key = k
+			break
+		}
+	}
+	if key == nil {
+		return sig, fmt.Errorf("signed by %q, which is not in the keyring", sig.Key)
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil || !ed25519.Verify(key.Public, []byte(sig.Digest), raw) {
+		return sig, fmt.Errorf("signature by %q is not valid", sig.Key)
+	}
+
+	d, err := Digest(dir)
+	if err != nil {
+		return sig, err
+	}
+	if d != sig.Digest {
+		return sig, fmt.Errorf("chart has changed since it was signed by %q", sig.Key)
+	}
+	return sig, nil
+}
+
+// GenerateKey creates a new signing key.
+func GenerateKey(name string) (*Key, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{Name: name, Public: pub, Private: priv}, nil
+}
+
+// SavePrivate writes the private key to a file that only its owner can read.
+func (k *Key) SavePrivate(filename string) error {
+	return writePEM(filename, privatePEM, k.Name, k.Private, 0600)
+}
+
+// SavePublic writes the public key to a file.
+func (k *Key) SavePublic(filename string) error {
+	return writePEM(filename, publicPEM, k.Name, k.Public, 0644)
+}
+
+func writePEM(filename, typ, name string, data []byte, mode os.FileMode) error {
+	b := &pem.Block{Type: typ, Headers: map[string]string{"Name": name}, Bytes: data}
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(f, b); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadKey reads a public or private key file.
+func LoadKey(filename string) (*Key, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	b, _ := pem.Decode(data)
+	if b == nil {
+		return nil, fmt.Errorf("%s is not a key file", filename)
+	}
+	k := &Key{Name: b.Headers["Name"]}
+	if k.Name == "" {
+		return nil, fmt.Errorf("%s has no key name", filename)
+	}
+	switch {
+	case b.Type == privatePEM && len(b.Bytes) == ed25519.PrivateKeySize:
+		k.Private = ed25519.PrivateKey(b.Bytes)
+		k.Public = k.Private.Public().(ed25519.PublicKey)
+	case b.Type == publicPEM && len(b.Bytes) == ed25519.PublicKeySize:
+		k.Public = ed25519.PublicKey(b.Bytes)
+	default:
+		return nil, fmt.Errorf("%s is not an %s key", filename, Algorithm)
+	}
+	return k, nil
+}
+
+// LoadKeyring loads the public keys in dir. If dir does not exist, the
+// keyring is empty.
+func LoadKeyring(dir string) (Keyring, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+PublicKeyExt))
+	if err != nil {
+		return nil, err
+	}
+	ring := Keyring{}
+	for _, f := range files {
+		k, err := LoadKey(f)
+		if err != nil {
+			return nil, err
+		}
+		ring = append(ring, &Key{Name: k.Name, Public: k.Public})
+	}
+	return ring, nil
+}
@@ -0,0 +1,110 @@
+package provenance
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeChart(t *testing.T, dir, chartfile string) {
+	if err := os.MkdirAll(filepath.Join(dir, "manifests"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"Chart.yaml":         chartfile,
+		"manifests/pod.yaml": "kind: Pod\n",
+		"README.md":          "A chart.\n",
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "helmc-provenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	dir := filepath.Join(tmp, "redis")
+	writeChart(t, dir, "name: redis\nversion: 1.0.0\n")
+
+	k, err := GenerateKey("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := k.SavePrivate(filepath.Join(tmp, "alice.key")); err != nil {
+		t.Fatal(err)
+	}
+	keyring := filepath.Join(tmp, "keyring")
+	os.Mkdir(keyring, 0755)
+	if err := k.SavePublic(filepath.Join(keyring, "alice"+PublicKeyExt)); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.SavePublic(filepath.Join(keyring, "alice"+PublicKeyExt)); err == nil {
+		t.Error("Expected an existing key not to be overwritten")
+	}
+
+	if _, err := Verify(dir, nil); err != ErrUnsigned {
+		t.Errorf("Expected ErrUnsigned, got %v", err)
+	}
+
+	signer, err := LoadKey(filepath.Join(tmp, "alice.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Sign(dir, signer); err != nil {
+		t.Fatal(err)
+	}
+	ring, err := LoadKeyring(keyring)
+	if err != nil || len(ring) != 1 {
+		t.Fatalf("Expected one key in the keyring, got %d (%v)", len(ring), err)
+	}
+	if ring[0].Private != nil {
+		t.Error("Expected keyring keys to have no private key")
+	}
+	sig, err := Verify(dir, ring)
+	if err != nil {
+		t.Fatalf("Expected the chart to verify: %s", err)
+	}
+	if sig.Key != "alice" {
+		t.Errorf("Expected the signature to be by alice, got %q", sig.Key)
+	}
+
+	// Every file is covered, including files outside of manifests/.
+	ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("Changed.\n"), 0644)
+	if _, err := Verify(dir, ring); err == nil {
+		t.Error("Expected a changed README to fail")
+	}
+	ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("A chart.\n"), 0644)
+	os.Mkdir(filepath.Join(dir, "tpl"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "tpl", "gen.sh"), []byte("rm -rf /\n"), 0755)
+	if _, err := Verify(dir, ring); err == nil {
+		t.Error("Expected an added generator script to fail")
+	}
+	os.RemoveAll(filepath.Join(dir, "tpl"))
+	if _, err := Verify(dir, ring); err != nil {
+		t.Errorf("Expected the restored chart to verify: %s", err)
+	}
+
+	// Fetching renames the chart and records where it came from.
+	ioutil.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("name: cache\nversion: 1.0.0\nfrom:\n  name: redis\n  repo: https://example.com/charts\n"), 0644)
+	if _, err := Verify(dir, ring); err != nil {
+		t.Errorf("Expected a fetched chart to verify: %s", err)
+	}
+
+	other, _ := GenerateKey("mallory")
+	if _, err := Verify(dir, Keyring{other}); err == nil || !strings.Contains(err.Error(), "not in the keyring") {
+		t.Errorf("Expected an unknown key error, got %v", err)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "manifests", "pod.yaml"), []byte("kind: Secret\n"), 0644)
+	if _, err := Verify(dir, ring); err == nil || !strings.Contains(err.Error(), "changed") {
+		t.Errorf("Expected a tampered chart to fail, got %v", err)
+	}
+}
@@ -0,0 +1,425 @@
+// Package record keeps track of the charts that have been installed into Kubernetes.
+//
+// Every install produces a release record describing what was sent to the
+// cluster: the chart and its version, where it came from, the namespace, and
+// the exact manifests that were uploaded. Each install, upgrade, or rollback of
+// a release produces a new numbered revision. Revisions are stored as YAML
+// files in $HELMC_HOME/releases/NAME/REVISION.yaml, and the latest revision may
+// also be stored as a ConfigMap inside of the target namespace.
+//
+// The values of Secrets are never recorded; only their digests are. Record
+// files are only readable by their owner.
+package record
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/helm/helm-classic/chart"
+	"github.com/helm/helm-classic/codec"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// StatusDeployed indicates that all manifests were uploaded.
+	StatusDeployed = "DEPLOYED"
+	// StatusFailed indicates that an install did not complete.
+	StatusFailed = "FAILED"
+	// StatusDeleted indicates that a release has been uninstalled.
+	StatusDeleted = "DELETED"
+	// StatusSuperseded indicates that a later revision has been deployed.
+	StatusSuperseded = "SUPERSEDED"
+)
+
+// ConfigMapKey is the key inside of a release ConfigMap that holds the record.
+const ConfigMapKey = "release"
+
+// ErrNoRelease indicates that no record exists for a release.
+var ErrNoRelease = errors.New("no such release")
+
+// ErrNoRevision indicates that a release has no record of a revision.
+var ErrNoRevision = errors.New("no such revision")
+
+// Release describes a chart that has been installed into Kubernetes.
+type Release struct {
+	// Name is the name of the release. This is the local name of the chart.
+	Name string `yaml:"name"`
+	// Revision is the revision number of this record, starting at 1.
+	Revision int `yaml:"revision"`
+	// Chart is the name declared in the chart's Chart.yaml.
+	Chart string `yaml:"chart"`
+	// Version is the chart version declared in Chart.yaml.
+	Version string `yaml:"version"`
+	// From records where the chart was fetched from, if known.
+	From *chart.Dependency `yaml:"from,omitempty"`
+	// Namespace is the namespace that the chart was installed into.
+	Namespace string `yaml:"namespace,omitempty"`
+	// Timestamp is the time of the install, formatted as RFC 3339.
+	Timestamp string `yaml:"timestamp"`
+	// Status is one of the Status* constants.
+	Status string `yaml:"status"`
+	// Description is a short, human-readable summary of how this revision
+	// came to be.
+	Description string `yaml:"description,omitempty"`
+	// Manifests contains the rendered objects that were sent to Kubernetes.
+	Manifests []*Object `yaml:"manifests,omitempty"`
+}
+
+// MaxConfigMapSize is the largest release record that can be stored in a
+// ConfigMap. Kubernetes refuses objects larger than 1MiB.
+const MaxConfigMapSize = 1 << 20
+
+// Object is a single rendered manifest that belongs to a release.
+type Object struct {
+	Kind string `yaml:"kind"`
+	Name string `yaml:"name"`
+	// Data is the JSON document that was sent to Kubernetes. For a Secret,
+	// each value of data and stringData is replaced by its SHA-256 digest.
+	Data string `yaml:"data"`
+	// Redacted indicates that Data is not the document that was sent, so it
+	// cannot be sent again.
+	Redacted bool `yaml:"redacted,omitempty"`
+
+	// raw is the document that was sent, if it is still known.
+	raw []byte
+}
+
+// Raw returns the document that was sent to Kubernetes, or nil if it is not
+// known because the object was redacted.
+func (o *Object) Raw() []byte {
+	if o.raw != nil {
+		return o.raw
+	}
+	if o.Redacted {
+		return nil
+	}
+	return []byte(o.Data)
+}
+
+// New creates a new release record for the given chart.
+//
+// The returned record has no manifests and no status.
+func New(name, namespace string, cf *chart.Chartfile) *Release {
+	return &Release{
+		Name:      name,
+		Chart:     cf.Name,
+		Version:   cf.Version,
+		From:      cf.From,
+		Namespace: namespace,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// Add appends a rendered manifest to the release.
+//
+// The values of a Secret are not recorded. See NewObject.
+func (r *Release) Add(kind, name string, data []byte) {
+	r.Manifests = append(r.Manifests, NewObject(kind, name, data))
+}
+
+// NewObject creates a record of a rendered manifest.
+//
+// The values of a Secret are not recorded. See Redact.
+func NewObject(kind, name string, data []byte) *Object {
+	o := &Object{Kind: kind, Name: name, Data: string(data), raw: data}
+	if kind == "Secret" {
+		red, err := Redact(data)
+		if err != nil {
+			// A Secret that cannot be parsed is not recorded at all.
+			red = nil
+		}
+		o.Data, o.Redacted = string(red), true
+	}
+	return o
+}
+
+// Redact replaces each value in the data and stringData of a JSON Secret
+// with its SHA-256 digest.
+//
+// The digests still show whether a value changed, without revealing it.
+func Redact(data []byte) ([]byte, error) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	for _, field := range []string{"data", "stringData"} {
+		values, ok := obj[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for k, v := range values {
+			sum := sha256.Sum256([]byte(fmt.Sprint(v)))
+			values[k] = "sha256:" + hex.EncodeToString(sum[:])
+		}
+	}
+	return json.Marshal(obj)
+}
+
+// Revise returns a copy of the release with the same chart and manifests,
+// which will be saved as a new revision.
+//
+// The copy has a new timestamp, and no status or description.
+func (r *Release) Revise() *Release {
+	n := *r
+	n.Revision = 0
+	n.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	n.Status = ""
+	n.Description = ""
+	return &n
+}
+
+// Time returns the parsed timestamp of the release.
+func (r *Release) Time() (time.Time, error) {
+	return time.Parse(time.RFC3339, r.Timestamp)
+}
+
+// Load reads the latest revision of the named release from the given directory.
+//
+// If no record exists, ErrNoRelease is returned.
+func Load(dir, name string) (*Release, error) {
+	revs, err := revisions(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(revs) == 0 {
+		return nil, ErrNoRelease
+	}
+	return LoadRevision(dir, name, revs[len(revs)-1])
+}
+
+// LoadRevision reads one revision of the named release from the given directory.
+//
+// If the revision does not exist, ErrNoRevision is returned.
+func LoadRevision(dir, name string, revision int) (*Release, error) {
+	b, err := ioutil.ReadFile(filename(dir, name, revision))
+	if os.IsNotExist(err) {
+		return nil, ErrNoRevision
+	} else if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// History returns every revision of the named release, oldest first.
+//
+// If no record exists, ErrNoRelease is returned.
+func History(dir, name string) ([]*Release, error) {
+	revs, err := revisions(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(revs) == 0 {
+		return nil, ErrNoRelease
+	}
+	h := make([]*Release, 0, len(revs))
+	for _, rev := range revs {
+		r, err := LoadRevision(dir, name, rev)
+		if err != nil {
+			return h, err
+		}
+		h = append(h, r)
+	}
+	return h, nil
+}
+
+// Live returns the latest revision of the named release, with the objects
+// that its history says are in Kubernetes.
+//
+// A failed revision records only the objects that were applied before it
+// failed. The objects are therefore those of the last deployed revision,
+// updated with the objects applied by every failed revision since. If the
+// latest revision was deleted, it is returned as is.
+//
+// If no record exists, ErrNoRelease is returned.
+func Live(dir, name string) (*Release, error) {
+	h, err := History(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	latest := *h[len(h)-1]
+	if latest.Status == StatusDeleted {
+		return &latest, nil
+	}
+
+	start := 0
+	for i := len(h) - 1; i >= 0; i-- {
+		if h[i].Status == StatusDeployed {
+			start = i
+			break
+		} else if h[i].Status == StatusDeleted {
+			start = i + 1
+			break
+		}
+	}
+
+	latest.Manifests = nil
+	index := map[string]int{}
+	for _, r := range h[start:] {
+		for _, o := range r.Manifests {
+			key := o.Kind + "/" + o.Name
+			if i, ok := index[key]; ok {
+				latest.Manifests[i] = o
+				continue
+			}
+			index[key] = len(latest.Manifests)
+			latest.Manifests = append(latest.Manifests, o)
+		}
+	}
+	return &latest, nil
+}
+
+// Parse parses a YAML release record.
+func Parse(data []byte) (*Release, error) {
+	r := &Release{}
+	return r, yaml.Unmarshal(data, r)
+}
+
+// Save writes the release into the given directory, creating it if necessary.
+//
+// If the release does not have a revision number, it is saved as the next
+// revision of the release.
+func Save(dir string, r *Release) error {
+	if r.Name == "" {
+		return errors.New("release has no name")
+	}
+	if r.Revision == 0 {
+		revs, err := revisions(dir, r.Name)
+		if err != nil {
+			return err
+		}
+		r.Revision = 1
+		if len(revs) > 0 {
+			r.Revision = revs[len(revs)-1] + 1
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dir, r.Name), 0700); err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename(dir, r.Name, r.Revision), b, 0600)
+}
+
+// Supersede marks every deployed revision before the given one as superseded.
+func Supersede(dir, name string, revision int) error {
+	revs, err := revisions(dir, name)
+	if err != nil {
+		return err
+	}
+	for _, rev := range revs {
+		if rev >= revision {
+			continue
+		}
+		r, err := LoadRevision(dir, name, rev)
+		if err != nil {
+			return err
+		}
+		if r.Status != StatusDeployed {
+			continue
+		}
+		r.Status = StatusSuperseded
+		if err := Save(dir, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revisions returns the sorted revision numbers that are stored for a release.
+func revisions(dir, name string) ([]int, error) {
+	fis, err := ioutil.ReadDir(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return []int{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	revs := []int{}
+	for _, fi := range fis {
+		base := strings.TrimSuffix(fi.Name(), ".yaml")
+		if fi.IsDir() || base == fi.Name() {
+			continue
+		}
+		if rev, err := strconv.Atoi(base); err == nil {
+			revs = append(revs, rev)
+		}
+	}
+	sort.Ints(revs)
+	return revs, nil
+}
+
+func filename(dir, name string, revision int) string {
+	return filepath.Join(dir, name, strconv.Itoa(revision)+".yaml")
+}
+
+// ConfigMapName returns the name of the ConfigMap that stores a release.
+func ConfigMapName(name string) string {
+	return "helmc-release-" + name
+}
+
+// ConfigMap returns the JSON for a ConfigMap that stores this release.
+//
+// It is an error if the ConfigMap would be larger than MaxConfigMapSize.
+func (r *Release) ConfigMap() ([]byte, error) {
+	b, err := yaml.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	cm := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name": ConfigMapName(r.Name),
+			"labels": map[string]string{
+				"heritage": "helm",
+				"release":  r.Name,
+			},
+		},
+		"data": map[string]string{
+			ConfigMapKey: string(b),
+		},
+	}
+	data, err := json.Marshal(cm)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxConfigMapSize {
+		return nil, fmt.Errorf("the record is %d bytes, but a ConfigMap can hold at most %d", len(data), MaxConfigMapSize)
+	}
+	return data, nil
+}
+
+// ConfigMapRef returns the JSON for a ConfigMap that contains just enough
+// information to look up the ConfigMap for the named release.
+func ConfigMapRef(name string) []byte {
+	return []byte(fmt.Sprintf(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": %q}}`, ConfigMapName(name)))
+}
+
+// FromConfigMap parses a release record out of a JSON-encoded ConfigMap.
+func FromConfigMap(data []byte) (*Release, error) {
+	o, err := codec.JSON.Decode(data).One()
+	if err != nil {
+		return nil, err
+	}
+	cm, err := o.ConfigMap()
+	if err != nil {
+		return nil, err
+	}
+	d, ok := cm.Data[ConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap %s does not contain a release", cm.Name)
+	}
+	return Parse([]byte(d))
+}
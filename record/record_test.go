@@ -0,0 +1,213 @@
+package record
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/helm/helm-classic/chart"
+)
+
+func testRelease() *Release {
+	cf := &chart.Chartfile{
+		Name:    "redis-standalone",
+		Version: "0.0.1",
+		From: &chart.Dependency{
+			Name:    "redis-standalone",
+			Version: "0.0.1",
+			Repo:    "https://github.com/helm/charts",
+		},
+	}
+	r := New("redis", "default", cf)
+	r.Status = StatusDeployed
+	r.Add("Pod", "redis", []byte(`{"kind": "Pod"}`))
+	return r
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "helmc-release")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := Save(dir, testRelease()); err != nil {
+		t.Fatalf("Failed to save release: %s", err)
+	}
+
+	r, err := Load(dir, "redis")
+	if err != nil {
+		t.Fatalf("Failed to load release: %s", err)
+	}
+	if r.Chart != "redis-standalone" {
+		t.Errorf("Expected chart redis-standalone, got %q", r.Chart)
+	}
+	if r.Namespace != "default" {
+		t.Errorf("Expected namespace default, got %q", r.Namespace)
+	}
+	if r.From == nil || r.From.Repo != "https://github.com/helm/charts" {
+		t.Errorf("Expected From to be preserved, got %v", r.From)
+	}
+	if len(r.Manifests) != 1 || r.Manifests[0].Kind != "Pod" {
+		t.Errorf("Expected one Pod manifest, got %v", r.Manifests)
+	}
+	if _, err := r.Time(); err != nil {
+		t.Errorf("Could not parse timestamp %q: %s", r.Timestamp, err)
+	}
+
+	if r.Revision != 1 {
+		t.Errorf("Expected revision 1, got %d", r.Revision)
+	}
+	if fi, err := os.Stat(filename(dir, "redis", 1)); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("Expected the record to be saved with mode 0600, got %v (%v)", fi.Mode(), err)
+	}
+
+	if _, err := Load(dir, "nope"); err != ErrNoRelease {
+		t.Errorf("Expected ErrNoRelease, got %v", err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "helmc-release")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < 3; i++ {
+		if err := Save(dir, testRelease()); err != nil {
+			t.Fatalf("Failed to save release: %s", err)
+		}
+	}
+	if err := Supersede(dir, "redis", 3); err != nil {
+		t.Fatalf("Failed to supersede: %s", err)
+	}
+
+	h, err := History(dir, "redis")
+	if err != nil {
+		t.Fatalf("Failed to load history: %s", err)
+	}
+	if len(h) != 3 {
+		t.Fatalf("Expected 3 revisions, got %d", len(h))
+	}
+	for i, r := range h {
+		if r.Revision != i+1 {
+			t.Errorf("Expected revision %d, got %d", i+1, r.Revision)
+		}
+	}
+	if h[0].Status != StatusSuperseded || h[2].Status != StatusDeployed {
+		t.Errorf("Unexpected statuses %s, %s", h[0].Status, h[2].Status)
+	}
+
+	r, err := Load(dir, "redis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Revision != 3 {
+		t.Errorf("Expected latest revision 3, got %d", r.Revision)
+	}
+
+	if _, err := LoadRevision(dir, "redis", 4); err != ErrNoRevision {
+		t.Errorf("Expected ErrNoRevision, got %v", err)
+	}
+}
+
+func TestLive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "helmc-release")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := Save(dir, testRelease()); err != nil {
+		t.Fatalf("Failed to save release: %s", err)
+	}
+	failed := testRelease()
+	failed.Status = StatusFailed
+	failed.Manifests = nil
+	failed.Add("Service", "redis", []byte(`{"kind": "Service"}`))
+	if err := Save(dir, failed); err != nil {
+		t.Fatalf("Failed to save release: %s", err)
+	}
+
+	r, err := Live(dir, "redis")
+	if err != nil {
+		t.Fatalf("Failed to load release: %s", err)
+	}
+	if r.Revision != 2 || r.Status != StatusFailed {
+		t.Errorf("Expected revision 2 to be FAILED, got %d %s", r.Revision, r.Status)
+	}
+	if len(r.Manifests) != 2 || r.Manifests[0].Kind != "Pod" || r.Manifests[1].Kind != "Service" {
+		t.Errorf("Expected the Pod of revision 1 and the Service of revision 2, got %v", r.Manifests)
+	}
+
+	deleted := testRelease()
+	deleted.Status = StatusDeleted
+	if err := Save(dir, deleted); err != nil {
+		t.Fatalf("Failed to save release: %s", err)
+	}
+	failed.Revision = 0
+	if err := Save(dir, failed); err != nil {
+		t.Fatalf("Failed to save release: %s", err)
+	}
+	r, err = Live(dir, "redis")
+	if err != nil {
+		t.Fatalf("Failed to load release: %s", err)
+	}
+	if len(r.Manifests) != 1 || r.Manifests[0].Kind != "Service" {
+		t.Errorf("Expected only the Service after the release was deleted, got %v", r.Manifests)
+	}
+}
+
+func TestConfigMap(t *testing.T) {
+	data, err := testRelease().ConfigMap()
+	if err != nil {
+		t.Fatalf("Failed to encode ConfigMap: %s", err)
+	}
+
+	r, err := FromConfigMap(data)
+	if err != nil {
+		t.Fatalf("Failed to decode ConfigMap: %s", err)
+	}
+	if r.Name != "redis" || r.Status != StatusDeployed {
+		t.Errorf("Unexpected release %s (%s)", r.Name, r.Status)
+	}
+}
+
+func TestSecretsAreRedacted(t *testing.T) {
+	r := testRelease()
+	secret := []byte(`{"kind": "Secret", "metadata": {"name": "pw"}, "data": {"password": "aHVudGVyMg=="}, "stringData": {"token": "hunter2"}}`)
+	r.Add("Secret", "pw", secret)
+
+	o := r.Manifests[1]
+	if !o.Redacted || strings.Contains(o.Data, "aHVudGVyMg==") || strings.Contains(o.Data, "hunter2") {
+		t.Errorf("Expected the Secret values to be redacted, got %s", o.Data)
+	}
+	if !strings.Contains(o.Data, `"name":"pw"`) || !strings.Contains(o.Data, `"password":"sha256:`) {
+		t.Errorf("Expected the Secret name and keys to be kept, got %s", o.Data)
+	}
+	if string(o.Raw()) != string(secret) {
+		t.Errorf("Expected the sent document to be kept in memory")
+	}
+
+	data, err := r.ConfigMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hunter2") {
+		t.Errorf("Expected no Secret values in the ConfigMap")
+	}
+	loaded, err := FromConfigMap(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Manifests[1].Raw() != nil {
+		t.Errorf("Expected a loaded Secret to have no document that can be sent")
+	}
+
+	r.Add("ConfigMap", "big", []byte(strings.Repeat("x", MaxConfigMapSize)))
+	if _, err := r.ConfigMap(); err == nil {
+		t.Error("Expected an error for a record that does not fit in a ConfigMap")
+	}
+}
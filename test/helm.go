@@ -1,11 +1,17 @@
 package test
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 	"testing"
 
@@ -13,7 +19,8 @@ import (
 	"github.com/helm/helm-classic/util"
 )
 
-const tmpConfigfile = `repos:
+const tmpConfigfile = `version: 1
+repos:
   default: charts
   tables:
     - name: charts
@@ -100,3 +107,26 @@ func CaptureOutput(fn func()) (out string) {
 	out = string(b)
 	return
 }
+
+// ChartArchive builds a gzipped tar archive of a chart named name, which
+// contains the given files, and returns it with its "sha256:HEX" digest.
+func ChartArchive(name string, files map[string]string) ([]byte, string) {
+	paths := []string{}
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, p := range paths {
+		tw.WriteHeader(&tar.Header{Name: name + "/" + p, Mode: 0644, Size: int64(len(files[p]))})
+		tw.Write([]byte(files[p]))
+	}
+	tw.Close()
+	gz.Close()
+
+	sum := sha256.Sum256(buf.Bytes())
+	return buf.Bytes(), "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -13,12 +13,12 @@ import (
 const Configfile = "config.yaml"
 
 // DefaultConfigfile is the default Helm Classic configuration.
-const DefaultConfigfile = `repos:
+const DefaultConfigfile = `version: 1
+repos:
   default: charts
   tables:
     - name: charts
       repo: https://github.com/helm/charts
-workspace:
 `
 
 // EnsureHome ensures that a HELMC_HOME exists.
@@ -41,7 +41,7 @@ func EnsureHome(home string) {
 	if _, err := os.Stat(refi); err != nil {
 		log.Info("Creating %s", refi)
 		// Attempt to create a Repos.yaml
-		if err := ioutil.WriteFile(refi, []byte(DefaultConfigfile), 0755); err != nil {
+		if err := ioutil.WriteFile(refi, []byte(DefaultConfigfile), 0600); err != nil {
 			log.Die("Could not create %s: %s", refi, err)
 		}
 	}
@@ -11,6 +11,15 @@ const workspacePath = "workspace"
 // workspaceChartPath is the directory that contains a user's workspace charts.
 const workspaceChartPath = "workspace/charts"
 
+// releasePath is the directory that contains records of installed charts.
+const releasePath = "releases"
+
+// keyringPath is the directory that contains trusted public keys.
+const keyringPath = "keyring"
+
+// keyPath is the directory that contains the user's signing keys.
+const keyPath = "keys"
+
 // CacheDirectory - File path to cache directory based on home
 func CacheDirectory(home string, paths ...string) string {
 	fragments := append([]string{home, cachePath}, paths...)
@@ -22,3 +31,21 @@ func WorkspaceChartDirectory(home string, paths ...string) string {
 	fragments := append([]string{home, workspaceChartPath}, paths...)
 	return filepath.Join(fragments...)
 }
+
+// ReleaseDirectory - File path to the release records directory based on home
+func ReleaseDirectory(home string, paths ...string) string {
+	fragments := append([]string{home, releasePath}, paths...)
+	return filepath.Join(fragments...)
+}
+
+// KeyringDirectory - File path to the directory of trusted public keys based on home
+func KeyringDirectory(home string, paths ...string) string {
+	fragments := append([]string{home, keyringPath}, paths...)
+	return filepath.Join(fragments...)
+}
+
+// KeyDirectory - File path to the directory of signing keys based on home
+func KeyDirectory(home string, paths ...string) string {
+	fragments := append([]string{home, keyPath}, paths...)
+	return filepath.Join(fragments...)
+}